@@ -0,0 +1,52 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/alnvdl/varys/internal/fetch"
+	"github.com/alnvdl/varys/internal/list"
+)
+
+// discover finds the feeds of the websites whose URLs are given in args, and
+// prints them as a JSON list of feeds that can be used in FEEDS. It returns
+// the exit code of the command.
+func discover(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: varys discover <site URL>...")
+		return 2
+	}
+
+	fetcher := fetch.NewFetcher(clientParams())
+	feeds := []*list.InputFeed{}
+	code := 0
+	for _, siteURL := range args {
+		discovered, err := fetcher.Discover(context.Background(), siteURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot discover feeds for %s: %v\n", siteURL, err)
+			code = 1
+			continue
+		}
+		if len(discovered) == 0 {
+			fmt.Fprintf(os.Stderr, "no feeds found for %s\n", siteURL)
+		}
+		for _, df := range discovered {
+			feeds = append(feeds, &list.InputFeed{
+				Name: cmp.Or(df.Title, df.URL),
+				URL:  df.URL,
+				Type: df.Type,
+			})
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(feeds); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot encode feeds: %v\n", err)
+		return 1
+	}
+	return code
+}
@@ -5,15 +5,18 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/alnvdl/autosave"
 
+	"github.com/alnvdl/varys/internal/fetch"
 	"github.com/alnvdl/varys/internal/list"
 	"github.com/alnvdl/varys/internal/list/mem"
 	"github.com/alnvdl/varys/internal/web"
@@ -24,6 +27,9 @@ const (
 	defaultPort            = "8080"
 	defaultPersistInterval = 1 * time.Minute
 	defaultRefreshInterval = 5 * time.Minute
+	defaultRefreshJitter   = 30 * time.Second
+	defaultHostDelay       = 1 * time.Second
+	defaultShutdownTimeout = 10 * time.Second
 )
 
 func dbPath() string {
@@ -77,6 +83,76 @@ func refreshInterval() time.Duration {
 	return defaultRefreshInterval
 }
 
+// envDuration returns the duration in the given environment variable, or
+// zero if it is unset or invalid.
+func envDuration(name string) time.Duration {
+	d, _ := time.ParseDuration(os.Getenv(name))
+	return d
+}
+
+// envInt returns the integer in the given environment variable, or zero if it
+// is unset or invalid.
+func envInt(name string) int64 {
+	i, _ := strconv.ParseInt(os.Getenv(name), 10, 64)
+	return i
+}
+
+// maxRedirects returns the value of FETCH_MAX_REDIRECTS, or nil if it is not
+// set to a valid number, since 0 disables redirects.
+func maxRedirects() *int {
+	n, err := strconv.Atoi(os.Getenv("FETCH_MAX_REDIRECTS"))
+	if err != nil || n < 0 {
+		return nil
+	}
+	return &n
+}
+
+func userAgent() string {
+	version, err := web.Version()
+	if err != nil {
+		version = "unknown"
+	}
+	return fmt.Sprintf("Varys/%s (+https://github.com/alnvdl/varys)", version)
+}
+
+// clientParams returns the params for the HTTP client used for fetching
+// feeds. Unset values are left as zero (or nil) so the fetch package defaults
+// apply.
+func clientParams() fetch.ClientParams {
+	return fetch.ClientParams{
+		ConnectTimeout: envDuration("FETCH_CONNECT_TIMEOUT"),
+		HeaderTimeout:  envDuration("FETCH_HEADER_TIMEOUT"),
+		Timeout:        envDuration("FETCH_TIMEOUT"),
+		MaxBodySize:    envInt("FETCH_MAX_BODY_SIZE"),
+		MaxRedirects:   maxRedirects(),
+		UserAgent:      userAgent(),
+	}
+}
+
+func refreshJitter() time.Duration {
+	rj := os.Getenv("REFRESH_JITTER")
+	if d, err := time.ParseDuration(rj); err == nil {
+		return d
+	}
+	return defaultRefreshJitter
+}
+
+func hostDelay() time.Duration {
+	hd := os.Getenv("REFRESH_HOST_DELAY")
+	if d, err := time.ParseDuration(hd); err == nil {
+		return d
+	}
+	return defaultHostDelay
+}
+
+func shutdownTimeout() time.Duration {
+	st := os.Getenv("SHUTDOWN_TIMEOUT")
+	if d, err := time.ParseDuration(st); err == nil {
+		return d
+	}
+	return defaultShutdownTimeout
+}
+
 func feeds() []*list.InputFeed {
 	var feeds []*list.InputFeed
 	if err := json.Unmarshal([]byte(os.Getenv("FEEDS")), &feeds); err != nil {
@@ -86,9 +162,23 @@ func feeds() []*list.InputFeed {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "discover" {
+		os.Exit(discover(os.Args[2:]))
+	}
+
+	fetcher := fetch.NewFetcher(clientParams())
 	feedList, err := mem.NewList(mem.ListParams{
-		InitialFeeds:    feeds(),
-		RefreshInterval: refreshInterval(),
+		InitialFeeds:       feeds(),
+		RefreshInterval:    refreshInterval(),
+		MinRefreshInterval: envDuration("REFRESH_MIN_INTERVAL"),
+		MaxRefreshInterval: envDuration("REFRESH_MAX_INTERVAL"),
+		RefreshJitter:      refreshJitter(),
+		RefreshConcurrency: int(envInt("REFRESH_CONCURRENCY")),
+		HostConcurrency:    int(envInt("REFRESH_HOST_CONCURRENCY")),
+		HostDelay:          hostDelay(),
+		Fetcher:            fetcher.Fetch,
+		IconFetcher:        fetcher.FetchIcon,
+		ContentFetcher:     fetcher.FetchContent,
 		AutoSaveParams: autosave.Params{
 			FilePath: dbPath(),
 			Interval: persistInterval(),
@@ -102,6 +192,7 @@ func main() {
 
 	handler := web.NewHandler(&web.HandlerParams{
 		FeedList:    feedList,
+		Discover:    fetcher.Discover,
 		AccessToken: accessToken(),
 		SessionKey:  sessionKey(),
 	})
@@ -113,19 +204,45 @@ func main() {
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	shutdownDone := make(chan struct{})
 	go func() {
 		<-signals
-		feedList.Close()
-		slog.Info("shutting down server")
-		server.Shutdown(context.Background())
+		shutdown(server, feedList)
+		close(shutdownDone)
 	}()
 
 	slog.Info("starting server", slog.String("address", server.Addr))
 	if err := server.ListenAndServe(); err != nil {
 		if err == http.ErrServerClosed {
+			<-shutdownDone
 			slog.Info("server shut down")
 		} else {
 			slog.Error("unexpected error on listen and serve", slog.String("error", err.Error()))
 		}
 	}
 }
+
+// shutdown closes the feed list, cancelling any outstanding fetches, and then
+// shuts down the server. If that takes longer than the shutdown timeout, the
+// remaining work is abandoned and open connections are closed.
+func shutdown(server *http.Server, feedList *mem.List) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		feedList.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-ctx.Done():
+		slog.Error("timed out closing feed list")
+	}
+
+	slog.Info("shutting down server")
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("cannot shut down server gracefully", slog.String("error", err.Error()))
+		server.Close()
+	}
+}
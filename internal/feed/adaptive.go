@@ -0,0 +1,45 @@
+package feed
+
+import (
+	"slices"
+	"time"
+
+	"github.com/alnvdl/varys/internal/timeutil"
+)
+
+const (
+	// adaptivePollsPerPost is how many times a feed with an adaptive refresh
+	// interval is polled, on average, between two of its posts.
+	adaptivePollsPerPost = 4
+
+	// adaptiveSampleSize is the maximum number of intervals between posts
+	// considered when computing an adaptive refresh interval, so the interval
+	// follows changes in the posting frequency of the feed.
+	adaptiveSampleSize = 10
+)
+
+// AdaptiveInterval returns a refresh interval for the feed learned from how
+// often it publishes new items, bounded by minInterval and maxInterval. New
+// items are identified by the time they were first seen, so items found in
+// the same refresh count as a single post. If the feed has been quiet for
+// longer than its average interval between posts, that quiet period is used
+// instead. It returns 0 if not enough items have been seen yet.
+func (f *Feed) AdaptiveInterval(minInterval, maxInterval time.Duration) time.Duration {
+	var timestamps []int64
+	for _, item := range f.Items {
+		timestamps = append(timestamps, item.Timestamp)
+	}
+	slices.Sort(timestamps)
+	timestamps = slices.Compact(timestamps)
+	if len(timestamps) < 2 {
+		return 0
+	}
+	timestamps = timestamps[max(0, len(timestamps)-adaptiveSampleSize-1):]
+
+	first, last := timestamps[0], timestamps[len(timestamps)-1]
+	gap := (last - first) / int64(len(timestamps)-1)
+	gap = max(gap, timeutil.Now()-last)
+
+	interval := time.Duration(gap) * time.Second / adaptivePollsPerPost
+	return min(max(interval, minInterval), maxInterval)
+}
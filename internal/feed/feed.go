@@ -5,23 +5,52 @@ package feed
 import (
 	"cmp"
 	"errors"
+	"fmt"
 	"iter"
 	"log/slog"
+	"math/rand/v2"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/alnvdl/varys/internal/timeutil"
 )
 
 const (
-	TypeXML   = "xml"
-	TypeHTML  = "html"
-	TypeImage = "img"
+	TypeXML     = "xml"
+	TypeHTML    = "html"
+	TypeImage   = "img"
+	TypeJSON    = "json"
+	TypeJSONAPI = "jsonapi"
+)
+
+// Values for the sort_by param, which defines how the items of a feed are
+// sorted.
+const (
+	// SortByFirstSeen sorts items by the time they were first seen.
+	SortByFirstSeen = "first_seen"
+
+	// SortByPublished sorts items by the time they were published according
+	// to the feed, if known.
+	SortByPublished = "published"
+)
+
+const (
+	// minRetryBackoff is the delay before retrying a feed after its first
+	// consecutive failure. It doubles on every subsequent failure.
+	minRetryBackoff = 1 * time.Minute
+
+	// maxRetryBackoff caps the delay computed from consecutive failures.
+	maxRetryBackoff = 6 * time.Hour
+
+	// maxRetryAfter caps the delay requested by servers via Retry-After.
+	maxRetryAfter = 24 * time.Hour
 )
 
 // Feed represents a feed in the application.
 type Feed struct {
-	// Name is the name of the feed as defined by the user.
+	// Name is the name of the feed as defined by the user. If empty, the title
+	// declared by the publisher is used in summaries.
 	Name string `json:"name"`
 
 	// Type is the type of the feed. It can be one of the types defined in this
@@ -47,6 +76,45 @@ type Feed struct {
 	// feed.
 	LastRefreshError string `json:"error"`
 
+	// LastRefreshErrorCode is the HTTP status code that caused the last
+	// refresh error, if the error was caused by an unexpected HTTP response.
+	LastRefreshErrorCode int `json:"error_code"`
+
+	// ConsecutiveFailures is the number of refreshes that failed in a row
+	// because of fetch errors.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// NextAttemptAt is the earliest time when the feed should be refreshed
+	// again after failures. It is zero if the feed is not backing off.
+	NextAttemptAt int64 `json:"next_attempt_at"`
+
+	// Hints are the update hints declared by the publisher in the last
+	// successful refresh, if any.
+	Hints *UpdateHints `json:"hints"`
+
+	// RefreshInterval is the interval in seconds used when scheduling the
+	// next refresh of the feed.
+	RefreshInterval int64 `json:"refresh_interval"`
+
+	// NextRefreshAt is the time when the feed is scheduled to be refreshed
+	// next.
+	NextRefreshAt int64 `json:"next_refresh_at"`
+
+	// ETag is the entity tag returned by the server in the last successful
+	// refresh. It is sent back in conditional requests.
+	ETag string `json:"etag"`
+
+	// LastModified is the Last-Modified header returned by the server in the
+	// last successful refresh. It is sent back in conditional requests.
+	LastModified string `json:"last_modified"`
+
+	// Metadata is the information declared by the publisher about the feed in
+	// the last successful refresh, if any.
+	Metadata *Metadata `json:"metadata"`
+
+	// Icon is the cached icon of the feed, if any.
+	Icon *Icon `json:"icon,omitempty"`
+
 	// itemFeeds is used to map items to their original feeds in case this feed
 	// is a virtual feed aggregating items from multiple feeds. The key should
 	// be a combination of the feed UID and the item UID.
@@ -62,9 +130,21 @@ type FeedSummary struct {
 	// URL is the URL from which the feed is fetched.
 	URL string `json:"url"`
 
-	// Name is the name of the feed as defined by the user.
+	// Name is the name of the feed as defined by the user, or else the title
+	// declared by the publisher.
 	Name string `json:"name"`
 
+	// Description is a short plain-text description of the feed declared by
+	// the publisher, if any.
+	Description string `json:"description,omitempty"`
+
+	// SiteURL is the URL of the website the feed belongs to, if known.
+	SiteURL string `json:"site_url,omitempty"`
+
+	// IconURL is the API path where the cached icon of the feed is served, if
+	// any.
+	IconURL string `json:"icon_url,omitempty"`
+
 	// Items is a list of items in the feed. It is usually empty, unless
 	// explicitly requested.
 	Items []*ItemSummary `json:"items,omitempty"`
@@ -76,6 +156,26 @@ type FeedSummary struct {
 	// last attempt.
 	LastError string `json:"last_error"`
 
+	// LastErrorCode is the HTTP status code that caused LastError, if any.
+	// It can be used to tell apart permanent failures (e.g., 404 or 410) from
+	// transient ones (e.g., 503).
+	LastErrorCode int `json:"last_error_code"`
+
+	// ConsecutiveFailures is the number of refreshes that failed in a row.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// NextAttemptAt is the earliest time when the feed will be refreshed
+	// again if it is backing off after failures, or zero otherwise.
+	NextAttemptAt int64 `json:"next_attempt_at"`
+
+	// RefreshInterval is the interval in seconds used when scheduling the
+	// next refresh of the feed.
+	RefreshInterval int64 `json:"refresh_interval"`
+
+	// NextRefreshAt is the time when the feed is scheduled to be refreshed
+	// next.
+	NextRefreshAt int64 `json:"next_refresh_at"`
+
 	// ItemCount is the number of items in the feed.
 	ItemCount int `json:"item_count"`
 
@@ -98,12 +198,37 @@ func (f *Feed) UID() string {
 }
 
 type feedParams struct {
-	MaxItems int `json:"max_items"`
+	MaxItems        int    `json:"max_items"`
+	RefreshInterval string `json:"refresh_interval"`
+	RefreshWindow   string `json:"refresh_window"`
+	IgnoreHints     bool   `json:"ignore_hints"`
+	SortBy          string `json:"sort_by"`
+	// MarkUnreadOnUpdate marks read items as unread when the text of their
+	// title or content changes.
+	MarkUnreadOnUpdate bool `json:"mark_unread_on_update"`
+	// FetchFullContent replaces the content of new items with the content
+	// of the pages they link to.
+	FetchFullContent bool `json:"fetch_full_content"`
 }
 
 func (p *feedParams) Validate() error {
-	if p.MaxItems <= 0 {
-		return errors.New("max_items must be positive")
+	if p.MaxItems < 0 {
+		return errors.New("max_items cannot be negative")
+	}
+	if p.RefreshInterval != "" {
+		if d, err := time.ParseDuration(p.RefreshInterval); err != nil {
+			return fmt.Errorf("cannot parse refresh_interval: %v", err)
+		} else if d <= 0 {
+			return errors.New("refresh_interval must be positive")
+		}
+	}
+	if p.SortBy != "" && p.SortBy != SortByFirstSeen && p.SortBy != SortByPublished {
+		return fmt.Errorf("sort_by must be %q or %q", SortByFirstSeen, SortByPublished)
+	}
+	if p.RefreshWindow != "" {
+		if _, err := timeutil.ParseWindow(p.RefreshWindow); err != nil {
+			return fmt.Errorf("cannot parse refresh_window: %v", err)
+		}
 	}
 	return nil
 }
@@ -138,14 +263,15 @@ func (f *Feed) Prune(n int, observedRawItems int) {
 	remainingItems := items[:n]
 	f.Items = make(map[string]*Item)
 	for _, item := range remainingItems {
-		f.Items[UID(item.URL)] = &item
+		f.Items[UID(cmp.Or(item.GUID, item.URL))] = &item
 	}
 }
 
-// Refresh updates the feed with information coming from raw items or an error
+// Refresh updates the feed with the result of a fetch operation or an error
 // coming from a fetcher, and then prunes the feed to the maximum number of
-// items.
-func (f *Feed) Refresh(items []RawItem, ts int64, fetchErr error) {
+// items. If the result indicates that the feed was not modified, only the
+// refresh time is updated.
+func (f *Feed) Refresh(res *FetchResult, fetchErr error) {
 	log := slog.With(slog.String("feedName", f.Name))
 	var fetchErrMsg string
 	if fetchErr != nil {
@@ -156,13 +282,34 @@ func (f *Feed) Refresh(items []RawItem, ts int64, fetchErr error) {
 		slog.String("fetchErr", fetchErrMsg),
 	)
 
+	f.LastRefreshErrorCode = 0
 	if fetchErr != nil {
 		f.LastRefreshError = fetchErr.Error()
+		var retryAfter time.Duration
+		var httpErr *HTTPError
+		if errors.As(fetchErr, &httpErr) {
+			f.LastRefreshErrorCode = httpErr.StatusCode
+			retryAfter = httpErr.RetryAfter
+		}
+		f.ConsecutiveFailures++
+		backoff := retryBackoff(f.ConsecutiveFailures, retryAfter)
+		f.NextAttemptAt = timeutil.Now() + int64(backoff/time.Second)
+		log.Info("backing off feed",
+			slog.Int("consecutiveFailures", f.ConsecutiveFailures),
+			slog.Duration("backoff", backoff),
+		)
 		return
 	} else {
 		f.LastRefreshError = ""
+		f.ConsecutiveFailures = 0
+		f.NextAttemptAt = 0
 	}
-	if len(items) == 0 {
+	if res != nil && res.NotModified {
+		f.LastRefreshedAt = res.Timestamp
+		log.Info("feed not modified")
+		return
+	}
+	if res == nil || len(res.Items) == 0 {
 		f.LastRefreshError = "no items found in the last refresh"
 		return
 	}
@@ -171,54 +318,232 @@ func (f *Feed) Refresh(items []RawItem, ts int64, fetchErr error) {
 		f.Items = make(map[string]*Item)
 	}
 
-	for i, item := range items {
+	var p feedParams
+	validParams := ParseParams(f.Params, &p) == nil
+	markUnreadOnUpdate := validParams && p.MarkUnreadOnUpdate
+	keepContent := validParams && p.FetchFullContent
+
+	guids := make(map[string]int)
+	for _, item := range res.Items {
+		if item.GUID != "" {
+			guids[item.GUID]++
+		}
+	}
+	for i, item := range res.Items {
 		if !item.IsValid() {
 			log.Info("detected invalid item in feed, skipping", slog.Int("itemPos", i))
 			continue
 		}
+		// Some publishers reuse GUIDs for different items, so they cannot be
+		// used to identify them, unless the item was stored under its GUID
+		// before the GUID was reused.
+		if guids[item.GUID] > 1 {
+			if existing := f.Items[UID(item.GUID)]; existing == nil || existing.URL != item.URL {
+				item.GUID = ""
+			}
+		}
+		uid := item.UID()
+		if f.Items[uid] == nil {
+			f.migrateItem(item)
+		}
 		// If the item was never seen before, add it with the current
 		// timestamp.
-		if f.Items[item.UID()] == nil {
-			f.Items[item.UID()] = &Item{
+		if f.Items[uid] == nil {
+			f.Items[uid] = &Item{
 				FeedUID:   f.UID(),
-				Timestamp: ts,
+				Timestamp: res.Timestamp,
+			}
+			f.Items[uid].Refresh(item)
+			if keepContent && item.FullContentFailed {
+				f.Items[uid].FullContentFailures = 1
 			}
+			continue
+		}
+		// Otherwise, keep the previous version of the item if it was edited.
+		existing := f.Items[uid]
+		// The full content of items is only fetched once, so the content in
+		// the feed must not replace it. If fetching it failed before, it was
+		// fetched again, and the full content is not an edit of the item.
+		if keepContent {
+			if existing.NeedsFullContent() {
+				if item.FullContentFailed {
+					existing.FullContentFailures++
+				} else {
+					existing.Content = item.Content
+					existing.FullContentFailures = 0
+				}
+			}
+			item.Content = existing.Content
+		}
+		prev := existing.revision()
+		if existing.Refresh(item) && prev.edited(existing.revision()) {
+			existing.addRevision(prev, res.Timestamp)
+			if markUnreadOnUpdate && prev.materiallyEdited(existing.revision()) {
+				existing.Read = false
+			}
+			log.Info("detected item update", slog.String("itemURL", existing.URL))
 		}
-		f.Items[item.UID()].Refresh(item)
 	}
-	f.LastRefreshedAt = ts
+	f.LastRefreshedAt = res.Timestamp
+	f.Hints = res.Hints
+	f.Metadata = res.Metadata
+	f.ETag = res.ETag
+	f.LastModified = res.LastModified
 
-	f.Prune(0, len(items))
+	f.Prune(0, len(res.Items))
 	log.Info("feed refreshed", slog.Int("nFeedItems", len(f.Items)))
 }
 
-// SortedItems returns the items in the feed sorted by timestamp, position,
-// URL and then feed UID in descending order.
+// migrateItem moves an item that was identified by its URL before it had a
+// GUID (e.g., items stored before GUIDs were used as identifiers) to be
+// identified by the GUID of the raw item r, keeping its state.
+func (f *Feed) migrateItem(r RawItem) {
+	if r.GUID == "" {
+		return
+	}
+	oldUID := UID(r.URL)
+	item, ok := f.Items[oldUID]
+	if !ok || item.GUID != "" {
+		return
+	}
+	delete(f.Items, oldUID)
+	item.GUID = r.GUID
+	f.Items[item.UID()] = item
+	slog.Info("migrated item to GUID-based identity",
+		slog.String("feedName", f.Name),
+		slog.String("itemURL", r.URL),
+	)
+}
+
+// ScheduleRefresh returns the time when the feed should be refreshed next,
+// given that it was last refreshed at now, and records it in NextRefreshAt
+// along with the interval used in RefreshInterval. The interval between
+// refreshes is taken from the refresh_interval param, or defaultInterval if it
+// is not set.
+// Unless the ignore_hints param is set, the interval is extended to respect
+// the update hints declared by the publisher (up to maxHintInterval), and
+// hours and days the publisher asked to skip are avoided. A random jitter of
+// up to maxJitter (but never more than a tenth of the interval) is added to
+// spread refreshes over time.
+// The result never precedes NextAttemptAt, and it is moved into the
+// refresh_window param if one is set.
+func (f *Feed) ScheduleRefresh(now time.Time, defaultInterval, maxJitter time.Duration) time.Time {
+	interval := defaultInterval
+	var window *timeutil.Window
+	var p feedParams
+	if err := ParseParams(f.Params, &p); err == nil {
+		if d, err := time.ParseDuration(p.RefreshInterval); err == nil {
+			interval = d
+		}
+		if w, err := timeutil.ParseWindow(p.RefreshWindow); err == nil {
+			window = w
+		}
+	}
+	hints := f.Hints
+	if p.IgnoreHints {
+		hints = nil
+	}
+	if hints != nil {
+		interval = max(interval, hints.minInterval())
+	}
+
+	next := now.Add(interval)
+	if jitter := min(maxJitter, interval/10); jitter > 0 {
+		next = next.Add(rand.N(jitter))
+	}
+	if f.NextAttemptAt != 0 && next.Unix() < f.NextAttemptAt {
+		next = time.Unix(f.NextAttemptAt, 0)
+	}
+
+	// The window and the skip hints may move the time out of each other, so
+	// they are applied until they agree. If they never do, the last result
+	// is used.
+	for range 7 * 24 {
+		candidate := next
+		if window != nil {
+			candidate = window.Next(candidate)
+		}
+		if hints != nil {
+			candidate = hints.nextAllowed(candidate)
+		}
+		if candidate.Equal(next) {
+			break
+		}
+		next = candidate
+	}
+
+	f.RefreshInterval = int64(interval / time.Second)
+	f.NextRefreshAt = next.Unix()
+	return next
+}
+
+// retryBackoff returns how long to wait before retrying a feed that failed n
+// consecutive times. The delay grows exponentially with n up to a cap. A
+// retryAfter delay requested by the server is honored if it is longer.
+func retryBackoff(n int, retryAfter time.Duration) time.Duration {
+	backoff := minRetryBackoff
+	for i := 1; i < n && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxRetryBackoff)
+	return min(max(backoff, retryAfter), maxRetryAfter)
+}
+
+// SortedItems returns the items in the feed sorted by time, position, URL and
+// then feed UID in descending order. The time is when the item was first
+// seen, unless the sort_by param of the feed the item belongs to is set to
+// "published", in which case it is when the item was published (see
+// Item.sortTime).
 func (f *Feed) SortedItems() []Item {
-	var sortedItems []Item
-	for _, item := range f.Items {
-		sortedItems = append(sortedItems, *item)
+	type sortableItem struct {
+		Item
+		time int64
 	}
-	sort.Slice(sortedItems, func(i, j int) bool {
-		if sortedItems[i].Timestamp == sortedItems[j].Timestamp &&
-			sortedItems[i].Position == sortedItems[j].Position &&
-			sortedItems[i].URL == sortedItems[j].URL {
-			return sortedItems[i].FeedUID < sortedItems[j].FeedUID
+
+	byPublished := map[*Feed]bool{f: f.sortByPublished()}
+	var sortableItems []sortableItem
+	for key, item := range f.Items {
+		origin := cmp.Or(f.itemFeeds[key], f)
+		if _, ok := byPublished[origin]; !ok {
+			byPublished[origin] = origin.sortByPublished()
+		}
+		sortableItems = append(sortableItems, sortableItem{*item, item.sortTime(byPublished[origin])})
+	}
+	sort.Slice(sortableItems, func(i, j int) bool {
+		if sortableItems[i].time == sortableItems[j].time &&
+			sortableItems[i].Position == sortableItems[j].Position &&
+			sortableItems[i].URL == sortableItems[j].URL {
+			return sortableItems[i].FeedUID < sortableItems[j].FeedUID
 		}
 
-		if sortedItems[i].Timestamp == sortedItems[j].Timestamp &&
-			sortedItems[i].Position == sortedItems[j].Position {
-			return sortedItems[i].URL < sortedItems[j].URL
+		if sortableItems[i].time == sortableItems[j].time &&
+			sortableItems[i].Position == sortableItems[j].Position {
+			return sortableItems[i].URL < sortableItems[j].URL
 		}
 
-		if sortedItems[i].Timestamp == sortedItems[j].Timestamp {
-			return sortedItems[i].Position < sortedItems[j].Position
+		if sortableItems[i].time == sortableItems[j].time {
+			return sortableItems[i].Position < sortableItems[j].Position
 		}
-		return sortedItems[i].Timestamp > sortedItems[j].Timestamp
+		return sortableItems[i].time > sortableItems[j].time
 	})
+
+	var sortedItems []Item
+	for _, item := range sortableItems {
+		sortedItems = append(sortedItems, item.Item)
+	}
 	return sortedItems
 }
 
+// sortByPublished returns true if the sort_by param of the feed is set to
+// "published".
+func (f *Feed) sortByPublished() bool {
+	var p feedParams
+	if err := ParseParams(f.Params, &p); err != nil {
+		return false
+	}
+	return p.SortBy == SortByPublished
+}
+
 // MarkAllRead marks all feed items as read if their timestamp is less than or
 // equal to the given before timestamp.
 func (f *Feed) MarkAllRead(before int64) {
@@ -253,16 +578,30 @@ func (f *Feed) Summary(withItems bool) *FeedSummary {
 		}
 	}
 
+	var description, siteURL string
+	if f.Metadata != nil {
+		description = f.Metadata.Description
+		siteURL = f.Metadata.SiteURL
+	}
+
 	return &FeedSummary{
-		UID:         f.UID(),
-		URL:         f.URL,
-		Name:        f.Name,
-		Items:       itemSummaries,
-		LastUpdated: f.LastRefreshedAt,
-		LastError:   f.LastRefreshError,
-		ItemCount:   len(items),
-		ReadCount:   readCount,
-		LastItem:    lastItemTimestamp,
+		UID:                 f.UID(),
+		URL:                 f.URL,
+		Name:                f.title(),
+		Description:         description,
+		SiteURL:             siteURL,
+		IconURL:             f.iconPath(),
+		Items:               itemSummaries,
+		LastUpdated:         f.LastRefreshedAt,
+		LastError:           f.LastRefreshError,
+		LastErrorCode:       f.LastRefreshErrorCode,
+		ConsecutiveFailures: f.ConsecutiveFailures,
+		NextAttemptAt:       f.NextAttemptAt,
+		RefreshInterval:     f.RefreshInterval,
+		NextRefreshAt:       f.NextRefreshAt,
+		ItemCount:           len(items),
+		ReadCount:           readCount,
+		LastItem:            lastItemTimestamp,
 	}
 }
 
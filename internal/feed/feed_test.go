@@ -1,8 +1,12 @@
 package feed_test
 
 import (
+	"errors"
 	"fmt"
 	"maps"
+	"math"
+	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -17,7 +21,7 @@ func checkFeedItems(t *testing.T, feed feed.Feed, expectedItems []feed.Item) {
 	}
 	sortedItems := feed.SortedItems()
 	for i, expectedItem := range expectedItems {
-		if sortedItems[i] != expectedItem {
+		if !reflect.DeepEqual(sortedItems[i], expectedItem) {
 			t.Errorf("expected item %#v, got %#v", expectedItem, sortedItems[i])
 		}
 	}
@@ -39,6 +43,24 @@ func checkFeed(t *testing.T, feed feed.Feed, expectedFeed feed.Feed) {
 	if feed.LastRefreshError != expectedFeed.LastRefreshError {
 		t.Errorf("expected last refresh error %v, got %v", expectedFeed.LastRefreshError, feed.LastRefreshError)
 	}
+	if feed.LastRefreshErrorCode != expectedFeed.LastRefreshErrorCode {
+		t.Errorf("expected last refresh error code %v, got %v", expectedFeed.LastRefreshErrorCode, feed.LastRefreshErrorCode)
+	}
+	if feed.ETag != expectedFeed.ETag {
+		t.Errorf("expected ETag %v, got %v", expectedFeed.ETag, feed.ETag)
+	}
+	if feed.LastModified != expectedFeed.LastModified {
+		t.Errorf("expected last modified %v, got %v", expectedFeed.LastModified, feed.LastModified)
+	}
+	if !reflect.DeepEqual(feed.Hints, expectedFeed.Hints) {
+		t.Errorf("expected hints %#v, got %#v", expectedFeed.Hints, feed.Hints)
+	}
+	if !reflect.DeepEqual(feed.Metadata, expectedFeed.Metadata) {
+		t.Errorf("expected metadata %#v, got %#v", expectedFeed.Metadata, feed.Metadata)
+	}
+	if keys, expectedKeys := slices.Sorted(maps.Keys(feed.Items)), slices.Sorted(maps.Keys(expectedFeed.Items)); !slices.Equal(keys, expectedKeys) {
+		t.Errorf("expected item keys %v, got %v", expectedKeys, keys)
+	}
 	checkFeedItems(t, feed, expectedFeed.SortedItems())
 }
 
@@ -225,6 +247,7 @@ func TestFeedSortedItems(t *testing.T) {
 	tests := []struct {
 		desc string
 
+		params       any
 		initialItems []feed.Item
 
 		expectedItems []feed.Item
@@ -288,12 +311,49 @@ func TestFeedSortedItems(t *testing.T) {
 			{RawItem: feed.RawItem{URL: "url4", Position: 4}, Timestamp: now},
 			{RawItem: feed.RawItem{URL: "url5", Position: 5}, Timestamp: now},
 		},
+	}, {
+		desc: "published dates are ignored by default",
+		initialItems: []feed.Item{
+			{RawItem: feed.RawItem{URL: "url1", Published: timeutil.HoursAgo(now, 2)}, Timestamp: now},
+			{RawItem: feed.RawItem{URL: "url2", Published: timeutil.HoursAgo(now, 1)}, Timestamp: timeutil.HoursAgo(now, 1)},
+		},
+		expectedItems: []feed.Item{
+			{RawItem: feed.RawItem{URL: "url1", Published: timeutil.HoursAgo(now, 2)}, Timestamp: now},
+			{RawItem: feed.RawItem{URL: "url2", Published: timeutil.HoursAgo(now, 1)}, Timestamp: timeutil.HoursAgo(now, 1)},
+		},
+	}, {
+		desc:   "sorted by published date",
+		params: map[string]any{"sort_by": "published"},
+		initialItems: []feed.Item{
+			{RawItem: feed.RawItem{URL: "url1", Published: timeutil.HoursAgo(now, 48)}, Timestamp: now},
+			{RawItem: feed.RawItem{URL: "url2", Published: timeutil.HoursAgo(now, 24)}, Timestamp: now},
+			{RawItem: feed.RawItem{URL: "url3", Updated: timeutil.HoursAgo(now, 36)}, Timestamp: now},
+			{RawItem: feed.RawItem{URL: "url4"}, Timestamp: timeutil.HoursAgo(now, 30)},
+		},
+		expectedItems: []feed.Item{
+			{RawItem: feed.RawItem{URL: "url2", Published: timeutil.HoursAgo(now, 24)}, Timestamp: now},
+			{RawItem: feed.RawItem{URL: "url4"}, Timestamp: timeutil.HoursAgo(now, 30)},
+			{RawItem: feed.RawItem{URL: "url3", Updated: timeutil.HoursAgo(now, 36)}, Timestamp: now},
+			{RawItem: feed.RawItem{URL: "url1", Published: timeutil.HoursAgo(now, 48)}, Timestamp: now},
+		},
+	}, {
+		desc:   "published dates in the future are not trusted",
+		params: map[string]any{"sort_by": "published"},
+		initialItems: []feed.Item{
+			{RawItem: feed.RawItem{URL: "url1", Published: now + 3600}, Timestamp: timeutil.HoursAgo(now, 2)},
+			{RawItem: feed.RawItem{URL: "url2", Published: timeutil.HoursAgo(now, 1)}, Timestamp: now},
+		},
+		expectedItems: []feed.Item{
+			{RawItem: feed.RawItem{URL: "url2", Published: timeutil.HoursAgo(now, 1)}, Timestamp: now},
+			{RawItem: feed.RawItem{URL: "url1", Published: now + 3600}, Timestamp: timeutil.HoursAgo(now, 2)},
+		},
 	}}
 
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
 			f := feed.Feed{
-				Items: make(map[string]*feed.Item),
+				Items:  make(map[string]*feed.Item),
+				Params: test.params,
 			}
 			for _, item := range test.initialItems {
 				f.Items[feed.UID(item.URL)] = &item
@@ -304,7 +364,7 @@ func TestFeedSortedItems(t *testing.T) {
 				return
 			}
 			for i, expectedItem := range test.expectedItems {
-				if sortedItems[i] != expectedItem {
+				if !reflect.DeepEqual(sortedItems[i], expectedItem) {
 					t.Errorf("expected item %#v, got %#v", expectedItem, sortedItems[i])
 				}
 			}
@@ -312,6 +372,33 @@ func TestFeedSortedItems(t *testing.T) {
 	}
 }
 
+func TestVirtualFeedSortedItems(t *testing.T) {
+	now := time.Now().Unix()
+
+	byPublished := &feed.Feed{
+		Name:   "Feed 1",
+		URL:    "url1",
+		Params: map[string]any{"sort_by": "published"},
+		Items: map[string]*feed.Item{
+			feed.UID("item1"): {RawItem: feed.RawItem{URL: "item1", Published: timeutil.HoursAgo(now, 10)}, FeedUID: feed.UID("url1"), Timestamp: now},
+		},
+	}
+	byFirstSeen := &feed.Feed{
+		Name: "Feed 2",
+		URL:  "url2",
+		Items: map[string]*feed.Item{
+			feed.UID("item2"): {RawItem: feed.RawItem{URL: "item2", Published: timeutil.HoursAgo(now, 20)}, FeedUID: feed.UID("url2"), Timestamp: timeutil.HoursAgo(now, 1)},
+		},
+	}
+
+	// Each item is sorted according to the params of its own feed.
+	f := feed.NewVirtualFeed("All", feed.AllItems(slices.Values([]*feed.Feed{byPublished, byFirstSeen})))
+	sortedItems := f.SortedItems()
+	if len(sortedItems) != 2 || sortedItems[0].URL != "item2" || sortedItems[1].URL != "item1" {
+		t.Errorf("expected items sorted as [item2 item1], got %#v", sortedItems)
+	}
+}
+
 func TestMarkAllRead(t *testing.T) {
 	now := time.Now().Unix()
 
@@ -461,6 +548,29 @@ func TestSummary(t *testing.T) {
 			LastUpdated: now,
 			LastItem:    now,
 		},
+	}, {
+		desc: "Feed with an HTTP error",
+		feeds: map[string]*feed.Feed{
+			"feed1": {
+				Name:                 "Feed 1",
+				URL:                  "url1",
+				Type:                 "xml",
+				Items:                map[string]*feed.Item{},
+				LastRefreshedAt:      now,
+				LastRefreshError:     "unexpected HTTP status 404 Not Found from url1",
+				LastRefreshErrorCode: 404,
+			},
+		},
+		realFeed:  "feed1",
+		withItems: false,
+		expectedSummary: &feed.FeedSummary{
+			UID:           feed.UID("url1"),
+			Name:          "Feed 1",
+			URL:           "url1",
+			LastUpdated:   now,
+			LastError:     "unexpected HTTP status 404 Not Found from url1",
+			LastErrorCode: 404,
+		},
 	}, {
 		desc: "A virtual feed named 'virtual' with items and a valid itemMapper pointing at two other feeds",
 		feeds: map[string]*feed.Feed{
@@ -494,6 +604,93 @@ func TestSummary(t *testing.T) {
 			LastUpdated: now,
 			LastItem:    now,
 		},
+	}, {
+		desc: "Feed with a scheduled refresh",
+		feeds: map[string]*feed.Feed{
+			"feed1": {
+				Name:            "Feed 1",
+				URL:             "url1",
+				Type:            "xml",
+				Items:           map[string]*feed.Item{},
+				LastRefreshedAt: now,
+				RefreshInterval: 3600,
+				NextRefreshAt:   now + 3600,
+			},
+		},
+		realFeed: "feed1",
+		expectedSummary: &feed.FeedSummary{
+			UID:             feed.UID("url1"),
+			Name:            "Feed 1",
+			URL:             "url1",
+			LastUpdated:     now,
+			RefreshInterval: 3600,
+			NextRefreshAt:   now + 3600,
+		},
+	}, {
+		desc: "Feed without a name uses the title from its metadata",
+		feeds: map[string]*feed.Feed{
+			"feed1": {
+				URL:   "url1",
+				Type:  "xml",
+				Items: map[string]*feed.Item{},
+				Metadata: &feed.Metadata{
+					Title:       "Publisher Title",
+					Description: "About the feed",
+					SiteURL:     "https://example.com",
+					IconURL:     "https://example.com/favicon.ico",
+				},
+				Icon: &feed.Icon{
+					URL:       "https://example.com/favicon.ico",
+					MimeType:  "image/x-icon",
+					Data:      []byte("icon"),
+					FetchedAt: now,
+				},
+				LastRefreshedAt: now,
+			},
+		},
+		realFeed: "feed1",
+		expectedSummary: &feed.FeedSummary{
+			UID:         feed.UID("url1"),
+			Name:        "Publisher Title",
+			URL:         "url1",
+			Description: "About the feed",
+			SiteURL:     "https://example.com",
+			IconURL:     fmt.Sprintf("/api/feeds/%s/icon?v=%d", feed.UID("url1"), now),
+			LastUpdated: now,
+		},
+	}, {
+		desc: "Feed with a name, metadata and an icon that could not be fetched",
+		feeds: map[string]*feed.Feed{
+			"feed1": {
+				Name:     "Feed 1",
+				URL:      "url1",
+				Type:     "xml",
+				Items:    map[string]*feed.Item{},
+				Metadata: &feed.Metadata{Title: "Publisher Title", IconURL: "https://example.com/favicon.ico"},
+				Icon:     &feed.Icon{URL: "https://example.com/favicon.ico", FetchedAt: now},
+			},
+		},
+		realFeed: "feed1",
+		expectedSummary: &feed.FeedSummary{
+			UID:  feed.UID("url1"),
+			Name: "Feed 1",
+			URL:  "url1",
+		},
+	}, {
+		desc: "Feed without a name or metadata uses its URL",
+		feeds: map[string]*feed.Feed{
+			"feed1": {
+				URL:   "url1",
+				Type:  "xml",
+				Items: map[string]*feed.Item{},
+			},
+		},
+		realFeed: "feed1",
+		expectedSummary: &feed.FeedSummary{
+			UID:  feed.UID("url1"),
+			Name: "url1",
+			URL:  "url1",
+		},
 	}}
 
 	for _, test := range tests {
@@ -511,7 +708,14 @@ func TestSummary(t *testing.T) {
 				summary.ItemCount != test.expectedSummary.ItemCount ||
 				summary.ReadCount != test.expectedSummary.ReadCount ||
 				summary.LastUpdated != test.expectedSummary.LastUpdated ||
-				summary.LastItem != test.expectedSummary.LastItem {
+				summary.LastItem != test.expectedSummary.LastItem ||
+				summary.LastError != test.expectedSummary.LastError ||
+				summary.LastErrorCode != test.expectedSummary.LastErrorCode ||
+				summary.RefreshInterval != test.expectedSummary.RefreshInterval ||
+				summary.NextRefreshAt != test.expectedSummary.NextRefreshAt ||
+				summary.Description != test.expectedSummary.Description ||
+				summary.SiteURL != test.expectedSummary.SiteURL ||
+				summary.IconURL != test.expectedSummary.IconURL {
 				t.Errorf("expected summary %#v, got %#v", test.expectedSummary, summary)
 			}
 
@@ -529,7 +733,7 @@ func TestFeedRefresh(t *testing.T) {
 		desc string
 
 		initialFeed feed.Feed
-		items       []feed.RawItem
+		result      *feed.FetchResult
 		fetchErr    error
 
 		expectedFeed   feed.Feed
@@ -541,7 +745,7 @@ func TestFeedRefresh(t *testing.T) {
 			URL:   "url1",
 			Items: map[string]*feed.Item{},
 		},
-		items:    nil,
+		result:   nil,
 		fetchErr: fmt.Errorf("fetch error"),
 		expectedFeed: feed.Feed{
 			Name:             "Feed 1",
@@ -549,6 +753,46 @@ func TestFeedRefresh(t *testing.T) {
 			Items:            map[string]*feed.Item{},
 			LastRefreshError: "fetch error",
 		},
+	}, {
+		desc: "fetchErr is an HTTP error",
+		initialFeed: feed.Feed{
+			Name:  "Feed 1",
+			URL:   "url1",
+			Items: map[string]*feed.Item{},
+		},
+		result:   nil,
+		fetchErr: fmt.Errorf("wrapped: %w", &feed.HTTPError{StatusCode: 410, URL: "url1"}),
+		expectedFeed: feed.Feed{
+			Name:                 "Feed 1",
+			URL:                  "url1",
+			Items:                map[string]*feed.Item{},
+			LastRefreshError:     "wrapped: unexpected HTTP status 410 Gone from url1",
+			LastRefreshErrorCode: 410,
+		},
+	}, {
+		desc: "successful refresh clears previous error code",
+		initialFeed: feed.Feed{
+			Name:                 "Feed 1",
+			URL:                  "url1",
+			Items:                map[string]*feed.Item{},
+			LastRefreshError:     "unexpected HTTP status 503 Service Unavailable from url1",
+			LastRefreshErrorCode: 503,
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "url1", Title: "Title 1"},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now},
+			},
+			LastRefreshedAt: now,
+		},
 	}, {
 		desc: "the feed had no items and items is nil and fetchErr is nil",
 		initialFeed: feed.Feed{
@@ -556,7 +800,7 @@ func TestFeedRefresh(t *testing.T) {
 			URL:   "url1",
 			Items: map[string]*feed.Item{},
 		},
-		items:    nil,
+		result:   &feed.FetchResult{Timestamp: now},
 		fetchErr: nil,
 		expectedFeed: feed.Feed{
 			Name:             "Feed 1",
@@ -571,8 +815,11 @@ func TestFeedRefresh(t *testing.T) {
 			URL:   "url1",
 			Items: map[string]*feed.Item{},
 		},
-		items: []feed.RawItem{
-			{URL: "url1", Title: "Title 1"},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "url1", Title: "Title 1"},
+			},
+			Timestamp: now,
 		},
 		fetchErr: nil,
 		expectedFeed: feed.Feed{
@@ -592,17 +839,26 @@ func TestFeedRefresh(t *testing.T) {
 				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1", Position: 0}, FeedUID: feed.UID("url1"), Timestamp: now},
 			},
 		},
-		items: []feed.RawItem{
-			{URL: "url1", Title: "Updated Title 1", Position: 0},
-			{URL: "url2", Title: "Title 2", Position: 1},
-			{URL: "", Title: "Invalid Item", Position: 2},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "url1", Title: "Updated Title 1", Position: 0},
+				{URL: "url2", Title: "Title 2", Position: 1},
+				{URL: "", Title: "Invalid Item", Position: 2},
+			},
+			Timestamp: now,
 		},
 		fetchErr: nil,
 		expectedFeed: feed.Feed{
 			Name: "Feed 1",
 			URL:  "url1",
 			Items: map[string]*feed.Item{
-				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Updated Title 1", Position: 0}, FeedUID: feed.UID("url1"), Timestamp: now},
+				feed.UID("url1"): {
+					RawItem:   feed.RawItem{URL: "url1", Title: "Updated Title 1", Position: 0},
+					FeedUID:   feed.UID("url1"),
+					Timestamp: now,
+					UpdatedAt: now,
+					Revisions: []feed.Revision{{Timestamp: now, Title: "Title 1"}},
+				},
 				feed.UID("url2"): {RawItem: feed.RawItem{URL: "url2", Title: "Title 2", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now},
 			},
 			LastRefreshedAt: now,
@@ -615,8 +871,36 @@ func TestFeedRefresh(t *testing.T) {
 			Items:            map[string]*feed.Item{},
 			LastRefreshError: "previous error",
 		},
-		items: []feed.RawItem{
-			{URL: "url1", Title: "Title 1"},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "url1", Title: "Title 1"},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now},
+			},
+			LastRefreshedAt:  now,
+			LastRefreshError: "",
+		},
+	}, {
+		desc: "successful refresh stores cache validators",
+		initialFeed: feed.Feed{
+			Name:  "Feed 1",
+			URL:   "url1",
+			Items: map[string]*feed.Item{},
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "url1", Title: "Title 1"},
+			},
+			Timestamp:    now,
+			ETag:         `"abc"`,
+			LastModified: "Mon, 02 Jan 2006 15:04:05 GMT",
 		},
 		fetchErr: nil,
 		expectedFeed: feed.Feed{
@@ -625,20 +909,711 @@ func TestFeedRefresh(t *testing.T) {
 			Items: map[string]*feed.Item{
 				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now},
 			},
+			LastRefreshedAt: now,
+			ETag:            `"abc"`,
+			LastModified:    "Mon, 02 Jan 2006 15:04:05 GMT",
+		},
+	}, {
+		desc: "not modified result keeps items and clears previous error",
+		initialFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now - 10},
+			},
+			LastRefreshedAt:  now - 10,
+			LastRefreshError: "previous error",
+			ETag:             `"abc"`,
+		},
+		result: &feed.FetchResult{
+			Timestamp:   now,
+			NotModified: true,
+			ETag:        `"abc"`,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now - 10},
+			},
 			LastRefreshedAt:  now,
 			LastRefreshError: "",
+			ETag:             `"abc"`,
+		},
+	}, {
+		desc: "successful refresh stores update hints",
+		initialFeed: feed.Feed{
+			Name:  "Feed 1",
+			URL:   "url1",
+			Items: map[string]*feed.Item{},
+			Hints: &feed.UpdateHints{TTL: 60},
+		},
+		result: &feed.FetchResult{
+			Items:     []feed.RawItem{{URL: "url1", Title: "Title 1"}},
+			Timestamp: now,
+			Hints:     &feed.UpdateHints{TTL: 3600, SkipDays: []time.Weekday{time.Sunday}},
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now},
+			},
+			LastRefreshedAt: now,
+			Hints:           &feed.UpdateHints{TTL: 3600, SkipDays: []time.Weekday{time.Sunday}},
+		},
+	}, {
+		desc: "successful refresh stores metadata",
+		initialFeed: feed.Feed{
+			URL:      "url1",
+			Items:    map[string]*feed.Item{},
+			Metadata: &feed.Metadata{Title: "Old Title"},
+		},
+		result: &feed.FetchResult{
+			Items:     []feed.RawItem{{URL: "url1", Title: "Title 1"}},
+			Timestamp: now,
+			Metadata:  &feed.Metadata{Title: "New Title", SiteURL: "https://example.com"},
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			URL: "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now},
+			},
+			LastRefreshedAt: now,
+			Metadata:        &feed.Metadata{Title: "New Title", SiteURL: "https://example.com"},
+		},
+	}, {
+		desc: "failed refresh keeps metadata",
+		initialFeed: feed.Feed{
+			URL:      "url1",
+			Items:    map[string]*feed.Item{},
+			Metadata: &feed.Metadata{Title: "Title"},
+		},
+		fetchErr: errors.New("network error"),
+		expectedFeed: feed.Feed{
+			URL:              "url1",
+			Items:            map[string]*feed.Item{},
+			LastRefreshError: "network error",
+			Metadata:         &feed.Metadata{Title: "Title"},
+		},
+	}, {
+		desc: "not modified keeps update hints",
+		initialFeed: feed.Feed{
+			Name:  "Feed 1",
+			URL:   "url1",
+			Items: map[string]*feed.Item{},
+			Hints: &feed.UpdateHints{TTL: 3600},
+		},
+		result: &feed.FetchResult{
+			Timestamp:   now,
+			NotModified: true,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name:            "Feed 1",
+			URL:             "url1",
+			Items:           map[string]*feed.Item{},
+			LastRefreshedAt: now,
+			Hints:           &feed.UpdateHints{TTL: 3600},
+		},
+	}, {
+		desc: "items with GUIDs keep their identity when their URLs change",
+		initialFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("guid1"): {RawItem: feed.RawItem{GUID: "guid1", URL: "http://url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+			},
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{GUID: "guid1", URL: "https://url1?utm_source=feed", Title: "Title 1"},
+				{GUID: "guid2", URL: "https://url2", Title: "Title 2", Position: 1},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("guid1"): {RawItem: feed.RawItem{GUID: "guid1", URL: "https://url1?utm_source=feed", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+				feed.UID("guid2"): {RawItem: feed.RawItem{GUID: "guid2", URL: "https://url2", Title: "Title 2", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now},
+			},
+			LastRefreshedAt: now,
+		},
+	}, {
+		desc: "items identified by URL are migrated to GUIDs",
+		initialFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("http://url1"): {RawItem: feed.RawItem{URL: "http://url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+				feed.UID("http://url2"): {RawItem: feed.RawItem{URL: "http://url2", Title: "Title 2", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+			},
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{GUID: "guid1", URL: "http://url1", Title: "Title 1"},
+				{GUID: "http://url2", URL: "http://url2", Title: "Title 2", Position: 1},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("guid1"):       {RawItem: feed.RawItem{GUID: "guid1", URL: "http://url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+				feed.UID("http://url2"): {RawItem: feed.RawItem{GUID: "http://url2", URL: "http://url2", Title: "Title 2", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+			},
+			LastRefreshedAt: now,
+		},
+	}, {
+		desc: "items with duplicate GUIDs are identified by URL",
+		initialFeed: feed.Feed{
+			Name:  "Feed 1",
+			URL:   "url1",
+			Items: map[string]*feed.Item{},
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{GUID: "same", URL: "http://url1", Title: "Title 1"},
+				{GUID: "same", URL: "http://url2", Title: "Title 2", Position: 1},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("http://url1"): {RawItem: feed.RawItem{URL: "http://url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now},
+				feed.UID("http://url2"): {RawItem: feed.RawItem{URL: "http://url2", Title: "Title 2", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now},
+			},
+			LastRefreshedAt: now,
+		},
+	}, {
+		desc: "items stored under a GUID that becomes duplicate keep their identity",
+		initialFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("same"): {RawItem: feed.RawItem{GUID: "same", URL: "http://url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+			},
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{GUID: "same", URL: "http://url1", Title: "Title 1"},
+				{GUID: "same", URL: "http://url2", Title: "Title 2", Position: 1},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("same"):        {RawItem: feed.RawItem{GUID: "same", URL: "http://url1", Title: "Title 1"}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+				feed.UID("http://url2"): {RawItem: feed.RawItem{URL: "http://url2", Title: "Title 2", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now},
+			},
+			LastRefreshedAt: now,
+		},
+	}, {
+		desc: "edited items keep their previous revisions",
+		initialFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {
+					RawItem:   feed.RawItem{URL: "url1", Title: "Title 1", Content: "<p>v6</p>"},
+					FeedUID:   feed.UID("url1"),
+					Timestamp: now - 600,
+					Read:      true,
+					UpdatedAt: now - 100,
+					Revisions: []feed.Revision{
+						{Timestamp: now - 600, Title: "Title 1", Content: "<p>v1</p>"},
+						{Timestamp: now - 500, Title: "Title 1", Content: "<p>v2</p>"},
+						{Timestamp: now - 400, Title: "Title 1", Content: "<p>v3</p>"},
+						{Timestamp: now - 300, Title: "Title 1", Content: "<p>v4</p>"},
+						{Timestamp: now - 200, Title: "Title 1", Content: "<p>v5</p>"},
+					},
+				},
+				feed.UID("url2"): {RawItem: feed.RawItem{URL: "url2", Title: "Title 2", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now - 600, Read: true},
+			},
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "url1", Title: "Title 1", Content: "<p>v7</p>"},
+				{URL: "url2", Title: "Title 2", Authors: "Author", Position: 1},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name: "Feed 1",
+			URL:  "url1",
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {
+					RawItem:   feed.RawItem{URL: "url1", Title: "Title 1", Content: "<p>v7</p>"},
+					FeedUID:   feed.UID("url1"),
+					Timestamp: now - 600,
+					Read:      true,
+					UpdatedAt: now,
+					Revisions: []feed.Revision{
+						{Timestamp: now - 500, Title: "Title 1", Content: "<p>v2</p>"},
+						{Timestamp: now - 400, Title: "Title 1", Content: "<p>v3</p>"},
+						{Timestamp: now - 300, Title: "Title 1", Content: "<p>v4</p>"},
+						{Timestamp: now - 200, Title: "Title 1", Content: "<p>v5</p>"},
+						{Timestamp: now - 100, Title: "Title 1", Content: "<p>v6</p>"},
+					},
+				},
+				feed.UID("url2"): {
+					RawItem:   feed.RawItem{URL: "url2", Title: "Title 2", Authors: "Author", Position: 1},
+					FeedUID:   feed.UID("url1"),
+					Timestamp: now - 600,
+					Read:      true,
+					UpdatedAt: now,
+					Revisions: []feed.Revision{{Timestamp: now - 600, Title: "Title 2"}},
+				},
+			},
+			LastRefreshedAt: now,
+		},
+	}, {
+		desc: "materially edited items are marked as unread with mark_unread_on_update",
+		initialFeed: feed.Feed{
+			Name:   "Feed 1",
+			URL:    "url1",
+			Params: map[string]any{"mark_unread_on_update": true},
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1", Content: "<p>Some text</p>"}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+				feed.UID("url2"): {RawItem: feed.RawItem{URL: "url2", Title: "Title 2", Content: "<p>Some text</p>", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+				feed.UID("url3"): {RawItem: feed.RawItem{URL: "url3", Title: "Title 3", Position: 2}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+			},
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "url1", Title: "Title 1", Content: "<p>Some other text</p>"},
+				{URL: "url2", Title: "Title 2", Content: "<p>Some <b>text</b></p>", Position: 1},
+				{URL: "url3", Title: "Title 3", Published: now, Position: 2},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name:   "Feed 1",
+			URL:    "url1",
+			Params: map[string]any{"mark_unread_on_update": true},
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {
+					RawItem:   feed.RawItem{URL: "url1", Title: "Title 1", Content: "<p>Some other text</p>"},
+					FeedUID:   feed.UID("url1"),
+					Timestamp: now - 100,
+					UpdatedAt: now,
+					Revisions: []feed.Revision{{Timestamp: now - 100, Title: "Title 1", Content: "<p>Some text</p>"}},
+				},
+				feed.UID("url2"): {
+					RawItem:   feed.RawItem{URL: "url2", Title: "Title 2", Content: "<p>Some <b>text</b></p>", Position: 1},
+					FeedUID:   feed.UID("url1"),
+					Timestamp: now - 100,
+					Read:      true,
+					UpdatedAt: now,
+					Revisions: []feed.Revision{{Timestamp: now - 100, Title: "Title 2", Content: "<p>Some text</p>"}},
+				},
+				feed.UID("url3"): {RawItem: feed.RawItem{URL: "url3", Title: "Title 3", Published: now, Position: 2}, FeedUID: feed.UID("url1"), Timestamp: now - 100, Read: true},
+			},
+			LastRefreshedAt: now,
+		},
+	}, {
+		desc: "full content of existing items is kept with fetch_full_content",
+		initialFeed: feed.Feed{
+			Name:   "Feed 1",
+			URL:    "url1",
+			Params: map[string]any{"fetch_full_content": true},
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1", Content: "<p>Full text</p>"}, FeedUID: feed.UID("url1"), Timestamp: now - 100},
+			},
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "url1", Title: "Title 1 edited", Content: "<p>Summary</p>"},
+				{URL: "url2", Title: "Title 2", Content: "<p>Full text 2</p>", Position: 1},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name:   "Feed 1",
+			URL:    "url1",
+			Params: map[string]any{"fetch_full_content": true},
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {
+					RawItem:   feed.RawItem{URL: "url1", Title: "Title 1 edited", Content: "<p>Full text</p>"},
+					FeedUID:   feed.UID("url1"),
+					Timestamp: now - 100,
+					UpdatedAt: now,
+					Revisions: []feed.Revision{{Timestamp: now - 100, Title: "Title 1", Content: "<p>Full text</p>"}},
+				},
+				feed.UID("url2"): {RawItem: feed.RawItem{URL: "url2", Title: "Title 2", Content: "<p>Full text 2</p>", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now},
+			},
+			LastRefreshedAt: now,
+		},
+	}, {
+		desc: "failed full content fetches are recorded and retried",
+		initialFeed: feed.Feed{
+			Name:   "Feed 1",
+			URL:    "url1",
+			Params: map[string]any{"fetch_full_content": true},
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1", Content: "<p>Summary 1</p>"}, FeedUID: feed.UID("url1"), Timestamp: now - 100, FullContentFailures: 1},
+				feed.UID("url2"): {RawItem: feed.RawItem{URL: "url2", Title: "Title 2", Content: "<p>Summary 2</p>", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now - 100, FullContentFailures: 2},
+				feed.UID("url3"): {RawItem: feed.RawItem{URL: "url3", Title: "Title 3", Content: "<p>Summary 3</p>", Position: 2}, FeedUID: feed.UID("url1"), Timestamp: now - 100, FullContentFailures: 5},
+			},
+		},
+		result: &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "url1", Title: "Title 1", Content: "<p>Full text 1</p>"},
+				{URL: "url2", Title: "Title 2", Content: "<p>Summary 2</p>", Position: 1, FullContentFailed: true},
+				{URL: "url3", Title: "Title 3", Content: "<p>Summary 3</p>", Position: 2},
+				{URL: "url4", Title: "Title 4", Content: "<p>Summary 4</p>", Position: 3, FullContentFailed: true},
+			},
+			Timestamp: now,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name:   "Feed 1",
+			URL:    "url1",
+			Params: map[string]any{"fetch_full_content": true},
+			Items: map[string]*feed.Item{
+				feed.UID("url1"): {RawItem: feed.RawItem{URL: "url1", Title: "Title 1", Content: "<p>Full text 1</p>"}, FeedUID: feed.UID("url1"), Timestamp: now - 100},
+				feed.UID("url2"): {RawItem: feed.RawItem{URL: "url2", Title: "Title 2", Content: "<p>Summary 2</p>", Position: 1}, FeedUID: feed.UID("url1"), Timestamp: now - 100, FullContentFailures: 3},
+				feed.UID("url3"): {RawItem: feed.RawItem{URL: "url3", Title: "Title 3", Content: "<p>Summary 3</p>", Position: 2}, FeedUID: feed.UID("url1"), Timestamp: now - 100, FullContentFailures: 5},
+				feed.UID("url4"): {RawItem: feed.RawItem{URL: "url4", Title: "Title 4", Content: "<p>Summary 4</p>", Position: 3}, FeedUID: feed.UID("url1"), Timestamp: now, FullContentFailures: 1},
+			},
+			LastRefreshedAt: now,
+		},
+	}, {
+		desc: "empty result does not store cache validators",
+		initialFeed: feed.Feed{
+			Name:  "Feed 1",
+			URL:   "url1",
+			Items: map[string]*feed.Item{},
+		},
+		result: &feed.FetchResult{
+			Timestamp: now,
+			ETag:      `"abc"`,
+		},
+		fetchErr: nil,
+		expectedFeed: feed.Feed{
+			Name:             "Feed 1",
+			URL:              "url1",
+			Items:            map[string]*feed.Item{},
+			LastRefreshError: "no items found in the last refresh",
 		},
 	}}
 
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
 			f := test.initialFeed
-			f.Refresh(test.items, now, test.fetchErr)
+			f.Refresh(test.result, test.fetchErr)
 			checkFeed(t, f, test.expectedFeed)
 		})
 	}
 }
 
+func TestFeedRefreshBackoff(t *testing.T) {
+	tests := []struct {
+		desc string
+
+		initialFailures int
+		fetchErr        error
+
+		expectedFailures int
+		expectedBackoff  time.Duration
+	}{{
+		desc:             "first failure",
+		initialFailures:  0,
+		fetchErr:         errors.New("oh no"),
+		expectedFailures: 1,
+		expectedBackoff:  1 * time.Minute,
+	}, {
+		desc:             "fourth failure",
+		initialFailures:  3,
+		fetchErr:         errors.New("oh no"),
+		expectedFailures: 4,
+		expectedBackoff:  8 * time.Minute,
+	}, {
+		desc:             "many failures are capped",
+		initialFailures:  100,
+		fetchErr:         errors.New("oh no"),
+		expectedFailures: 101,
+		expectedBackoff:  6 * time.Hour,
+	}, {
+		desc:             "longer Retry-After is honored",
+		initialFailures:  0,
+		fetchErr:         &feed.HTTPError{StatusCode: 429, RetryAfter: 1 * time.Hour},
+		expectedFailures: 1,
+		expectedBackoff:  1 * time.Hour,
+	}, {
+		desc:             "shorter Retry-After is ignored",
+		initialFailures:  5,
+		fetchErr:         &feed.HTTPError{StatusCode: 503, RetryAfter: 1 * time.Second},
+		expectedFailures: 6,
+		expectedBackoff:  32 * time.Minute,
+	}, {
+		desc:             "Retry-After is capped",
+		initialFailures:  0,
+		fetchErr:         &feed.HTTPError{StatusCode: 503, RetryAfter: 1000 * time.Hour},
+		expectedFailures: 1,
+		expectedBackoff:  24 * time.Hour,
+	}, {
+		desc:             "success resets the backoff",
+		initialFailures:  5,
+		fetchErr:         nil,
+		expectedFailures: 0,
+		expectedBackoff:  0,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			f := feed.Feed{
+				Name:                "Feed 1",
+				URL:                 "url1",
+				ConsecutiveFailures: test.initialFailures,
+				NextAttemptAt:       timeutil.Now() + 3600,
+			}
+			var res *feed.FetchResult
+			if test.fetchErr == nil {
+				res = &feed.FetchResult{NotModified: true, Timestamp: timeutil.Now()}
+			}
+			before := timeutil.Now()
+			f.Refresh(res, test.fetchErr)
+			after := timeutil.Now()
+
+			if f.ConsecutiveFailures != test.expectedFailures {
+				t.Errorf("expected %d consecutive failures, got %d", test.expectedFailures, f.ConsecutiveFailures)
+			}
+			if test.expectedBackoff == 0 {
+				if f.NextAttemptAt != 0 {
+					t.Errorf("expected no next attempt time, got %d", f.NextAttemptAt)
+				}
+				return
+			}
+			backoff := int64(test.expectedBackoff / time.Second)
+			if f.NextAttemptAt < before+backoff || f.NextAttemptAt > after+backoff {
+				t.Errorf("expected next attempt around %d, got %d", before+backoff, f.NextAttemptAt)
+			}
+			summary := f.Summary(false)
+			if summary.ConsecutiveFailures != f.ConsecutiveFailures || summary.NextAttemptAt != f.NextAttemptAt {
+				t.Errorf("expected summary to expose backoff state, got %#v", summary)
+			}
+		})
+	}
+}
+
+func TestFeedScheduleRefresh(t *testing.T) {
+	// 2025-01-06 is a Monday.
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.Local)
+	// Skip hints are in UTC, so they are computed from the default next
+	// refresh to be independent of the local time zone.
+	defaultNext := now.Add(5 * time.Minute).UTC()
+
+	tests := []struct {
+		desc string
+
+		params        any
+		hints         *feed.UpdateHints
+		nextAttemptAt int64
+		maxJitter     time.Duration
+
+		expectedMin time.Time
+		expectedMax time.Time
+	}{{
+		desc:        "default interval",
+		expectedMin: now.Add(5 * time.Minute),
+		expectedMax: now.Add(5 * time.Minute),
+	}, {
+		desc:        "custom interval",
+		params:      map[string]any{"refresh_interval": "1h"},
+		expectedMin: now.Add(1 * time.Hour),
+		expectedMax: now.Add(1 * time.Hour),
+	}, {
+		desc:        "invalid params fall back to the default interval",
+		params:      map[string]any{"refresh_interval": "often"},
+		expectedMin: now.Add(5 * time.Minute),
+		expectedMax: now.Add(5 * time.Minute),
+	}, {
+		desc:        "jitter is limited to a tenth of the interval",
+		params:      map[string]any{"refresh_interval": "10m"},
+		maxJitter:   1 * time.Hour,
+		expectedMin: now.Add(10 * time.Minute),
+		expectedMax: now.Add(11 * time.Minute),
+	}, {
+		desc:        "jitter within the limit",
+		params:      map[string]any{"refresh_interval": "10m"},
+		maxJitter:   10 * time.Second,
+		expectedMin: now.Add(10 * time.Minute),
+		expectedMax: now.Add(10*time.Minute + 10*time.Second),
+	}, {
+		desc:        "inside the refresh window",
+		params:      map[string]any{"refresh_window": "weekdays 08:00-18:00"},
+		expectedMin: now.Add(5 * time.Minute),
+		expectedMax: now.Add(5 * time.Minute),
+	}, {
+		desc:        "outside the refresh window",
+		params:      map[string]any{"refresh_interval": "8h", "refresh_window": "weekdays 08:00-18:00"},
+		expectedMin: time.Date(2025, 1, 7, 8, 0, 0, 0, time.Local),
+		expectedMax: time.Date(2025, 1, 7, 8, 0, 0, 0, time.Local),
+	}, {
+		desc:          "backing off",
+		nextAttemptAt: now.Add(1 * time.Hour).Unix(),
+		expectedMin:   now.Add(1 * time.Hour),
+		expectedMax:   now.Add(1 * time.Hour),
+	}, {
+		desc:        "ttl hint extends the interval",
+		hints:       &feed.UpdateHints{TTL: 60 * 60},
+		expectedMin: now.Add(1 * time.Hour),
+		expectedMax: now.Add(1 * time.Hour),
+	}, {
+		desc:        "update period hint extends the interval",
+		hints:       &feed.UpdateHints{TTL: 60 * 60, UpdatePeriod: 2 * 60 * 60},
+		expectedMin: now.Add(2 * time.Hour),
+		expectedMax: now.Add(2 * time.Hour),
+	}, {
+		desc:        "yearly update period hint is capped",
+		hints:       &feed.UpdateHints{UpdatePeriod: 365 * 24 * 60 * 60},
+		expectedMin: now.Add(24 * time.Hour),
+		expectedMax: now.Add(24 * time.Hour),
+	}, {
+		desc:        "huge ttl hint is capped",
+		hints:       &feed.UpdateHints{TTL: math.MaxInt64},
+		expectedMin: now.Add(24 * time.Hour),
+		expectedMax: now.Add(24 * time.Hour),
+	}, {
+		desc:        "capped hints do not shorten the interval",
+		params:      map[string]any{"refresh_interval": "48h"},
+		hints:       &feed.UpdateHints{UpdatePeriod: 365 * 24 * 60 * 60},
+		expectedMin: now.Add(48 * time.Hour),
+		expectedMax: now.Add(48 * time.Hour),
+	}, {
+		desc:        "hints do not shorten the interval",
+		params:      map[string]any{"refresh_interval": "3h"},
+		hints:       &feed.UpdateHints{TTL: 60 * 60},
+		expectedMin: now.Add(3 * time.Hour),
+		expectedMax: now.Add(3 * time.Hour),
+	}, {
+		desc:        "skip hours hint",
+		hints:       &feed.UpdateHints{SkipHours: []int{defaultNext.Hour(), (defaultNext.Hour() + 1) % 24}},
+		expectedMin: defaultNext.Truncate(time.Hour).Add(2 * time.Hour),
+		expectedMax: defaultNext.Truncate(time.Hour).Add(2 * time.Hour),
+	}, {
+		desc:        "skip days hint",
+		hints:       &feed.UpdateHints{SkipDays: []time.Weekday{defaultNext.Weekday()}},
+		expectedMin: defaultNext.Truncate(24 * time.Hour).Add(24 * time.Hour),
+		expectedMax: defaultNext.Truncate(24 * time.Hour).Add(24 * time.Hour),
+	}, {
+		desc:        "skip hints covering the whole week are ignored",
+		hints:       &feed.UpdateHints{SkipDays: []time.Weekday{0, 1, 2, 3, 4, 5, 6}},
+		expectedMin: now.Add(5 * time.Minute),
+		expectedMax: now.Add(5 * time.Minute),
+	}, {
+		desc:        "hints are ignored if requested",
+		params:      map[string]any{"ignore_hints": true},
+		hints:       &feed.UpdateHints{TTL: 60 * 60, SkipHours: []int{defaultNext.Hour()}},
+		expectedMin: now.Add(5 * time.Minute),
+		expectedMax: now.Add(5 * time.Minute),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			f := feed.Feed{
+				Name:          "Feed 1",
+				URL:           "url1",
+				Params:        test.params,
+				Hints:         test.hints,
+				NextAttemptAt: test.nextAttemptAt,
+			}
+			result := f.ScheduleRefresh(now, 5*time.Minute, test.maxJitter)
+			if result.Before(test.expectedMin) || result.After(test.expectedMax) {
+				t.Errorf("expected next refresh between %v and %v, got %v", test.expectedMin, test.expectedMax, result)
+			}
+			if f.NextRefreshAt != result.Unix() {
+				t.Errorf("expected next refresh at %v, got %v", result.Unix(), f.NextRefreshAt)
+			}
+		})
+	}
+}
+
+func TestFeedAdaptiveInterval(t *testing.T) {
+	now := timeutil.Now()
+
+	// items returns items first seen at the given number of hours ago.
+	items := func(hoursAgo ...int) map[string]*feed.Item {
+		items := make(map[string]*feed.Item)
+		for i, h := range hoursAgo {
+			url := fmt.Sprintf("url%d", i)
+			items[feed.UID(url)] = &feed.Item{
+				RawItem:   feed.RawItem{URL: url, Title: "Title"},
+				Timestamp: timeutil.HoursAgo(now, h),
+			}
+		}
+		return items
+	}
+
+	tests := []struct {
+		desc string
+
+		items map[string]*feed.Item
+
+		expected time.Duration
+	}{{
+		desc:     "no items",
+		items:    items(),
+		expected: 0,
+	}, {
+		desc:     "all items seen in the same refresh",
+		items:    items(0, 0, 0),
+		expected: 0,
+	}, {
+		desc:     "posts every 8 hours",
+		items:    items(16, 16, 8, 0),
+		expected: 2 * time.Hour,
+	}, {
+		desc:     "busy feed is bounded by the minimum interval",
+		items:    items(0, 0, 0, 0, 1),
+		expected: 30 * time.Minute,
+	}, {
+		desc:     "weekly feed is bounded by the maximum interval",
+		items:    items(0, 7*24, 14*24),
+		expected: 6 * time.Hour,
+	}, {
+		desc:     "quiet feed uses the time since the last post",
+		items:    items(12, 13, 14, 15),
+		expected: 3 * time.Hour,
+	}, {
+		desc:     "only recent posts are considered",
+		items:    items(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 100),
+		expected: 30 * time.Minute,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			f := feed.Feed{
+				Name:  "Feed 1",
+				URL:   "url1",
+				Items: test.items,
+			}
+			result := f.AdaptiveInterval(30*time.Minute, 6*time.Hour)
+			if result != test.expected {
+				t.Errorf("expected interval %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
 func TestFeedUID(t *testing.T) {
 	tests := []struct {
 		desc string
@@ -671,3 +1646,57 @@ func TestFeedUID(t *testing.T) {
 		})
 	}
 }
+
+func TestIconNeedsRefresh(t *testing.T) {
+	now := timeutil.Now()
+
+	tests := []struct {
+		desc     string
+		icon     *feed.Icon
+		iconURL  string
+		expected bool
+	}{{
+		desc:     "no icon URL",
+		icon:     nil,
+		iconURL:  "",
+		expected: false,
+	}, {
+		desc:     "icon never fetched",
+		icon:     nil,
+		iconURL:  "https://example.com/favicon.ico",
+		expected: true,
+	}, {
+		desc:     "recently fetched icon",
+		icon:     &feed.Icon{URL: "https://example.com/favicon.ico", Data: []byte("icon"), FetchedAt: timeutil.HoursAgo(now, 24)},
+		iconURL:  "https://example.com/favicon.ico",
+		expected: false,
+	}, {
+		desc:     "icon URL changed",
+		icon:     &feed.Icon{URL: "https://example.com/favicon.ico", Data: []byte("icon"), FetchedAt: now},
+		iconURL:  "https://example.com/logo.png",
+		expected: true,
+	}, {
+		desc:     "icon fetched a week ago",
+		icon:     &feed.Icon{URL: "https://example.com/favicon.ico", Data: []byte("icon"), FetchedAt: timeutil.HoursAgo(now, 7*24)},
+		iconURL:  "https://example.com/favicon.ico",
+		expected: true,
+	}, {
+		desc:     "recent failure",
+		icon:     &feed.Icon{URL: "https://example.com/favicon.ico", FetchedAt: timeutil.HoursAgo(now, 12)},
+		iconURL:  "https://example.com/favicon.ico",
+		expected: false,
+	}, {
+		desc:     "failure a day ago is retried",
+		icon:     &feed.Icon{URL: "https://example.com/favicon.ico", FetchedAt: timeutil.HoursAgo(now, 24)},
+		iconURL:  "https://example.com/favicon.ico",
+		expected: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := test.icon.NeedsRefresh(test.iconURL, now); got != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
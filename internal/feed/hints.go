@@ -0,0 +1,55 @@
+package feed
+
+import (
+	"slices"
+	"time"
+)
+
+// maxHintInterval caps the interval between refreshes implied by hints, so
+// feeds declaring that they are rarely updated (e.g., yearly) are still
+// refreshed daily.
+const maxHintInterval = 24 * time.Hour
+
+// UpdateHints are caching hints declared by publishers in their feeds (e.g.,
+// RSS ttl, skipHours and skipDays, and the syndication module's update
+// period).
+type UpdateHints struct {
+	// TTL is how long the feed may be cached before being refreshed, in
+	// seconds.
+	TTL int64 `json:"ttl,omitempty"`
+
+	// UpdatePeriod is the expected interval between updates of the feed, in
+	// seconds.
+	UpdatePeriod int64 `json:"update_period,omitempty"`
+
+	// SkipHours are the hours of the day (0-23, in UTC) in which the feed
+	// should not be refreshed.
+	SkipHours []int `json:"skip_hours,omitempty"`
+
+	// SkipDays are the days of the week (in UTC) in which the feed should not
+	// be refreshed.
+	SkipDays []time.Weekday `json:"skip_days,omitempty"`
+}
+
+// minInterval returns the minimum interval between refreshes implied by the
+// hints, up to maxHintInterval.
+func (h *UpdateHints) minInterval() time.Duration {
+	// The hints are capped in seconds, so huge values cannot overflow.
+	secs := min(max(h.TTL, h.UpdatePeriod), int64(maxHintInterval/time.Second))
+	return time.Duration(secs) * time.Second
+}
+
+// nextAllowed returns t if the hints allow refreshing the feed at t, or
+// otherwise the start of the next hour in which refreshing is allowed. If the
+// hints skip every hour of the week, they are ignored and t is returned.
+func (h *UpdateHints) nextAllowed(t time.Time) time.Time {
+	next := t
+	for range 7 * 24 {
+		u := next.UTC()
+		if !slices.Contains(h.SkipHours, u.Hour()) && !slices.Contains(h.SkipDays, u.Weekday()) {
+			return next
+		}
+		next = u.Truncate(time.Hour).Add(time.Hour).In(t.Location())
+	}
+	return t
+}
@@ -1,7 +1,35 @@
 package feed
 
+import (
+	"cmp"
+	"slices"
+)
+
+// maxFullContentFailures is the number of consecutive refreshes in which
+// fetching the full content of an item may fail before it is no longer
+// retried.
+const maxFullContentFailures = 5
+
+// Attachment is a media file attached to an item (e.g., the audio of a podcast
+// episode or the video of a video feed).
+type Attachment struct {
+	// URL is the URL of the media file.
+	URL string `json:"url"`
+	// MimeType is the MIME type of the media file, or empty if unknown.
+	MimeType string `json:"mime_type,omitempty"`
+	// Length is the size of the media file in bytes, or 0 if unknown.
+	Length int64 `json:"length,omitempty"`
+	// Duration is the duration of the media in seconds, or 0 if unknown.
+	Duration int64 `json:"duration,omitempty"`
+	// Thumbnail is the URL of an image representing the media, if any.
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
 // RawItem is the representation of an item as it comes from a feed.
 type RawItem struct {
+	// GUID is the identifier given to the item by the publisher (e.g., an RSS
+	// guid or an Atom id), if any.
+	GUID string `json:"guid,omitempty"`
 	// URL is the URL of the item.
 	URL string `json:"url"`
 	// Title is a short title or description of the item.
@@ -11,20 +39,35 @@ type RawItem struct {
 	Authors string `json:"authors"`
 	// Content is the full content of the item as a sanitized HTML fragment.
 	Content string `json:"content"`
+	// Published is the time when the item was published according to the
+	// feed, or 0 if unknown.
+	Published int64 `json:"published"`
+	// Updated is the time when the item was last updated according to the
+	// feed, or 0 if unknown.
+	Updated int64 `json:"updated"`
+	// Attachments are the media files attached to the item, with sanitized
+	// URLs.
+	Attachments []Attachment `json:"attachments,omitempty"`
 	// Position is the position of the item in the feed when it was first seen.
 	// Assuming two items are first seen at the same time, a lower position
 	// typically means a newer item (i.e., that's how blogs are typically laid
 	// out).
 	Position int `json:"position"`
+
+	// FullContentFailed is true if the full content of the item had to be
+	// fetched from its URL but could not be. It is not stored.
+	FullContentFailed bool `json:"-"`
 }
 
 // UID returns a unique identifier for the raw item if it is valid. Otherwise,
-// returns an empty string.
+// returns an empty string. The identifier is based on the GUID of the item, so
+// it stays the same if the publisher changes the URL of the item, or on the URL
+// if the item has no GUID.
 func (i *RawItem) UID() string {
 	if !i.IsValid() {
 		return ""
 	}
-	return UID(i.URL)
+	return UID(cmp.Or(i.GUID, i.URL))
 }
 
 // IsValid returns true if the item has a URL and a title, thus being
@@ -48,11 +91,40 @@ type Item struct {
 	// Read is true if the item was marked as read by the user.
 	Read bool `json:"read"`
 
+	// UpdatedAt is the time when an edit to the title, authors or content of
+	// the item was last seen, or 0 if it was never edited.
+	UpdatedAt int64 `json:"updated_at"`
+	// Revisions are the previous versions of the item, from oldest to newest.
+	// Only the last few revisions are kept.
+	Revisions []Revision `json:"revisions,omitempty"`
+
+	// Readable is the main content of the page the item links to, if it was
+	// ever requested.
+	Readable *Readable `json:"readable,omitempty"`
+
+	// FullContentFailures is the number of consecutive refreshes in which the
+	// full content of the item could not be fetched, or 0 if it was fetched
+	// or never had to be.
+	FullContentFailures int `json:"full_content_failures,omitempty"`
+
 	// A backlink to the feed is not stored code and tests a bit simpler.
 	// If needed, callers may lookup the feed by its UID in the list of feeds
 	// or use [AllItems] to iterate over (*Feed, *Item) pairs.
 }
 
+// Readable is the main content of the page an item links to, extracted and
+// cached on request.
+type Readable struct {
+	// URL is the URL of the page from which the content was extracted.
+	URL string `json:"url"`
+
+	// Content is the sanitized HTML of the main content of the page.
+	Content string `json:"content"`
+
+	// FetchedAt is the time when the page was fetched.
+	FetchedAt int64 `json:"fetched_at"`
+}
+
 // ItemSummary is the external representation of the item (e.g., for presenting
 // to users).
 type ItemSummary struct {
@@ -81,6 +153,14 @@ type ItemSummary struct {
 	// Timestamp is the time when the item was first seen.
 	Timestamp int64 `json:"timestamp"`
 
+	// Published is the time when the item was published according to the
+	// feed, or 0 if unknown.
+	Published int64 `json:"published"`
+
+	// Updated is the time when the item was last updated according to the
+	// feed, or 0 if unknown.
+	Updated int64 `json:"updated"`
+
 	// Authors is a short comma-separated summary of authors of the item.
 	// It comes directly from the feed and is not sanitized. Do not embed it in
 	// the page directly without proper measures.
@@ -89,6 +169,18 @@ type ItemSummary struct {
 	// Read is true if the item was marked as read by the user.
 	Read bool `json:"read"`
 
+	// UpdatedAt is the time when an edit to the item was last seen, or 0 if it
+	// was never edited.
+	UpdatedAt int64 `json:"updated_at"`
+
+	// RevisionCount is the number of previous versions of the item that are
+	// kept. They can be compared with the current version using the diff API.
+	RevisionCount int `json:"revision_count"`
+
+	// Attachments are the media files attached to the item, with sanitized
+	// URLs. The MIME types come directly from the feed.
+	Attachments []Attachment `json:"attachments,omitempty"`
+
 	// Content is the full content of the item as a sanitized HTML fragment.
 	Content string `json:"content,omitempty"`
 }
@@ -102,6 +194,9 @@ func (i *Item) Refresh(r RawItem) bool {
 	if i.URL == "" && i.Position != r.Position {
 		i.Position = r.Position
 	}
+	// The GUID only changes when an item identified by its URL is migrated
+	// to be identified by its GUID, which is not a change to the user.
+	i.GUID = r.GUID
 	if i.URL != r.URL {
 		i.URL = r.URL
 		changed = true
@@ -118,19 +213,36 @@ func (i *Item) Refresh(r RawItem) bool {
 		i.Content = r.Content
 		changed = true
 	}
+	if i.Published != r.Published {
+		i.Published = r.Published
+		changed = true
+	}
+	if i.Updated != r.Updated {
+		i.Updated = r.Updated
+		changed = true
+	}
+	if !slices.Equal(i.Attachments, r.Attachments) {
+		i.Attachments = r.Attachments
+		changed = true
+	}
 	return changed
 }
 
 func (i *Item) Summary(f *Feed, includeContent bool) *ItemSummary {
 	is := &ItemSummary{
-		UID:       i.UID(),
-		FeedUID:   f.UID(),
-		FeedName:  f.Name,
-		URL:       i.URL,
-		Title:     i.Title,
-		Timestamp: i.Timestamp,
-		Authors:   i.Authors,
-		Read:      i.Read,
+		UID:           i.UID(),
+		FeedUID:       f.UID(),
+		FeedName:      f.title(),
+		URL:           i.URL,
+		Title:         i.Title,
+		Timestamp:     i.Timestamp,
+		Published:     i.Published,
+		Updated:       i.Updated,
+		Authors:       i.Authors,
+		Attachments:   i.Attachments,
+		Read:          i.Read,
+		UpdatedAt:     i.UpdatedAt,
+		RevisionCount: len(i.Revisions),
 	}
 	if includeContent {
 		is.Content = i.Content
@@ -138,6 +250,28 @@ func (i *Item) Summary(f *Feed, includeContent bool) *ItemSummary {
 	return is
 }
 
+// sortTime returns the time used to sort the item. It is the time the item was
+// first seen, unless byPublished is set and the feed declares when the item
+// was published (or else updated). Items claiming to be published after they
+// were first seen are sorted by the time they were first seen, so wrong dates
+// in the future cannot keep them at the top.
+func (i *Item) sortTime(byPublished bool) int64 {
+	if !byPublished {
+		return i.Timestamp
+	}
+	if published := cmp.Or(i.Published, i.Updated); published != 0 {
+		return min(published, i.Timestamp)
+	}
+	return i.Timestamp
+}
+
+// NeedsFullContent returns true if fetching the full content of the item
+// failed, and it should be retried in the next refresh. Fetching is retried up
+// to maxFullContentFailures times.
+func (i *Item) NeedsFullContent() bool {
+	return i.FullContentFailures > 0 && i.FullContentFailures < maxFullContentFailures
+}
+
 // MarkRead marks all feed items as read.
 func (i *Item) MarkRead() {
 	i.Read = true
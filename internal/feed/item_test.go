@@ -1,6 +1,7 @@
 package feed
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -31,6 +32,26 @@ func TestItemRefresh(t *testing.T) {
 			RawItem: RawItem{URL: "url1", Title: "Updated Title 1", Position: 1, Authors: "Author 2", Content: "Content 2"},
 		},
 		expectedResult: true,
+	}, {
+		desc: "raw item dates changed",
+		initialItem: Item{
+			RawItem: RawItem{URL: "url1", Title: "Title 1", Published: 100},
+		},
+		rawItem: RawItem{URL: "url1", Title: "Title 1", Published: 100, Updated: 200},
+		expectedItem: Item{
+			RawItem: RawItem{URL: "url1", Title: "Title 1", Published: 100, Updated: 200},
+		},
+		expectedResult: true,
+	}, {
+		desc: "raw item attachments changed",
+		initialItem: Item{
+			RawItem: RawItem{URL: "url1", Title: "Title 1", Attachments: []Attachment{{URL: "url1.mp3"}}},
+		},
+		rawItem: RawItem{URL: "url1", Title: "Title 1", Attachments: []Attachment{{URL: "url1.mp3", MimeType: "audio/mpeg"}}},
+		expectedItem: Item{
+			RawItem: RawItem{URL: "url1", Title: "Title 1", Attachments: []Attachment{{URL: "url1.mp3", MimeType: "audio/mpeg"}}},
+		},
+		expectedResult: true,
 	}, {
 		desc: "raw item did not change",
 		initialItem: Item{
@@ -50,7 +71,7 @@ func TestItemRefresh(t *testing.T) {
 			if result != test.expectedResult {
 				t.Errorf("expected result %v, got %v", test.expectedResult, result)
 			}
-			if item != test.expectedItem {
+			if !reflect.DeepEqual(item, test.expectedItem) {
 				t.Errorf("expected item %#v, got %#v", test.expectedItem, item)
 			}
 		})
@@ -125,7 +146,7 @@ func TestItemSummary(t *testing.T) {
 	}{{
 		desc: "item with regular feed with content",
 		item: Item{
-			RawItem:   RawItem{URL: "url1", Title: "Title 1", Authors: "Author 1", Content: "Content 1"},
+			RawItem:   RawItem{URL: "url1", Title: "Title 1", Authors: "Author 1", Content: "Content 1", Published: 1234560000, Updated: 1234564000},
 			FeedUID:   "feed1",
 			Timestamp: 1234567890,
 			Read:      true,
@@ -142,6 +163,8 @@ func TestItemSummary(t *testing.T) {
 			URL:       "url1",
 			Title:     "Title 1",
 			Timestamp: 1234567890,
+			Published: 1234560000,
+			Updated:   1234564000,
 			Authors:   "Author 1",
 			Read:      true,
 			Content:   "Content 1",
@@ -192,6 +215,26 @@ func TestItemSummary(t *testing.T) {
 			Read:      true,
 			Content:   "Content 1",
 		},
+	}, {
+		desc: "item with attachments",
+		item: Item{
+			RawItem:   RawItem{URL: "url1", Title: "Title 1", Attachments: []Attachment{{URL: "url1.mp4", MimeType: "video/mp4", Thumbnail: "url1.jpg"}}},
+			Timestamp: 1234567890,
+		},
+		feed: Feed{
+			Name: "Feed 1",
+			URL:  "feed1",
+		},
+		includeContent: false,
+		expected: ItemSummary{
+			UID:         UID("url1"),
+			FeedUID:     UID("feed1"),
+			FeedName:    "Feed 1",
+			URL:         "url1",
+			Title:       "Title 1",
+			Timestamp:   1234567890,
+			Attachments: []Attachment{{URL: "url1.mp4", MimeType: "video/mp4", Thumbnail: "url1.jpg"}},
+		},
 	}, {
 		desc: "item with virtual feed named 'all' without content",
 		item: Item{
@@ -219,7 +262,7 @@ func TestItemSummary(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
 			result := test.item.Summary(&test.feed, test.includeContent)
-			if *result != test.expected {
+			if !reflect.DeepEqual(*result, test.expected) {
 				t.Errorf("expected %#v, got %#v", test.expected, result)
 			}
 		})
@@ -0,0 +1,99 @@
+package feed
+
+import (
+	"cmp"
+	"fmt"
+)
+
+const (
+	// iconMaxAge is how long a cached icon is used before it is fetched again.
+	iconMaxAge = 7 * 24 * 60 * 60
+
+	// iconRetryAge is how long to wait before trying to fetch an icon again
+	// after failing to fetch it.
+	iconRetryAge = 24 * 60 * 60
+)
+
+// Metadata is the information declared by a publisher about a feed itself.
+type Metadata struct {
+	// Title is the title of the feed.
+	Title string `json:"title,omitempty"`
+
+	// Description is a short plain-text description of the feed.
+	Description string `json:"description,omitempty"`
+
+	// SiteURL is the URL of the website the feed belongs to.
+	SiteURL string `json:"site_url,omitempty"`
+
+	// Language is the language of the feed (e.g., "en-us").
+	Language string `json:"language,omitempty"`
+
+	// IconURL is the URL of an image representing the feed. It is either
+	// declared in the feed or the favicon of the website.
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// Icon is an image representing a feed, cached so it can be served by the
+// application.
+type Icon struct {
+	// URL is the URL from which the icon was fetched.
+	URL string `json:"url"`
+
+	// MimeType is the MIME type of the icon.
+	MimeType string `json:"mime_type,omitempty"`
+
+	// Data is the content of the icon. It is empty if the icon could not be
+	// fetched.
+	Data []byte `json:"data,omitempty"`
+
+	// FetchedAt is the time when the icon was fetched (or when fetching it
+	// failed).
+	FetchedAt int64 `json:"fetched_at"`
+}
+
+// NeedsRefresh returns true if the icon i, which may be nil if no icon was
+// fetched yet, must be fetched from iconURL at now. That is the case if it was
+// fetched from another URL or long enough ago, with failures being retried
+// sooner.
+func (i *Icon) NeedsRefresh(iconURL string, now int64) bool {
+	if iconURL == "" {
+		return false
+	}
+	if i == nil || i.URL != iconURL {
+		return true
+	}
+	maxAge := int64(iconMaxAge)
+	if len(i.Data) == 0 {
+		maxAge = iconRetryAge
+	}
+	return now-i.FetchedAt >= maxAge
+}
+
+// IconURL returns the URL of the icon to be fetched for the feed, or an empty
+// string if the feed declares none.
+func (f *Feed) IconURL() string {
+	if f.Metadata == nil {
+		return ""
+	}
+	return f.Metadata.IconURL
+}
+
+// title returns the name of the feed as defined by the user, or else the title
+// declared by the publisher, or else the URL of the feed.
+func (f *Feed) title() string {
+	var title string
+	if f.Metadata != nil {
+		title = f.Metadata.Title
+	}
+	return cmp.Or(f.Name, title, f.URL)
+}
+
+// iconPath returns the API path where the cached icon of the feed is served,
+// or an empty string if no icon is cached. The path changes when the icon is
+// fetched again, so it can be cached by clients.
+func (f *Feed) iconPath() string {
+	if f.Icon == nil || len(f.Icon.Data) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("/api/feeds/%s/icon?v=%d", f.UID(), f.Icon.FetchedAt)
+}
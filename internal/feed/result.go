@@ -0,0 +1,74 @@
+package feed
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// FetchResult is the outcome of a successful fetch operation, to be applied
+// to a feed using [Feed.Refresh].
+type FetchResult struct {
+	// Items are the raw items parsed from the fetched data. It is empty if
+	// NotModified is true.
+	Items []RawItem
+
+	// Timestamp is the time of the fetch operation.
+	Timestamp int64
+
+	// NotModified is true if the server indicated that the feed did not
+	// change since the last fetch (i.e., it returned HTTP 304).
+	NotModified bool
+
+	// ETag is the entity tag returned by the server, if any.
+	ETag string
+
+	// LastModified is the Last-Modified header returned by the server, if
+	// any.
+	LastModified string
+
+	// Hints are the update hints declared in the feed, if any.
+	Hints *UpdateHints
+
+	// Metadata is the information declared in the feed about the feed
+	// itself, if any.
+	Metadata *Metadata
+}
+
+// HTTPError is a fetch error caused by the server responding with an
+// unexpected HTTP status code.
+type HTTPError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// URL is the final URL of the request, after following redirects.
+	URL string
+
+	// ContentType is the content type of the response, if any.
+	ContentType string
+
+	// RetryAfter is the delay requested by the server via the Retry-After
+	// header before the next attempt, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("unexpected HTTP status %d %s from %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+	if e.ContentType != "" {
+		msg += fmt.Sprintf(" (content type %s)", e.ContentType)
+	}
+	return msg
+}
+
+// DiscoveredFeed is a feed found on a website (e.g., advertised by one of its
+// pages).
+type DiscoveredFeed struct {
+	// URL is the URL of the feed.
+	URL string `json:"url"`
+
+	// Type is the type of the feed (e.g., TypeXML).
+	Type string `json:"type"`
+
+	// Title is the title of the feed, or empty if unknown.
+	Title string `json:"title"`
+}
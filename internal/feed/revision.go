@@ -0,0 +1,219 @@
+package feed
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxRevisions is the maximum number of previous revisions kept per item.
+const maxRevisions = 5
+
+// maxDiffCells bounds the size of the table used to diff the lines of two
+// revisions. Larger changes are shown as a deletion of all old lines followed
+// by an insertion of all new lines.
+const maxDiffCells = 1 << 22
+
+// Revision is a version of an item as it was seen at some point.
+type Revision struct {
+	// Timestamp is the time when this version of the item was first seen.
+	Timestamp int64 `json:"timestamp"`
+	// Title is the title of the item in this version.
+	Title string `json:"title"`
+	// Authors is the authors of the item in this version.
+	Authors string `json:"authors"`
+	// Content is the content of the item in this version.
+	Content string `json:"content"`
+}
+
+// revision returns the current version of the item.
+func (i *Item) revision() Revision {
+	return Revision{
+		Timestamp: cmp.Or(i.UpdatedAt, i.Timestamp),
+		Title:     i.Title,
+		Authors:   i.Authors,
+		Content:   i.Content,
+	}
+}
+
+// edited returns true if the title, authors or content of the revisions
+// differ.
+func (r Revision) edited(other Revision) bool {
+	return r.Title != other.Title || r.Authors != other.Authors || r.Content != other.Content
+}
+
+// materiallyEdited returns true if the text of the title or content of the
+// revisions differ, ignoring changes to markup and whitespace.
+func (r Revision) materiallyEdited(other Revision) bool {
+	return !slices.Equal(htmlLines(r.Title), htmlLines(other.Title)) ||
+		!slices.Equal(htmlLines(r.Content), htmlLines(other.Content))
+}
+
+// addRevision records prev as a previous revision of the item, which was
+// edited at now. Only the last maxRevisions revisions are kept.
+func (i *Item) addRevision(prev Revision, now int64) {
+	i.Revisions = append(i.Revisions, prev)
+	if len(i.Revisions) > maxRevisions {
+		i.Revisions = slices.Clone(i.Revisions[len(i.Revisions)-maxRevisions:])
+	}
+	i.UpdatedAt = now
+}
+
+// Diff operations.
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffLine is a line in the diff between two revisions.
+type DiffLine struct {
+	// Op is the operation that transforms the old revision into the new one:
+	// the line is either equal in both, inserted or deleted.
+	Op string `json:"op"`
+	// Text is the text of the line.
+	Text string `json:"text"`
+}
+
+// ItemDiff is the difference between two revisions of an item. Revisions are
+// numbered from 0 (the oldest revision kept) to the number of previous
+// revisions (the current version of the item).
+type ItemDiff struct {
+	From          int        `json:"from"`
+	To            int        `json:"to"`
+	FromTimestamp int64      `json:"from_timestamp"`
+	ToTimestamp   int64      `json:"to_timestamp"`
+	Title         []DiffLine `json:"title"`
+	Authors       []DiffLine `json:"authors"`
+	// Content is the diff of the text of the contents, with one line per
+	// block of text.
+	Content []DiffLine `json:"content"`
+}
+
+// Diff returns the difference between the revisions of the item numbered from
+// and to (see ItemDiff). If to is negative, the current version is used, and
+// if from is negative, the revision before to is used. It returns nil if any
+// of the revisions does not exist.
+func (i *Item) Diff(from, to int) *ItemDiff {
+	versions := append(slices.Clone(i.Revisions), i.revision())
+	if to < 0 {
+		to = len(versions) - 1
+	}
+	if from < 0 {
+		from = max(to-1, 0)
+	}
+	if from >= len(versions) || to >= len(versions) {
+		return nil
+	}
+	a, b := versions[from], versions[to]
+	return &ItemDiff{
+		From:          from,
+		To:            to,
+		FromTimestamp: a.Timestamp,
+		ToTimestamp:   b.Timestamp,
+		Title:         diffLines([]string{a.Title}, []string{b.Title}),
+		Authors:       diffLines([]string{a.Authors}, []string{b.Authors}),
+		Content:       diffLines(htmlLines(a.Content), htmlLines(b.Content)),
+	}
+}
+
+// blockElements are the HTML elements that start a new line of text.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "blockquote": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true,
+	"figure": true, "footer": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// htmlLines returns the lines of text in an HTML fragment, with one line per
+// block of text and whitespace collapsed.
+func htmlLines(fragment string) []string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		switch tt {
+		case html.TextToken:
+			// Line breaks in text are just whitespace.
+			b.WriteString(strings.ReplaceAll(string(z.Text()), "\n", " "))
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			if blockElements[string(name)] {
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	var lines []string
+	for line := range strings.SplitSeq(b.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// diffLines returns the operations that transform the lines in a into the
+// lines in b, based on their longest common subsequence.
+func diffLines(a, b []string) []DiffLine {
+	var prefix, suffix []DiffLine
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, DiffLine{Op: DiffEqual, Text: a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append(suffix, DiffLine{Op: DiffEqual, Text: a[len(a)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	slices.Reverse(suffix)
+
+	diff := prefix
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: line})
+		}
+		for _, line := range b {
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: line})
+		}
+		return append(diff, suffix...)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	return append(diff, suffix...)
+}
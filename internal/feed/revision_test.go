@@ -0,0 +1,131 @@
+package feed
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestItemDiff(t *testing.T) {
+	item := Item{
+		RawItem:   RawItem{URL: "url1", Title: "Title 3", Authors: "Author", Content: "<p>Line 1</p><p>Line 2 edited</p><p>Line 4</p>"},
+		Timestamp: 100,
+		UpdatedAt: 300,
+		Revisions: []Revision{
+			{Timestamp: 100, Title: "Title 1", Authors: "Author", Content: "<p>Line 1</p><p>Line 2</p><p>Line 3</p>"},
+			{Timestamp: 200, Title: "Title 2", Authors: "Author", Content: "<p>Line 1</p><p>Line 2</p><p>Line 3</p><p>Line 4</p>"},
+		},
+	}
+
+	tests := []struct {
+		desc         string
+		from, to     int
+		expectedDiff *ItemDiff
+	}{{
+		desc: "defaults to the last revision and the current version",
+		from: -1,
+		to:   -1,
+		expectedDiff: &ItemDiff{
+			From:          1,
+			To:            2,
+			FromTimestamp: 200,
+			ToTimestamp:   300,
+			Title: []DiffLine{
+				{Op: DiffDelete, Text: "Title 2"},
+				{Op: DiffInsert, Text: "Title 3"},
+			},
+			Authors: []DiffLine{{Op: DiffEqual, Text: "Author"}},
+			Content: []DiffLine{
+				{Op: DiffEqual, Text: "Line 1"},
+				{Op: DiffDelete, Text: "Line 2"},
+				{Op: DiffDelete, Text: "Line 3"},
+				{Op: DiffInsert, Text: "Line 2 edited"},
+				{Op: DiffEqual, Text: "Line 4"},
+			},
+		},
+	}, {
+		desc: "between two previous revisions",
+		from: 0,
+		to:   1,
+		expectedDiff: &ItemDiff{
+			From:          0,
+			To:            1,
+			FromTimestamp: 100,
+			ToTimestamp:   200,
+			Title: []DiffLine{
+				{Op: DiffDelete, Text: "Title 1"},
+				{Op: DiffInsert, Text: "Title 2"},
+			},
+			Authors: []DiffLine{{Op: DiffEqual, Text: "Author"}},
+			Content: []DiffLine{
+				{Op: DiffEqual, Text: "Line 1"},
+				{Op: DiffEqual, Text: "Line 2"},
+				{Op: DiffEqual, Text: "Line 3"},
+				{Op: DiffInsert, Text: "Line 4"},
+			},
+		},
+	}, {
+		desc: "from defaults to the revision before to",
+		from: -1,
+		to:   0,
+		expectedDiff: &ItemDiff{
+			From:          0,
+			To:            0,
+			FromTimestamp: 100,
+			ToTimestamp:   100,
+			Title:         []DiffLine{{Op: DiffEqual, Text: "Title 1"}},
+			Authors:       []DiffLine{{Op: DiffEqual, Text: "Author"}},
+			Content: []DiffLine{
+				{Op: DiffEqual, Text: "Line 1"},
+				{Op: DiffEqual, Text: "Line 2"},
+				{Op: DiffEqual, Text: "Line 3"},
+			},
+		},
+	}, {
+		desc:         "revision does not exist",
+		from:         0,
+		to:           3,
+		expectedDiff: nil,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			diff := item.Diff(test.from, test.to)
+			if !reflect.DeepEqual(diff, test.expectedDiff) {
+				t.Errorf("expected diff %#v, got %#v", test.expectedDiff, diff)
+			}
+		})
+	}
+}
+
+func TestHTMLLines(t *testing.T) {
+	tests := []struct {
+		desc          string
+		fragment      string
+		expectedLines []string
+	}{{
+		desc:          "empty fragment",
+		fragment:      "",
+		expectedLines: nil,
+	}, {
+		desc:          "plain text",
+		fragment:      "  Some   text ",
+		expectedLines: []string{"Some text"},
+	}, {
+		desc:          "blocks and inline elements",
+		fragment:      "<h1>Title</h1><p>Some <b>bold</b>\ntext</p><ul><li>One</li><li>Two</li></ul>Line<br>break",
+		expectedLines: []string{"Title", "Some bold text", "One", "Two", "Line", "break"},
+	}, {
+		desc:          "entities",
+		fragment:      "<p>Fish &amp; chips</p>",
+		expectedLines: []string{"Fish & chips"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			lines := htmlLines(test.fragment)
+			if !reflect.DeepEqual(lines, test.expectedLines) {
+				t.Errorf("expected lines %#v, got %#v", test.expectedLines, lines)
+			}
+		})
+	}
+}
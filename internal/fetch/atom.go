@@ -0,0 +1,363 @@
+package fetch
+
+import (
+	"encoding/xml"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/alnvdl/varys/internal/feed"
+	xhtml "golang.org/x/net/html"
+)
+
+// Atom is an Atom feed document (RFC 4287).
+type Atom struct {
+	Base     string       `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Lang     string       `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Entries  []AtomEntry  `xml:"entry"`
+	Links    []AtomLink   `xml:"link"`
+	Authors  []AtomPerson `xml:"author"`
+	Title    AtomText     `xml:"title"`
+	Subtitle AtomText     `xml:"subtitle"`
+	Icon     string       `xml:"icon"`
+	Logo     string       `xml:"logo"`
+	Syndication
+}
+
+// base returns the base URL of the feed document. The URL of the document is
+// not known to the parser, so it is taken from the links of the feed.
+func (a *Atom) base() *url.URL {
+	docURL := absoluteURL(coalesce(
+		atomLinkHref(a.Links, "self"),
+		atomLinkHref(a.Links, "alternate"),
+		atomLinkHref(a.Links, ""),
+	))
+	return xmlBase(a.Base, docURL)
+}
+
+// metadata returns the metadata declared in the feed. The site URL is the
+// alternate link of the feed, preferring HTML pages.
+func (a *Atom) metadata() *feed.Metadata {
+	var siteURL string
+	for _, link := range a.Links {
+		if link.rel() == "alternate" && link.Href != "" && (siteURL == "" || isHTMLLink(link)) {
+			siteURL = urlToString(resolveURL(link.Href, xmlBase(link.Base, a.base()), nil))
+			if isHTMLLink(link) {
+				break
+			}
+		}
+	}
+	return newMetadata(a.Title.plain(), a.Subtitle.plain(), siteURL, a.Lang, a.base(), a.Icon, a.Logo)
+}
+
+type AtomEntry struct {
+	Base      string       `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID        string       `xml:"id"`
+	GUID      string       `xml:"guid"`
+	Links     []AtomLink   `xml:"link"`
+	Title     AtomText     `xml:"title"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Authors   []AtomPerson `xml:"author"`
+	Content   AtomText     `xml:"content"`
+	Summary   AtomText     `xml:"summary"`
+
+	MediaGroups     []MediaGroup     `xml:"group"`
+	MediaThumbnails []MediaThumbnail `xml:"thumbnail"`
+}
+
+type AtomLink struct {
+	Base   string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// rel returns the relation of the link, which is "alternate" if not given.
+func (l *AtomLink) rel() string {
+	return lowerOr(l.Rel, "alternate")
+}
+
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	URI   string `xml:"uri"`
+	Email string `xml:"email"`
+}
+
+// String returns the name of the person, or else their email or URI.
+func (p AtomPerson) String() string {
+	return coalesce(strings.TrimSpace(p.Name), strings.TrimSpace(p.Email), strings.TrimSpace(p.URI))
+}
+
+// AtomText is an Atom text construct (e.g., a title) or content element. Its
+// type can be "text" (the default), "html" or "xhtml", or a MIME type for
+// content.
+type AtomText struct {
+	Base  string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Type  string `xml:"type,attr"`
+	Src   string `xml:"src,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// htmlTag matches HTML start and end tags.
+var htmlTag = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9]*(\s[^<>]*)?/?>`)
+
+// kind returns "text", "html" or "xhtml" according to the type of the text,
+// or an empty string if the text is not in a format that can be shown (e.g.,
+// out-of-line or base64-encoded content).
+func (t *AtomText) kind() string {
+	if t.Src != "" {
+		return ""
+	}
+	// Text is the default type, but many feeds leave it out for HTML in
+	// CDATA sections, so untyped CDATA containing tags is treated as HTML.
+	// Escaped markup in untyped text is text, as it should be.
+	if t.Type == "" && strings.Contains(t.Inner, "<![CDATA[") && htmlTag.MatchString(t.Text) {
+		return "html"
+	}
+	switch typ := lowerOr(t.Type, "text"); {
+	case typ == "text" || typ == "html" || typ == "xhtml":
+		return typ
+	case typ == "text/html":
+		return "html"
+	case typ == "application/xhtml+xml":
+		return "xhtml"
+	case strings.HasPrefix(typ, "text/"):
+		return "text"
+	}
+	return ""
+}
+
+// html returns the text as an HTML fragment. The fragment is not sanitized.
+func (t *AtomText) html() string {
+	switch t.kind() {
+	case "text":
+		return html.EscapeString(strings.TrimSpace(t.Text))
+	case "html":
+		return strings.TrimSpace(t.Text)
+	case "xhtml":
+		return xhtmlToHTML(t.Inner)
+	}
+	return ""
+}
+
+// plain returns the text without markup.
+func (t *AtomText) plain() string {
+	switch t.kind() {
+	case "text":
+		return strings.TrimSpace(t.Text)
+	case "html", "xhtml":
+		return htmlToText(t.html())
+	}
+	return ""
+}
+
+// parseAtomEntries converts the entries of an Atom feed into raw items. URLs
+// are resolved against the xml:base of the elements where they appear, or
+// the URL of the feed.
+func parseAtomEntries(atom *Atom, p xmlParams) []feed.RawItem {
+	feedBase := atom.base()
+
+	var feedItems []feed.RawItem
+	for pos, entry := range atom.Entries {
+		entryBase := xmlBase(entry.Base, feedBase)
+		resolvedItemURL := entry.url(entryBase)
+
+		text := &entry.Content
+		if text.html() == "" {
+			text = &entry.Summary
+		}
+		content := text.html()
+		if p.InferParagraphs {
+			content = inferParagraphs(content)
+		}
+		// Relative URLs in content are resolved against the item URL, unless
+		// the feed declares a base URL for them.
+		contentBase := resolvedItemURL
+		if atom.Base != "" || entry.Base != "" || text.Base != "" {
+			contentBase = xmlBase(text.Base, entryBase)
+		}
+
+		media := Media{
+			Groups:     entry.MediaGroups,
+			Thumbnails: entry.MediaThumbnails,
+		}
+		for _, link := range entry.Links {
+			if link.rel() == "enclosure" {
+				media.Enclosures = append(media.Enclosures, Enclosure{
+					URL:    urlToString(resolveURL(link.Href, xmlBase(link.Base, entryBase), nil)),
+					Type:   link.Type,
+					Length: link.Length,
+				})
+			}
+		}
+
+		authors := entry.Authors
+		if len(authors) == 0 {
+			authors = atom.Authors
+		}
+		var names []string
+		for _, author := range authors {
+			if name := author.String(); name != "" {
+				names = append(names, name)
+			}
+		}
+
+		feedItems = append(feedItems, feed.RawItem{
+			GUID:        coalesce(strings.TrimSpace(entry.ID), strings.TrimSpace(entry.GUID)),
+			URL:         urlToString(resolvedItemURL),
+			Title:       entry.Title.plain(),
+			Authors:     strings.Join(names, ", "),
+			Content:     silentlySanitizeHTML(content, contentBase),
+			Published:   parseDate(entry.Published),
+			Updated:     parseDate(entry.Updated),
+			Attachments: media.attachments(resolvedItemURL),
+			Position:    pos,
+		})
+	}
+	return feedItems
+}
+
+// url returns the URL of the page of the entry, resolved against base. It is
+// the alternate link of the entry, preferring HTML pages. If the entry has no
+// alternate links, its ID is used if it is a web URL, or else the first link
+// that does not point to the entry itself or to other resources.
+func (e *AtomEntry) url(base *url.URL) *url.URL {
+	var alternates []AtomLink
+	for _, link := range e.Links {
+		if link.rel() == "alternate" && link.Href != "" {
+			alternates = append(alternates, link)
+		}
+	}
+	if len(alternates) > 0 {
+		link := alternates[0]
+		if i := slices.IndexFunc(alternates, isHTMLLink); i >= 0 {
+			link = alternates[i]
+		}
+		return resolveURL(link.Href, xmlBase(link.Base, base), nil)
+	}
+
+	if id := absoluteURL(strings.TrimSpace(e.ID)); id != nil && (id.Scheme == "http" || id.Scheme == "https") {
+		return id
+	}
+
+	for _, link := range e.Links {
+		switch link.rel() {
+		case "self", "enclosure", "edit", "edit-media", "replies":
+			continue
+		}
+		if link.Href != "" {
+			return resolveURL(link.Href, xmlBase(link.Base, base), nil)
+		}
+	}
+	return nil
+}
+
+func isHTMLLink(link AtomLink) bool {
+	typ := strings.ToLower(link.Type)
+	return typ == "" || typ == "text/html" || typ == "application/xhtml+xml"
+}
+
+// atomLinkHref returns the href of the first link with the given relation, or
+// of the first link if rel is empty.
+func atomLinkHref(links []AtomLink, rel string) string {
+	for _, link := range links {
+		if rel == "" || link.rel() == rel {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// xmlBase returns the base URL declared by an xml:base attribute, resolved
+// against the base URL of the parent element. If no valid base is declared,
+// the parent base is returned.
+func xmlBase(base string, parent *url.URL) *url.URL {
+	if base == "" {
+		return parent
+	}
+	resolved := resolveURL(strings.TrimSpace(base), parent, nil)
+	if resolved == nil || !resolved.IsAbs() {
+		return parent
+	}
+	return resolved
+}
+
+// lowerOr returns s in lower case, or def if s is blank.
+func lowerOr(s, def string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// voidElements are the HTML elements that cannot have contents, and must not
+// have end tags.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// xhtmlToHTML converts the contents of an Atom xhtml text construct into an
+// HTML fragment. The contents are a single xhtml div, which is not part of
+// the text, and whose elements may have namespace prefixes that HTML does not
+// understand.
+func xhtmlToHTML(inner string) string {
+	dec := xml.NewDecoder(strings.NewReader(inner))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 && tok.Name.Local == "div" {
+				continue
+			}
+			b.WriteString("<" + tok.Name.Local)
+			for _, attr := range tok.Attr {
+				if attr.Name.Space != "" {
+					continue
+				}
+				b.WriteString(" " + attr.Name.Local + `="` + html.EscapeString(attr.Value) + `"`)
+			}
+			b.WriteString(">")
+		case xml.EndElement:
+			depth--
+			if depth == 0 && tok.Name.Local == "div" || voidElements[tok.Name.Local] {
+				continue
+			}
+			b.WriteString("</" + tok.Name.Local + ">")
+		case xml.CharData:
+			b.WriteString(html.EscapeString(string(tok)))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// htmlToText returns the text in an HTML fragment, without markup.
+func htmlToText(fragment string) string {
+	doc, err := xhtml.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for n := range doc.Descendants() {
+		if n.Type == xhtml.TextNode {
+			b.WriteString(n.Data)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,180 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultHeaderTimeout  = 20 * time.Second
+	defaultTimeout        = 60 * time.Second
+	defaultMaxBodySize    = 10 * 1024 * 1024
+	defaultMaxRedirects   = 10
+	defaultUserAgent      = "Varys"
+)
+
+// ClientParams configures the HTTP client used for fetching feeds. Zero
+// values (or nil, for pointers) are replaced by sensible defaults.
+type ClientParams struct {
+	// ConnectTimeout limits how long establishing a connection (including the
+	// TLS handshake) may take.
+	ConnectTimeout time.Duration
+
+	// HeaderTimeout limits how long to wait for the response headers after
+	// the request is sent.
+	HeaderTimeout time.Duration
+
+	// Timeout limits the total time of a fetch, including reading the body.
+	Timeout time.Duration
+
+	// MaxBodySize is the maximum size of a response body in bytes.
+	MaxBodySize int64
+
+	// MaxRedirects is the maximum number of redirects followed in a fetch.
+	// If it points to 0, redirects are not followed.
+	MaxRedirects *int
+
+	// UserAgent is the value of the User-Agent header sent in requests.
+	UserAgent string
+}
+
+// withDefaults returns a copy of p where zero values are replaced by the
+// defaults.
+func (p ClientParams) withDefaults() ClientParams {
+	if p.ConnectTimeout == 0 {
+		p.ConnectTimeout = defaultConnectTimeout
+	}
+	if p.HeaderTimeout == 0 {
+		p.HeaderTimeout = defaultHeaderTimeout
+	}
+	if p.Timeout == 0 {
+		p.Timeout = defaultTimeout
+	}
+	if p.MaxBodySize == 0 {
+		p.MaxBodySize = defaultMaxBodySize
+	}
+	if p.MaxRedirects == nil {
+		p.MaxRedirects = new(defaultMaxRedirects)
+	}
+	if p.UserAgent == "" {
+		p.UserAgent = defaultUserAgent
+	}
+	return p
+}
+
+// clientParams defines the feed params that can override ClientParams for a
+// single feed.
+type clientParams struct {
+	ConnectTimeout string `json:"connect_timeout"`
+	HeaderTimeout  string `json:"header_timeout"`
+	Timeout        string `json:"timeout"`
+	MaxBodySize    int64  `json:"max_body_size"`
+	MaxRedirects   *int   `json:"max_redirects"`
+	UserAgent      string `json:"user_agent"`
+}
+
+func (p *clientParams) Validate() error {
+	for name, value := range map[string]string{
+		"connect_timeout": p.ConnectTimeout,
+		"header_timeout":  p.HeaderTimeout,
+		"timeout":         p.Timeout,
+	} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("cannot parse %s: %v", name, err)
+		} else if d <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+	if p.MaxBodySize < 0 {
+		return errors.New("max_body_size cannot be negative")
+	}
+	if p.MaxRedirects != nil && *p.MaxRedirects < 0 {
+		return errors.New("max_redirects cannot be negative")
+	}
+	return nil
+}
+
+// apply returns a copy of cp with the overrides defined in p. It assumes p
+// was validated.
+func (p *clientParams) apply(cp ClientParams) ClientParams {
+	if d, err := time.ParseDuration(p.ConnectTimeout); err == nil {
+		cp.ConnectTimeout = d
+	}
+	if d, err := time.ParseDuration(p.HeaderTimeout); err == nil {
+		cp.HeaderTimeout = d
+	}
+	if d, err := time.ParseDuration(p.Timeout); err == nil {
+		cp.Timeout = d
+	}
+	if p.MaxBodySize > 0 {
+		cp.MaxBodySize = p.MaxBodySize
+	}
+	if p.MaxRedirects != nil {
+		cp.MaxRedirects = p.MaxRedirects
+	}
+	if p.UserAgent != "" {
+		cp.UserAgent = p.UserAgent
+	}
+	return cp
+}
+
+// Fetcher fetches feeds using an HTTP client configured by ClientParams.
+type Fetcher struct {
+	params    ClientParams
+	transport *http.Transport
+}
+
+// NewFetcher creates a new Fetcher based on the given p parameters.
+func NewFetcher(p ClientParams) *Fetcher {
+	p = p.withDefaults()
+	return &Fetcher{
+		params:    p,
+		transport: newTransport(p),
+	}
+}
+
+// defaultFetcher is the Fetcher used by the package-level Fetch function.
+var defaultFetcher = NewFetcher(ClientParams{})
+
+// newTransport creates an HTTP transport respecting the connection and header
+// timeouts in p.
+func newTransport(p ClientParams) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{
+		Timeout:   p.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	t.TLSHandshakeTimeout = p.ConnectTimeout
+	t.ResponseHeaderTimeout = p.HeaderTimeout
+	return t
+}
+
+// client returns an HTTP client for the given cp parameters, which may
+// contain per-feed overrides, and a function to release the client's
+// resources once it is no longer needed. The shared transport is reused
+// unless cp requires different connection or header timeouts.
+func (f *Fetcher) client(cp ClientParams) (*http.Client, func()) {
+	transport := f.transport
+	release := func() {}
+	if cp.ConnectTimeout != f.params.ConnectTimeout || cp.HeaderTimeout != f.params.HeaderTimeout {
+		transport = newTransport(cp)
+		release = transport.CloseIdleConnections
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cp.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > *cp.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", *cp.MaxRedirects)
+			}
+			return nil
+		},
+	}, release
+}
@@ -0,0 +1,148 @@
+package fetch_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alnvdl/varys/internal/fetch"
+)
+
+func TestFetcherClientParams(t *testing.T) {
+	const data = `<rss><channel><item><title>Item 1</title><link>http://example.com/item1</link></item></channel></rss>`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		if r.UserAgent() != r.URL.Query().Get("ua") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(data))
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(data + strings.Repeat(" ", 100)))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(data))
+	})
+	mux.HandleFunc("/redirect/{n}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("n") {
+		case "0":
+			http.Redirect(w, r, "/redirect/1", http.StatusFound)
+		case "1":
+			http.Redirect(w, r, "/redirect/2", http.StatusFound)
+		default:
+			w.Write([]byte(data))
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		desc          string
+		clientParams  fetch.ClientParams
+		path          string
+		feedParams    any
+		expectedError string
+	}{{
+		desc:         "custom user agent",
+		clientParams: fetch.ClientParams{UserAgent: "Varys/test"},
+		path:         "/feed?ua=Varys/test",
+	}, {
+		desc:         "user agent overridden by feed params",
+		clientParams: fetch.ClientParams{UserAgent: "Varys/test"},
+		path:         "/feed?ua=Other",
+		feedParams:   map[string]any{"user_agent": "Other"},
+	}, {
+		desc:         "body within the size limit",
+		clientParams: fetch.ClientParams{MaxBodySize: int64(len(data))},
+		path:         "/feed?ua=Varys",
+	}, {
+		desc:          "body over the size limit",
+		clientParams:  fetch.ClientParams{MaxBodySize: int64(len(data))},
+		path:          "/big",
+		expectedError: "response body exceeds 101 bytes",
+	}, {
+		desc:         "body size limit overridden by feed params",
+		clientParams: fetch.ClientParams{MaxBodySize: int64(len(data))},
+		path:         "/big",
+		feedParams:   map[string]any{"max_body_size": 1000},
+	}, {
+		desc:          "timeout",
+		clientParams:  fetch.ClientParams{Timeout: 50 * time.Millisecond},
+		path:          "/slow",
+		expectedError: "Client.Timeout exceeded",
+	}, {
+		desc:          "timeout overridden by feed params",
+		clientParams:  fetch.ClientParams{Timeout: 5 * time.Second},
+		path:          "/slow",
+		feedParams:    map[string]any{"timeout": "50ms"},
+		expectedError: "Client.Timeout exceeded",
+	}, {
+		desc:          "header timeout overridden by feed params",
+		path:          "/slow",
+		feedParams:    map[string]any{"header_timeout": "50ms"},
+		expectedError: "timeout awaiting response headers",
+	}, {
+		desc: "redirects followed by default",
+		path: "/redirect/0",
+	}, {
+		desc:         "redirects within the limit",
+		clientParams: fetch.ClientParams{MaxRedirects: new(2)},
+		path:         "/redirect/0",
+	}, {
+		desc:          "redirects over the limit",
+		clientParams:  fetch.ClientParams{MaxRedirects: new(1)},
+		path:          "/redirect/0",
+		expectedError: "stopped after 1 redirects",
+	}, {
+		desc:          "redirects not followed",
+		clientParams:  fetch.ClientParams{MaxRedirects: new(0)},
+		path:          "/redirect/0",
+		expectedError: "stopped after 0 redirects",
+	}, {
+		desc:          "redirects disabled by feed params",
+		clientParams:  fetch.ClientParams{MaxRedirects: new(2)},
+		path:          "/redirect/0",
+		feedParams:    map[string]any{"max_redirects": 0},
+		expectedError: "stopped after 0 redirects",
+	}, {
+		desc:          "invalid feed params",
+		path:          "/feed",
+		feedParams:    map[string]any{"timeout": "soon"},
+		expectedError: `cannot parse client params: cannot validate: cannot parse timeout: time: invalid duration "soon"`,
+	}, {
+		desc:          "negative feed params",
+		path:          "/feed",
+		feedParams:    map[string]any{"max_redirects": -1},
+		expectedError: "cannot parse client params: cannot validate: max_redirects cannot be negative",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			f := fetch.NewFetcher(test.clientParams)
+			res, err := f.Fetch(context.Background(), fetch.FetchParams{
+				URL:        server.URL + test.path,
+				FeedName:   test.desc,
+				FeedType:   "xml",
+				FeedParams: test.feedParams,
+			})
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(res.Items) != 1 {
+				t.Errorf("expected 1 item, got %d", len(res.Items))
+			}
+		})
+	}
+}
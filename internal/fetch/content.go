@@ -0,0 +1,350 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// maxContentFetches is the maximum number of items whose full content is
+	// fetched in a single refresh of a feed. Other new items keep the content
+	// from the feed.
+	maxContentFetches = 20
+
+	// minArticleLength is the minimum length of the text of an article found
+	// by the heuristic, in runes. Shorter texts are usually not articles.
+	minArticleLength = 140
+)
+
+// contentParams defines the feed params for fetching the full content of
+// items.
+type contentParams struct {
+	FetchFullContent bool   `json:"fetch_full_content"`
+	ContentSelector  string `json:"content_selector"`
+}
+
+func (p *contentParams) Validate() error {
+	if p.ContentSelector == "" {
+		return nil
+	}
+	if !p.FetchFullContent {
+		return errors.New("content_selector cannot be set without fetch_full_content")
+	}
+	if _, err := parseSelector(p.ContentSelector); err != nil {
+		return fmt.Errorf("cannot parse content_selector: %v", err)
+	}
+	return nil
+}
+
+// FetchContent fetches the main content of the page at pageURL using a
+// Fetcher with the default client params. See [Fetcher.FetchContent].
+func FetchContent(ctx context.Context, pageURL, contentSelector string) (string, error) {
+	return defaultFetcher.FetchContent(ctx, pageURL, contentSelector)
+}
+
+// FetchContent fetches the page at pageURL and returns its main content as a
+// sanitized HTML fragment. The content is made of the elements matched by
+// contentSelector if it is set, or else of the elements found by a heuristic
+// scoring the density of text in the page.
+func (f *Fetcher) FetchContent(ctx context.Context, pageURL, contentSelector string) (string, error) {
+	return f.fetchContent(ctx, f.params, pageURL, contentSelector)
+}
+
+func (f *Fetcher) fetchContent(ctx context.Context, cp ClientParams, pageURL, contentSelector string) (string, error) {
+	var sel selector
+	if contentSelector != "" {
+		var err error
+		if sel, err = parseSelector(contentSelector); err != nil {
+			return "", fmt.Errorf("cannot parse content selector: %v", err)
+		}
+	}
+
+	p, err := f.get(ctx, cp, pageURL)
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch page: %w", err)
+	}
+	if mimeType, _, _ := mime.ParseMediaType(p.contentType); mimeType != "" && mimeType != "text/html" && mimeType != "application/xhtml+xml" {
+		return "", fmt.Errorf("unsupported page type %s", mimeType)
+	}
+	r, err := charset.NewReader(bytes.NewReader(p.data), p.contentType)
+	if err != nil {
+		return "", fmt.Errorf("cannot detect encoding: %v", err)
+	}
+	doc, err := html.ParseWithOptions(r, html.ParseOptionEnableScripting(false))
+	if err != nil {
+		return "", fmt.Errorf("cannot parse HTML: %v", err)
+	}
+
+	var content string
+	if sel != nil {
+		content = selectedContent(doc, sel)
+	} else {
+		content = articleContent(doc)
+	}
+	content = silentlySanitizeHTML(content, p.url)
+	if strings.TrimSpace(content) == "" {
+		return "", errors.New("cannot find content in page")
+	}
+	return content, nil
+}
+
+// fetchFullContent replaces the content of the valid items whose UIDs are not
+// known in p with the full content fetched from their URLs, with at most
+// maxContentFetches fetches. Pages are fetched one at a time, waiting for each
+// request as defined by p. Items whose full content cannot be fetched keep
+// their content, and are flagged so the fetch can be retried later.
+func (f *Fetcher) fetchFullContent(ctx context.Context, cp ClientParams, p *FetchParams, items []feed.RawItem, contentSelector string, log *slog.Logger) {
+	var n int
+	for i := range items {
+		item := &items[i]
+		if !item.IsValid() || p.KnownItems[item.UID()] {
+			continue
+		}
+		if n == maxContentFetches {
+			log.Info("not fetching full content of remaining new items", slog.Int("maxContentFetches", maxContentFetches))
+			break
+		}
+		n++
+		if err := p.wait(ctx, item.URL); err != nil {
+			log.Info("not fetching full content", slog.String("err", err.Error()))
+			item.FullContentFailed = true
+			continue
+		}
+		content, err := f.fetchContent(ctx, cp, item.URL, contentSelector)
+		if err != nil {
+			log.Info("cannot fetch full content",
+				slog.String("itemURL", item.URL),
+				slog.String("err", err.Error()),
+			)
+			item.FullContentFailed = true
+			continue
+		}
+		item.Content = content
+	}
+}
+
+// selectedContent returns the HTML of the elements matched by sel in doc.
+// Elements inside matched elements are not matched again.
+func selectedContent(doc *html.Node, sel selector) string {
+	var nodes []*html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if sel.match(n) {
+			nodes = append(nodes, n)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	return renderNodes(nodes)
+}
+
+// renderNodes returns the HTML of nodes.
+func renderNodes(nodes []*html.Node) string {
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		html.Render(&buf, n)
+	}
+	return buf.String()
+}
+
+// unlikelyTags are elements that are not part of the main content of pages.
+var unlikelyTags = map[string]bool{
+	"aside":    true,
+	"button":   true,
+	"footer":   true,
+	"form":     true,
+	"header":   true,
+	"iframe":   true,
+	"input":    true,
+	"nav":      true,
+	"noscript": true,
+	"script":   true,
+	"select":   true,
+	"style":    true,
+	"svg":      true,
+	"template": true,
+	"textarea": true,
+}
+
+var (
+	// unlikelyCandidate matches classes and IDs of elements that are not
+	// part of the main content of pages, unless maybeCandidate matches too.
+	unlikelyCandidate = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|foot|header|menu|modal|nav|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe`)
+	maybeCandidate    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+
+	// positiveClass and negativeClass match classes and IDs of elements that
+	// are respectively likely and unlikely to hold the main content.
+	positiveClass = regexp.MustCompile(`(?i)article|blog|body|content|entry|h-entry|hentry|main|page|post|story|text`)
+	negativeClass = regexp.MustCompile(`(?i)byline|comment|contact|foot|footnote|hidden|masthead|media|meta|outbrain|promo|related|scroll|share|shopping|sidebar|sponsor|tags|tool|widget`)
+)
+
+// articleContent returns the HTML of the main content of doc as found by a
+// heuristic similar to that of Readability: paragraphs score their ancestors
+// by the amount of text in them, the ancestor with the highest score after
+// discounting its links is picked, and so are its siblings with similar
+// scores. An empty string is returned if no content is found. doc is
+// modified.
+func articleContent(doc *html.Node) string {
+	removeUnlikely(doc)
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode || (n.Data != "p" && n.Data != "pre" && n.Data != "td" && n.Data != "blockquote") {
+			continue
+		}
+		text := nodeText(n)
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(length)/100, 3)
+		// The parent gets the whole score, the grandparent half of it, and
+		// the great-grandparent a third.
+		ancestor := n.Parent
+		for level := 1; level <= 3 && ancestor != nil && ancestor.Type == html.ElementNode; level++ {
+			if _, ok := scores[ancestor]; !ok {
+				scores[ancestor] = initialScore(ancestor)
+				candidates = append(candidates, ancestor)
+			}
+			scores[ancestor] += score / float64(level)
+			ancestor = ancestor.Parent
+		}
+	}
+
+	var top *html.Node
+	for _, c := range candidates {
+		scores[c] *= 1 - linkDensity(c)
+		if top == nil || scores[c] > scores[top] {
+			top = c
+		}
+	}
+	if top == nil || utf8.RuneCountInString(nodeText(top)) < minArticleLength {
+		return ""
+	}
+	if top.Parent == nil {
+		return renderNodes([]*html.Node{top})
+	}
+
+	threshold := max(10, scores[top]*0.2)
+	var nodes []*html.Node
+	for s := top.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s == top {
+			nodes = append(nodes, s)
+			continue
+		}
+		if s.Type != html.ElementNode {
+			continue
+		}
+		if score, ok := scores[s]; ok && score >= threshold {
+			nodes = append(nodes, s)
+			continue
+		}
+		if s.Data == "p" {
+			text := nodeText(s)
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(s)
+			if (length > 80 && density < 0.25) || (length > 0 && density == 0 && strings.Contains(text, ". ")) {
+				nodes = append(nodes, s)
+			}
+		}
+	}
+	return renderNodes(nodes)
+}
+
+// removeUnlikely removes the elements of doc that are unlikely to be part of
+// its main content.
+func removeUnlikely(doc *html.Node) {
+	var unlikely []*html.Node
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if unlikelyTags[n.Data] || hasAttr(n, "hidden") {
+			unlikely = append(unlikely, n)
+			continue
+		}
+		switch n.Data {
+		case "html", "body", "main", "article", "a":
+			continue
+		}
+		classAndID := attrValue(n, "class") + " " + attrValue(n, "id")
+		if unlikelyCandidate.MatchString(classAndID) && !maybeCandidate.MatchString(classAndID) {
+			unlikely = append(unlikely, n)
+		}
+	}
+	for _, n := range unlikely {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// initialScore returns the score of a candidate element before the text in
+// it is taken into account.
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.Data {
+	case "article":
+		score = 10
+	case "div":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+	for _, v := range []string{attrValue(n, "class"), attrValue(n, "id")} {
+		if v == "" {
+			continue
+		}
+		if negativeClass.MatchString(v) {
+			score -= 25
+		}
+		if positiveClass.MatchString(v) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity returns the fraction of the text in n that is inside links.
+func linkDensity(n *html.Node) float64 {
+	length := utf8.RuneCountInString(nodeText(n))
+	if length == 0 {
+		return 0
+	}
+	var linkLength int
+	for d := range n.Descendants() {
+		if d.Type == html.ElementNode && d.Data == "a" {
+			linkLength += utf8.RuneCountInString(nodeText(d))
+		}
+	}
+	return min(float64(linkLength)/float64(length), 1)
+}
+
+// hasAttr returns true if n has the attribute key.
+func hasAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
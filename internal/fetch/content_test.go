@@ -0,0 +1,234 @@
+package fetch_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"github.com/alnvdl/varys/internal/fetch"
+)
+
+const articlePage = `<html>
+	<head><title>Article</title><script>var x = 1;</script></head>
+	<body>
+		<header><nav><a href="/">Home</a> <a href="/about">About</a></nav></header>
+		<div class="sidebar">
+			<p>Subscribe to our newsletter, it has news, deals, and more, every week.</p>
+		</div>
+		<div class="post-content">
+			<h1>The title</h1>
+			<p>The first paragraph of the article, which is long enough to be counted, and has commas.</p>
+			<p>The second paragraph of the article, with a <a href="/link">link</a> to another page, and more text.</p>
+			<p>The third paragraph of the article. It finishes the article, and it is also long.</p>
+		</div>
+		<div class="comments">
+			<p>A comment that is long enough to be counted, saying that the article is good.</p>
+		</div>
+		<footer><p>Copyright of the site, all rights reserved, forever and ever.</p></footer>
+	</body>
+</html>`
+
+func TestFetchContent(t *testing.T) {
+	tests := []struct {
+		desc            string
+		contentType     string
+		page            string
+		status          int
+		contentSelector string
+		expectedContent string
+		expectedError   string
+	}{{
+		desc:        "article found by the heuristic",
+		contentType: "text/html; charset=utf-8",
+		page:        articlePage,
+		expectedContent: `<div>
+			<h1>The title</h1>
+			<p>The first paragraph of the article, which is long enough to be counted, and has commas.</p>
+			<p>The second paragraph of the article, with a <a href="{server}/link">link</a> to another page, and more text.</p>
+			<p>The third paragraph of the article. It finishes the article, and it is also long.</p>
+		</div>`,
+	}, {
+		desc:            "unsupported content selector",
+		contentType:     "text/html",
+		page:            articlePage,
+		contentSelector: ".post-content p:first-of-type, .comments p",
+		expectedError:   `cannot parse content selector: unsupported pseudo-class "first-of-type"`,
+	}, {
+		desc:            "content selector matching many elements",
+		contentType:     "text/html",
+		page:            articlePage,
+		contentSelector: ".post-content h1, .comments p",
+		expectedContent: `<h1>The title</h1><p>A comment that is long enough to be counted, saying that the article is good.</p>`,
+	}, {
+		desc:            "content selector matching nothing",
+		contentType:     "text/html",
+		page:            articlePage,
+		contentSelector: "article",
+		expectedError:   "cannot find content in page",
+	}, {
+		desc:          "page without an article",
+		contentType:   "text/html",
+		page:          `<html><body><p>Too short.</p><ul><li><a href="/a">A link</a></li></ul></body></html>`,
+		expectedError: "cannot find content in page",
+	}, {
+		desc:          "page is not HTML",
+		contentType:   "application/pdf",
+		page:          "%PDF-1.4",
+		expectedError: "unsupported page type application/pdf",
+	}, {
+		desc:          "page not found",
+		status:        http.StatusNotFound,
+		expectedError: "cannot fetch page: unexpected HTTP status 404 Not Found",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+					return
+				}
+				w.Header().Set("Content-Type", test.contentType)
+				w.Write([]byte(test.page))
+			}))
+			defer server.Close()
+
+			content, err := fetch.FetchContent(context.Background(), server.URL+"/article", test.contentSelector)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expectedContent := strings.ReplaceAll(test.expectedContent, "{server}", server.URL)
+			if content != expectedContent {
+				t.Errorf("expected content %q, got %q", expectedContent, content)
+			}
+		})
+	}
+}
+
+func TestFetchFullContent(t *testing.T) {
+	tests := []struct {
+		desc            string
+		feedParams      map[string]any
+		knownPaths      []string
+		expectedFetches []string
+		expectedContent map[string]string
+		expectedFailed  []string
+		expectedError   string
+	}{{
+		desc:            "full content is not fetched by default",
+		feedParams:      map[string]any{},
+		expectedFetches: nil,
+		expectedContent: map[string]string{
+			"/post/1":       "Summary 1",
+			"/post/2":       "Summary 2",
+			"/post/missing": "Summary 3",
+		},
+	}, {
+		desc:            "full content of new items",
+		feedParams:      map[string]any{"fetch_full_content": true, "content_selector": "#body"},
+		knownPaths:      []string{"/post/1"},
+		expectedFetches: []string{"/post/2", "/post/missing"},
+		expectedContent: map[string]string{
+			"/post/1":       "Summary 1",
+			"/post/2":       "<div>Full post 2</div>",
+			"/post/missing": "Summary 3",
+		},
+		expectedFailed: []string{"/post/missing"},
+	}, {
+		desc:          "content selector without fetching full content",
+		feedParams:    map[string]any{"content_selector": "#body"},
+		expectedError: "content_selector cannot be set without fetch_full_content",
+	}, {
+		desc:          "invalid content selector",
+		feedParams:    map[string]any{"fetch_full_content": true, "content_selector": "#"},
+		expectedError: `cannot parse content_selector: expected ID at the end in selector "#"`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var mu sync.Mutex
+			var fetches []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/feed":
+					w.Write([]byte(strings.ReplaceAll(`<rss><channel>
+						<item><title>Post 1</title><link>{server}/post/1</link><description>Summary 1</description></item>
+						<item><title>Post 2</title><link>{server}/post/2</link><description>Summary 2</description></item>
+						<item><title>Post 3</title><link>{server}/post/missing</link><description>Summary 3</description></item>
+					</channel></rss>`, "{server}", "http://"+r.Host)))
+					return
+				}
+				mu.Lock()
+				fetches = append(fetches, r.URL.Path)
+				mu.Unlock()
+				if r.URL.Path == "/post/2" {
+					w.Write([]byte(`<html><body><div id="body">Full post 2</div></body></html>`))
+					return
+				}
+				http.NotFound(w, r)
+			}))
+			defer server.Close()
+
+			knownItems := make(map[string]bool)
+			for _, path := range test.knownPaths {
+				knownItems[feed.UID(server.URL+path)] = true
+			}
+
+			var waits []string
+			res, err := fetch.Fetch(context.Background(), fetch.FetchParams{
+				URL:        server.URL + "/feed",
+				FeedName:   "Feed",
+				FeedType:   feed.TypeXML,
+				FeedParams: test.feedParams,
+				KnownItems: knownItems,
+				Wait: func(ctx context.Context, rawURL string) error {
+					waits = append(waits, strings.TrimPrefix(rawURL, server.URL))
+					return nil
+				},
+			})
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(fetches, test.expectedFetches) {
+				t.Errorf("expected fetches %v, got %v", test.expectedFetches, fetches)
+			}
+			// Every page is waited for.
+			if !reflect.DeepEqual(waits, test.expectedFetches) {
+				t.Errorf("expected waits %v, got %v", test.expectedFetches, waits)
+			}
+			if len(res.Items) != len(test.expectedContent) {
+				t.Fatalf("expected %d items, got %d", len(test.expectedContent), len(res.Items))
+			}
+			for _, item := range res.Items {
+				path := strings.TrimPrefix(item.URL, server.URL)
+				if item.Content != test.expectedContent[path] {
+					t.Errorf("expected content %q for %s, got %q", test.expectedContent[path], path, item.Content)
+				}
+				if failed := slices.Contains(test.expectedFailed, path); item.FullContentFailed != failed {
+					t.Errorf("expected full content failed %v for %s, got %v", failed, path, item.FullContentFailed)
+				}
+			}
+		})
+	}
+}
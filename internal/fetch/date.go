@@ -0,0 +1,115 @@
+package fetch
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the layouts tried when parsing dates found in feeds, in
+// order. They cover RFC 822 and RFC 1123 as used by RSS (with and without
+// seconds, weekdays and numeric zones), RFC 3339 as used by Atom, and a few
+// broken variants commonly found in the wild.
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 MST",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Mon, 2 January 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04:05",
+	"Mon, 2 Jan 2006",
+	"2 Jan 2006",
+	time.RFC850,
+	time.UnixDate,
+	time.ANSIC,
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04-07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05-07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// zoneOffsets maps the time zone names allowed by RFC 822 (and a few others
+// commonly found in feeds) to numeric offsets. Go only knows the offsets of
+// names in the local time zone, so they are replaced before parsing.
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"BST":  "+0100",
+	"BRT":  "-0300",
+	"IST":  "+0530",
+	"JST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+}
+
+var (
+	// dateSpaces matches runs of whitespace in dates.
+	dateSpaces = regexp.MustCompile(`\s+`)
+	// dateComment matches parenthesized comments in dates, such as
+	// "(Coordinated Universal Time)".
+	dateComment = regexp.MustCompile(`\s*\(.*\)\s*$`)
+	// dateZoneName matches a zone name at the end of a date.
+	dateZoneName = regexp.MustCompile(`\s([A-Za-z]{1,4})$`)
+)
+
+// minDate is the earliest date accepted as valid. Earlier dates are almost
+// certainly broken (e.g., zero values rendered by publishing software).
+var minDate = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// parseDate parses a date found in a feed, returning it as a Unix timestamp,
+// or 0 if it cannot be parsed. Dates without a time zone are assumed to be in
+// UTC.
+func parseDate(s string) int64 {
+	s = strings.TrimSpace(dateSpaces.ReplaceAllString(s, " "))
+	s = dateComment.ReplaceAllString(s, "")
+	s = strings.TrimSuffix(strings.TrimPrefix(s, ","), ",")
+	if m := dateZoneName.FindStringSubmatch(s); m != nil {
+		if offset, ok := zoneOffsets[strings.ToUpper(m[1])]; ok {
+			s = strings.TrimSuffix(s, m[1]) + offset
+		}
+	}
+	if s == "" {
+		return 0
+	}
+
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if t.Before(minDate) {
+			return 0
+		}
+		return t.Unix()
+	}
+	return 0
+}
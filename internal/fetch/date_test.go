@@ -0,0 +1,107 @@
+package fetch_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alnvdl/varys/internal/fetch"
+)
+
+func TestParseDate(t *testing.T) {
+	expected := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC).Unix()
+
+	tests := []struct {
+		desc     string
+		date     string
+		expected int64
+	}{{
+		desc:     "RFC 1123 with numeric zone",
+		date:     "Thu, 02 Jan 2025 15:04:05 +0000",
+		expected: expected,
+	}, {
+		desc:     "RFC 1123 with GMT",
+		date:     "Thu, 02 Jan 2025 15:04:05 GMT",
+		expected: expected,
+	}, {
+		desc:     "RFC 822 with US zone",
+		date:     "Thu, 02 Jan 2025 10:04:05 EST",
+		expected: expected,
+	}, {
+		desc:     "RFC 822 with military UT zone",
+		date:     "Thu, 02 Jan 2025 15:04:05 UT",
+		expected: expected,
+	}, {
+		desc:     "RFC 822 without weekday and with single digit day",
+		date:     "2 Jan 2025 15:04:05 +0000",
+		expected: expected,
+	}, {
+		desc:     "RFC 822 without seconds",
+		date:     "Thu, 02 Jan 2025 15:04 +0000",
+		expected: time.Date(2025, 1, 2, 15, 4, 0, 0, time.UTC).Unix(),
+	}, {
+		desc:     "wrong weekday",
+		date:     "Mon, 02 Jan 2025 15:04:05 +0000",
+		expected: expected,
+	}, {
+		desc:     "full month name",
+		date:     "Thu, 2 January 2025 15:04:05 +0000",
+		expected: expected,
+	}, {
+		desc:     "extra whitespace",
+		date:     "  Thu,  02 Jan 2025\n15:04:05   +0000 ",
+		expected: expected,
+	}, {
+		desc:     "zone comment",
+		date:     "Thu, 02 Jan 2025 15:04:05 +0000 (Coordinated Universal Time)",
+		expected: expected,
+	}, {
+		desc:     "RFC 3339",
+		date:     "2025-01-02T15:04:05Z",
+		expected: expected,
+	}, {
+		desc:     "RFC 3339 with offset and fraction",
+		date:     "2025-01-02T12:04:05.123-03:00",
+		expected: expected,
+	}, {
+		desc:     "ISO 8601 with offset without colon",
+		date:     "2025-01-02T12:04:05-0300",
+		expected: expected,
+	}, {
+		desc:     "ISO 8601 without zone",
+		date:     "2025-01-02T15:04:05",
+		expected: expected,
+	}, {
+		desc:     "SQL-like date",
+		date:     "2025-01-02 15:04:05",
+		expected: expected,
+	}, {
+		desc:     "date only",
+		date:     "2025-01-02",
+		expected: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC).Unix(),
+	}, {
+		desc:     "Unix date",
+		date:     "Thu Jan  2 15:04:05 UTC 2025",
+		expected: expected,
+	}, {
+		desc:     "empty",
+		date:     "",
+		expected: 0,
+	}, {
+		desc:     "garbage",
+		date:     "yesterday",
+		expected: 0,
+	}, {
+		desc:     "zero date",
+		date:     "0001-01-01T00:00:00Z",
+		expected: 0,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			result := fetch.ParseDate(test.date)
+			if result != test.expected {
+				t.Errorf("expected %v, got %v", time.Unix(test.expected, 0).UTC(), time.Unix(result, 0).UTC())
+			}
+		})
+	}
+}
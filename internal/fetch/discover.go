@@ -0,0 +1,250 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// feedLinkTypes maps the MIME types of feeds advertised by web pages to feed
+// types.
+var feedLinkTypes = map[string]string{
+	"application/rss+xml":   feed.TypeXML,
+	"application/atom+xml":  feed.TypeXML,
+	"application/rdf+xml":   feed.TypeXML,
+	"application/feed+json": feed.TypeJSON,
+}
+
+// commonFeedPaths are the paths where websites commonly serve their feeds.
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss",
+	"/rss.xml",
+	"/atom.xml",
+	"/feed.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+// Discover finds the feeds of the website at siteURL using a Fetcher with the
+// default client params. See [Fetcher.Discover].
+func Discover(ctx context.Context, siteURL string) ([]feed.DiscoveredFeed, error) {
+	return defaultFetcher.Discover(ctx, siteURL)
+}
+
+// Discover finds the feeds of the website at siteURL. If siteURL points to a
+// feed, only that feed is returned. Otherwise, the feeds advertised by the
+// page in link elements are returned, or if there are none, the feeds found in
+// common paths of the website (e.g., /feed). The request is aborted if ctx is
+// canceled.
+func (f *Fetcher) Discover(ctx context.Context, siteURL string) ([]feed.DiscoveredFeed, error) {
+	log := slog.With(slog.String("siteURL", siteURL))
+	log.Info("discovering feeds")
+
+	u, err := url.Parse(siteURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid site URL %q", siteURL)
+	}
+
+	p, err := f.get(ctx, f.params, u.String())
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch site: %w", err)
+	}
+	if df := sniffFeed(p.data); df != nil {
+		df.URL = p.url.String()
+		log.Info("site URL is a feed")
+		return []feed.DiscoveredFeed{*df}, nil
+	}
+
+	feeds, err := feedLinks(p.data, p.contentType, p.url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse site: %v", err)
+	}
+	if len(feeds) > 0 {
+		log.Info("found feeds advertised by site", slog.Int("nFeeds", len(feeds)))
+		return feeds, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		probeURL := p.url.ResolveReference(&url.URL{Path: path})
+		probe, err := f.get(ctx, f.params, probeURL.String())
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		df := sniffFeed(probe.data)
+		if df == nil {
+			continue
+		}
+		df.URL = probe.url.String()
+		// Different paths often redirect to the same feed.
+		if !slices.ContainsFunc(feeds, func(other feed.DiscoveredFeed) bool { return other.URL == df.URL }) {
+			feeds = append(feeds, *df)
+		}
+	}
+	log.Info("found feeds in common paths", slog.Int("nFeeds", len(feeds)))
+	return feeds, nil
+}
+
+// page is a document fetched from the web.
+type page struct {
+	// url is the final URL of the page, after following redirects.
+	url         *url.URL
+	contentType string
+	data        []byte
+}
+
+// get fetches the page at rawURL using the given cp client params.
+func (f *Fetcher) get(ctx context.Context, cp ClientParams, rawURL string) (*page, error) {
+	client, release := f.client(cp)
+	defer release()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %v", err)
+	}
+	req.Header.Set("User-Agent", cp.UserAgent)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot make request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &feed.HTTPError{
+			StatusCode:  res.StatusCode,
+			URL:         res.Request.URL.String(),
+			ContentType: res.Header.Get("Content-Type"),
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, cp.MaxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %v", err)
+	}
+	if int64(len(data)) > cp.MaxBodySize {
+		return nil, fmt.Errorf("response body exceeds %d bytes", cp.MaxBodySize)
+	}
+	return &page{
+		url:         res.Request.URL,
+		contentType: res.Header.Get("Content-Type"),
+		data:        data,
+	}, nil
+}
+
+// sniffFeed returns the type and title of the feed in data, or nil if data is
+// not an RSS, Atom or JSON feed.
+func sniffFeed(data []byte) *feed.DiscoveredFeed {
+	var jf struct {
+		Version string `json:"version"`
+		Title   string `json:"title"`
+	}
+	if err := json.Unmarshal(data, &jf); err == nil {
+		if !strings.Contains(jf.Version, "jsonfeed.org/version/") {
+			return nil
+		}
+		return &feed.DiscoveredFeed{Type: feed.TypeJSON, Title: strings.TrimSpace(jf.Title)}
+	}
+
+	var doc struct {
+		XMLName xml.Name
+		Title   AtomText `xml:"title"`
+		Channel struct {
+			Title string `xml:"title"`
+		} `xml:"channel"`
+	}
+	if err := tryParseFeed(data, &doc); err != nil {
+		return nil
+	}
+	switch doc.XMLName.Local {
+	case "rss", "RDF":
+		return &feed.DiscoveredFeed{Type: feed.TypeXML, Title: strings.TrimSpace(doc.Channel.Title)}
+	case "feed":
+		return &feed.DiscoveredFeed{Type: feed.TypeXML, Title: doc.Title.plain()}
+	}
+	return nil
+}
+
+// feedLinks returns the feeds advertised by an HTML page in link elements
+// (e.g., <link rel="alternate" type="application/rss+xml" href="/feed">).
+// Links without a title are given the title of the page.
+func feedLinks(data []byte, contentType string, pageURL *url.URL) ([]feed.DiscoveredFeed, error) {
+	r, err := charset.NewReader(bytes.NewReader(data), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("cannot detect encoding: %v", err)
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse HTML: %v", err)
+	}
+
+	var links []*html.Node
+	baseURL := pageURL
+	var pageTitle string
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		switch n.Data {
+		case "base":
+			if base := resolveURL(attrValue(n, "href"), pageURL, nil); base != nil && baseURL == pageURL {
+				baseURL = base
+			}
+		case "title":
+			if pageTitle == "" && n.FirstChild != nil {
+				pageTitle = strings.Join(strings.Fields(n.FirstChild.Data), " ")
+			}
+		case "link":
+			links = append(links, n)
+		}
+	}
+
+	var feeds []feed.DiscoveredFeed
+	for _, link := range links {
+		if !slices.Contains(strings.Fields(strings.ToLower(attrValue(link, "rel"))), "alternate") {
+			continue
+		}
+		mimeType, _, _ := mime.ParseMediaType(attrValue(link, "type"))
+		typ, ok := feedLinkTypes[mimeType]
+		if !ok {
+			continue
+		}
+		feedURL := urlToString(resolveURL(strings.TrimSpace(attrValue(link, "href")), baseURL, nil))
+		if feedURL == "" || slices.ContainsFunc(feeds, func(df feed.DiscoveredFeed) bool { return df.URL == feedURL }) {
+			continue
+		}
+		feeds = append(feeds, feed.DiscoveredFeed{
+			URL:   feedURL,
+			Type:  typ,
+			Title: coalesce(strings.TrimSpace(attrValue(link, "title")), pageTitle),
+		})
+	}
+	return feeds, nil
+}
+
+// attrValue returns the value of the attribute of n with the given key, or an
+// empty string if n has no such attribute.
+func attrValue(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
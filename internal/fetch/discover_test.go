@@ -0,0 +1,141 @@
+package fetch_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"github.com/alnvdl/varys/internal/fetch"
+)
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		desc string
+		// pages maps paths to the documents served in them. Paths not in
+		// the map return 404, and documents starting with "redirect:" are
+		// redirects to the path following the prefix.
+		pages         map[string]string
+		sitePath      string
+		expectedFeeds []feed.DiscoveredFeed
+		expectedError string
+	}{{
+		desc: "feeds advertised in link elements",
+		pages: map[string]string{
+			"/blog/": `<html>
+				<head>
+					<title>My  Blog</title>
+					<base href="/blog/">
+					<link rel="alternate" type="application/rss+xml" title="Posts" href="rss.xml">
+					<link rel="alternate" type="application/atom+xml" href="https://example.com/atom.xml">
+					<link rel="Alternate" type="application/feed+json; charset=utf-8" title="JSON" href="feed.json">
+					<link rel="alternate" type="application/rss+xml" title="Duplicate" href="/blog/rss.xml">
+					<link rel="alternate" type="text/html" href="/blog/other">
+					<link rel="stylesheet" type="application/rss+xml" href="/blog/style.css">
+				</head>
+				<body></body>
+			</html>`,
+		},
+		sitePath: "/blog/",
+		expectedFeeds: []feed.DiscoveredFeed{
+			{URL: "{server}/blog/rss.xml", Type: "xml", Title: "Posts"},
+			{URL: "https://example.com/atom.xml", Type: "xml", Title: "My Blog"},
+			{URL: "{server}/blog/feed.json", Type: "json", Title: "JSON"},
+		},
+	}, {
+		desc: "site URL is a feed",
+		pages: map[string]string{
+			"/feed": `<rss><channel><title> Feed Title </title><item><title>Item</title></item></channel></rss>`,
+		},
+		sitePath: "/feed",
+		expectedFeeds: []feed.DiscoveredFeed{
+			{URL: "{server}/feed", Type: "xml", Title: "Feed Title"},
+		},
+	}, {
+		desc: "site URL is a JSON feed",
+		pages: map[string]string{
+			"/feed.json": `{"version": "https://jsonfeed.org/version/1.1", "title": "JSON Feed", "items": []}`,
+		},
+		sitePath: "/feed.json",
+		expectedFeeds: []feed.DiscoveredFeed{
+			{URL: "{server}/feed.json", Type: "json", Title: "JSON Feed"},
+		},
+	}, {
+		desc: "feeds found in common paths",
+		pages: map[string]string{
+			"/":         `<html><head><title>Site</title></head><body>No feeds here</body></html>`,
+			"/feed":     "redirect:/atom.xml",
+			"/atom.xml": `<feed xmlns="http://www.w3.org/2005/Atom"><title type="html">Atom &lt;b&gt;Feed&lt;/b&gt;</title></feed>`,
+			"/rss":      `<html><body>Not a feed</body></html>`,
+			"/index.xml": `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+				<channel><title>RDF Feed</title></channel>
+			</rdf:RDF>`,
+		},
+		sitePath: "/",
+		expectedFeeds: []feed.DiscoveredFeed{
+			{URL: "{server}/atom.xml", Type: "xml", Title: "Atom Feed"},
+			{URL: "{server}/index.xml", Type: "xml", Title: "RDF Feed"},
+		},
+	}, {
+		desc: "no feeds found",
+		pages: map[string]string{
+			"/": `<html><body>No feeds here</body></html>`,
+		},
+		sitePath:      "/",
+		expectedFeeds: nil,
+	}, {
+		desc:          "site not found",
+		pages:         map[string]string{},
+		sitePath:      "/",
+		expectedError: "cannot fetch site: unexpected HTTP status 404 Not Found",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, ok := test.pages[r.URL.Path]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				if target, ok := strings.CutPrefix(data, "redirect:"); ok {
+					http.Redirect(w, r, target, http.StatusFound)
+					return
+				}
+				w.Write([]byte(data))
+			}))
+			defer server.Close()
+
+			feeds, err := fetch.Discover(context.Background(), server.URL+test.sitePath)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var expectedFeeds []feed.DiscoveredFeed
+			for _, df := range test.expectedFeeds {
+				df.URL = strings.ReplaceAll(df.URL, "{server}", server.URL)
+				expectedFeeds = append(expectedFeeds, df)
+			}
+			if !reflect.DeepEqual(feeds, expectedFeeds) {
+				t.Errorf("expected feeds %#v, got %#v", expectedFeeds, feeds)
+			}
+		})
+	}
+}
+
+func TestDiscoverInvalidURL(t *testing.T) {
+	for _, siteURL := range []string{"", "example.com", "ftp://example.com", "http://"} {
+		_, err := fetch.Discover(context.Background(), siteURL)
+		if err == nil || !strings.Contains(err.Error(), "invalid site URL") {
+			t.Errorf("expected invalid site URL error for %q, got %v", siteURL, err)
+		}
+	}
+}
@@ -3,5 +3,9 @@ package fetch
 var SilentlySanitizeHTML = silentlySanitizeHTML
 
 var ParseXML = parseXML
+var ParseJSON = parseJSON
+var ParseJSONAPI = parseJSONAPI
 var ParseHTML = parseHTML
 var ParseImage = parseImage
+var ParseRetryAfter = parseRetryAfter
+var ParseDate = parseDate
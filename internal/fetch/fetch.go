@@ -3,10 +3,15 @@
 package fetch
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/alnvdl/varys/internal/feed"
 	"github.com/alnvdl/varys/internal/timeutil"
@@ -18,45 +23,186 @@ type FetchParams struct {
 	FeedName   string
 	FeedType   string
 	FeedParams any
+
+	// ETag and LastModified are the validators returned by the server in a
+	// previous fetch. If set, they are used to make a conditional request.
+	ETag         string
+	LastModified string
+
+	// KnownItems holds the UIDs of the items already in the feed, except for
+	// those whose full content must be fetched again. If the full content of
+	// items is fetched, it is only fetched for other items. Feeds are only
+	// fetched past their first page if no items are known.
+	KnownItems map[string]bool
+
+	// Wait, if set, is called before each additional request made while
+	// fetching the feed (e.g., for more pages or full content), and blocks until the request
+	// may start according to the limits of the caller for the host of rawURL.
+	// If it returns an error, the request is not made.
+	Wait func(ctx context.Context, rawURL string) error
+}
+
+// wait waits until an additional request to rawURL may start (see
+// FetchParams.Wait).
+func (p *FetchParams) wait(ctx context.Context, rawURL string) error {
+	if p.Wait == nil {
+		return ctx.Err()
+	}
+	return p.Wait(ctx, rawURL)
+}
+
+// parseResult is the outcome of parsing feed data.
+type parseResult struct {
+	Items    []feed.RawItem
+	Hints    *feed.UpdateHints
+	Metadata *feed.Metadata
+	// NextPage is the URL of the next page of the feed, which may be relative
+	// to the URL of the parsed page, or empty if there is none.
+	NextPage string
 }
 
 // parser is a function that parses feed data, optionally using the given
-// params, and returns raw items.
-type parser func(data []byte, params any) ([]feed.RawItem, error)
+// params, and returns raw items along with any hints declared in the feed.
+type parser func(data []byte, params any) (*parseResult, error)
 
 var parsers = map[string]parser{
-	feed.TypeXML:   parseXML,
-	feed.TypeHTML:  parseHTML,
-	feed.TypeImage: parseImage,
+	feed.TypeXML:     parseXML,
+	feed.TypeHTML:    parseHTML,
+	feed.TypeImage:   parseImage,
+	feed.TypeJSON:    parseJSON,
+	feed.TypeJSONAPI: parseJSONAPI,
+}
+
+// Fetch fetches and parses the feed identified by the given p parameters
+// using a Fetcher with the default client params. See [Fetcher.Fetch].
+func Fetch(ctx context.Context, p FetchParams) (*feed.FetchResult, error) {
+	return defaultFetcher.Fetch(ctx, p)
 }
 
 // Fetch fetches and parses the feed identified by the given p parameters,
-// returning a slice of raw items and the timestamp of the fetch operation.
-func Fetch(p FetchParams) ([]feed.RawItem, int64, error) {
+// returning the raw items, the timestamp of the fetch operation and the cache
+// validators sent by the server. If the server responds that the feed was not
+// modified since the validators in p were issued, the result is marked as
+// such and contains no items. The client params of f may be overridden by the
+// feed params in p. The request is aborted if ctx is canceled.
+func (f *Fetcher) Fetch(ctx context.Context, p FetchParams) (*feed.FetchResult, error) {
 	log := slog.With(slog.String("feedName", p.FeedName))
 	log.Info("fetching feed")
 
-	res, err := http.Get(p.URL)
+	parser, ok := parsers[p.FeedType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported feed type: %s", p.FeedType)
+	}
+
+	var overrides clientParams
+	if err := feed.ParseParams(p.FeedParams, &overrides); err != nil {
+		return nil, fmt.Errorf("cannot parse client params: %v", err)
+	}
+	var cps contentParams
+	if err := feed.ParseParams(p.FeedParams, &cps); err != nil {
+		return nil, fmt.Errorf("cannot parse content params: %v", err)
+	}
+	var pps pageParams
+	if err := feed.ParseParams(p.FeedParams, &pps); err != nil {
+		return nil, fmt.Errorf("cannot parse page params: %v", err)
+	}
+	cp := overrides.apply(f.params)
+	client, release := f.client(cp)
+	defer release()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
 	if err != nil {
-		return nil, 0, fmt.Errorf("cannot make request: %v", err)
+		return nil, fmt.Errorf("cannot create request: %v", err)
 	}
-	defer res.Body.Close()
-	data, err := io.ReadAll(res.Body)
+	req.Header.Set("User-Agent", cp.UserAgent)
+	if p.ETag != "" {
+		req.Header.Set("If-None-Match", p.ETag)
+	}
+	if p.LastModified != "" {
+		req.Header.Set("If-Modified-Since", p.LastModified)
+	}
+
+	res, err := client.Do(req)
 	if err != nil {
-		return nil, 0, fmt.Errorf("cannot read response body: %v", err)
+		return nil, fmt.Errorf("cannot make request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified {
+		log.Info("feed not modified")
+		// Servers may omit validators in 304 responses, in which case the
+		// previous ones remain valid.
+		return &feed.FetchResult{
+			Timestamp:    timeutil.Now(),
+			NotModified:  true,
+			ETag:         coalesce(res.Header.Get("ETag"), p.ETag),
+			LastModified: coalesce(res.Header.Get("Last-Modified"), p.LastModified),
+		}, nil
 	}
 
-	var items []feed.RawItem
-	if parser, ok := parsers[p.FeedType]; ok {
-		log.Info("parsing feed", slog.String("feedType", p.FeedType))
-		items, err = parser(data, p.FeedParams)
-		if err != nil {
-			return nil, 0, fmt.Errorf("cannot parse feed: %v", err)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &feed.HTTPError{
+			StatusCode:  res.StatusCode,
+			URL:         res.Request.URL.String(),
+			ContentType: res.Header.Get("Content-Type"),
+			RetryAfter:  parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
 		}
-	} else {
-		return nil, 0, fmt.Errorf("unsupported feed type: %s", p.FeedType)
 	}
 
-	log.Info("feed fetched and parsed", slog.Int("nFeedItems", len(items)))
-	return items, timeutil.Now(), nil
+	// Reading one byte past the limit tells apart bodies that are exactly
+	// at the limit from bodies that exceed it.
+	data, err := io.ReadAll(io.LimitReader(res.Body, cp.MaxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %v", err)
+	}
+	if int64(len(data)) > cp.MaxBodySize {
+		return nil, fmt.Errorf("response body exceeds %d bytes", cp.MaxBodySize)
+	}
+
+	log.Info("parsing feed", slog.String("feedType", p.FeedType))
+	parsed, err := parser(data, p.FeedParams)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse feed: %v", err)
+	}
+
+	completeMetadata(parsed.Metadata, res.Request.URL)
+
+	// Only the first fetch of a feed backfills it with the items of more
+	// pages, later fetches are expected to find new items in the first page.
+	if pps.MaxPages > 1 && len(p.KnownItems) == 0 {
+		parsed.Items = f.fetchMorePages(ctx, cp, parser, &p, res.Request.URL, parsed, pps.MaxPages, log)
+	}
+
+	if cps.FetchFullContent {
+		f.fetchFullContent(ctx, cp, &p, parsed.Items, cps.ContentSelector, log)
+	}
+
+	log.Info("feed fetched and parsed", slog.Int("nFeedItems", len(parsed.Items)))
+	return &feed.FetchResult{
+		Items:        parsed.Items,
+		Hints:        parsed.Hints,
+		Metadata:     parsed.Metadata,
+		Timestamp:    timeutil.Now(),
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which may be
+// either a number of seconds or an HTTP date, returning the delay relative to
+// now. It returns zero if the value is empty, invalid or in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		// Clamp the value to avoid overflowing time.Duration.
+		seconds = min(max(seconds, 0), int64(math.MaxInt64/time.Second))
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
 }
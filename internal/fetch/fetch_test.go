@@ -1,9 +1,13 @@
 package fetch_test
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/alnvdl/varys/internal/feed"
 	"github.com/alnvdl/varys/internal/fetch"
@@ -35,11 +39,12 @@ func TestFetch(t *testing.T) {
 		feedType: "xml",
 		expectedItems: []feed.RawItem{
 			{
-				URL:      "http://example.com/item1",
-				Title:    "Item 1",
-				Authors:  "Author 1",
-				Content:  "Content 1",
-				Position: 0,
+				URL:       "http://example.com/item1",
+				Title:     "Item 1",
+				Authors:   "Author 1",
+				Content:   "Content 1",
+				Published: time.Date(2025, 1, 2, 22, 4, 5, 0, time.UTC).Unix(),
+				Position:  0,
 			},
 		},
 		expectedError: "",
@@ -104,28 +109,31 @@ func TestFetch(t *testing.T) {
 		feedType: "xml",
 		expectedItems: []feed.RawItem{
 			{
-				URL:      "http://example.com/item1",
-				Title:    "Item 1",
-				Authors:  "Author 1",
-				Content:  "Content 1",
-				Position: 0,
+				URL:       "http://example.com/item1",
+				Title:     "Item 1",
+				Authors:   "Author 1",
+				Content:   "Content 1",
+				Published: time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC).Unix(),
+				Position:  0,
 			},
 			{
 				Position: 1,
 			},
 			{
-				URL:      "http://example.com/item2",
-				Title:    "Item 2",
-				Authors:  "Author 2",
-				Content:  "Content 2",
-				Position: 2,
+				URL:       "http://example.com/item2",
+				Title:     "Item 2",
+				Authors:   "Author 2",
+				Content:   "Content 2",
+				Published: time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC).Unix(),
+				Position:  2,
 			},
 			{
-				URL:      "http://example.com/item3",
-				Title:    "Item 3",
-				Authors:  "Author 3",
-				Content:  "Content 3",
-				Position: 3,
+				URL:       "http://example.com/item3",
+				Title:     "Item 3",
+				Authors:   "Author 3",
+				Content:   "Content 3",
+				Published: time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC).Unix(),
+				Position:  3,
 			},
 		},
 		expectedError: "",
@@ -156,11 +164,15 @@ func TestFetch(t *testing.T) {
 				feedURL = server.URL
 			}
 
-			items, _, err := fetch.Fetch(fetch.FetchParams{
+			res, err := fetch.Fetch(context.Background(), fetch.FetchParams{
 				URL:      feedURL,
 				FeedName: test.desc,
 				FeedType: test.feedType,
 			})
+			var items []feed.RawItem
+			if res != nil {
+				items = res.Items
+			}
 
 			if (test.expectedError != "" && err == nil) || (err != nil && err.Error() != test.expectedError) {
 				t.Errorf("expected error %v, got %v", test.expectedError, err)
@@ -171,10 +183,242 @@ func TestFetch(t *testing.T) {
 			}
 
 			for i, expectedItem := range test.expectedItems {
-				if items[i] != expectedItem {
+				if !reflect.DeepEqual(items[i], expectedItem) {
 					t.Errorf("expected item %#v, got %#v", expectedItem, items[i])
 				}
 			}
 		})
 	}
 }
+
+func TestFetchConditional(t *testing.T) {
+	const (
+		etag         = `"v1"`
+		lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+		data         = `<rss><channel><item><title>Item 1</title><link>http://example.com/item1</link></item></channel></rss>`
+	)
+
+	tests := []struct {
+		desc                 string
+		etag                 string
+		lastModified         string
+		expectedNotModified  bool
+		expectedItems        int
+		expectedETag         string
+		expectedLastModified string
+	}{{
+		desc:                 "no validators",
+		expectedItems:        1,
+		expectedETag:         etag,
+		expectedLastModified: lastModified,
+	}, {
+		desc:                 "matching ETag",
+		etag:                 etag,
+		expectedNotModified:  true,
+		expectedETag:         etag,
+		expectedLastModified: lastModified,
+	}, {
+		desc:                 "matching Last-Modified",
+		lastModified:         lastModified,
+		expectedNotModified:  true,
+		expectedETag:         etag,
+		expectedLastModified: lastModified,
+	}, {
+		desc:                 "stale ETag",
+		etag:                 `"v0"`,
+		expectedItems:        1,
+		expectedETag:         etag,
+		expectedLastModified: lastModified,
+	}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == etag || r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res, err := fetch.Fetch(context.Background(), fetch.FetchParams{
+				URL:          server.URL,
+				FeedName:     test.desc,
+				FeedType:     "xml",
+				ETag:         test.etag,
+				LastModified: test.lastModified,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.NotModified != test.expectedNotModified {
+				t.Errorf("expected not modified %v, got %v", test.expectedNotModified, res.NotModified)
+			}
+			if len(res.Items) != test.expectedItems {
+				t.Errorf("expected %d items, got %d", test.expectedItems, len(res.Items))
+			}
+			if res.ETag != test.expectedETag {
+				t.Errorf("expected ETag %v, got %v", test.expectedETag, res.ETag)
+			}
+			if res.LastModified != test.expectedLastModified {
+				t.Errorf("expected last modified %v, got %v", test.expectedLastModified, res.LastModified)
+			}
+			if res.Timestamp == 0 {
+				t.Errorf("expected timestamp to be set")
+			}
+		})
+	}
+}
+
+func TestFetchHTTPError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/gone/for/good", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone/for/good", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte("<html><body><a href='/item1'>Gone</a></body></html>"))
+	})
+	mux.HandleFunc("/unavailable", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		desc                string
+		path                string
+		expectedStatusCode  int
+		expectedURL         string
+		expectedContentType string
+		expectedRetryAfter  time.Duration
+		expectedError       string
+	}{{
+		desc:                "gone after redirect",
+		path:                "/gone",
+		expectedStatusCode:  http.StatusGone,
+		expectedURL:         server.URL + "/gone/for/good",
+		expectedContentType: "text/html",
+		expectedError:       "unexpected HTTP status 410 Gone from " + server.URL + "/gone/for/good (content type text/html)",
+	}, {
+		desc:               "service unavailable",
+		path:               "/unavailable",
+		expectedStatusCode: http.StatusServiceUnavailable,
+		expectedURL:        server.URL + "/unavailable",
+		expectedError:      "unexpected HTTP status 503 Service Unavailable from " + server.URL + "/unavailable",
+	}, {
+		desc:               "too many requests with Retry-After",
+		path:               "/busy",
+		expectedStatusCode: http.StatusTooManyRequests,
+		expectedURL:        server.URL + "/busy",
+		expectedRetryAfter: 120 * time.Second,
+		expectedError:      "unexpected HTTP status 429 Too Many Requests from " + server.URL + "/busy",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res, err := fetch.Fetch(context.Background(), fetch.FetchParams{
+				URL:      server.URL + test.path,
+				FeedName: test.desc,
+				FeedType: "xml",
+			})
+			if res != nil {
+				t.Errorf("expected no result, got %#v", res)
+			}
+			var httpErr *feed.HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("expected HTTP error, got %v", err)
+			}
+			if httpErr.StatusCode != test.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", test.expectedStatusCode, httpErr.StatusCode)
+			}
+			if httpErr.URL != test.expectedURL {
+				t.Errorf("expected URL %s, got %s", test.expectedURL, httpErr.URL)
+			}
+			if httpErr.ContentType != test.expectedContentType {
+				t.Errorf("expected content type %s, got %s", test.expectedContentType, httpErr.ContentType)
+			}
+			if httpErr.RetryAfter != test.expectedRetryAfter {
+				t.Errorf("expected retry after %v, got %v", test.expectedRetryAfter, httpErr.RetryAfter)
+			}
+			if err.Error() != test.expectedError {
+				t.Errorf("expected error %q, got %q", test.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestFetchCanceled(t *testing.T) {
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	res, err := fetch.Fetch(ctx, fetch.FetchParams{
+		URL:      server.URL,
+		FeedName: "slow feed",
+		FeedType: "xml",
+	})
+	if res != nil {
+		t.Errorf("expected no result, got %#v", res)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled error, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		desc     string
+		value    string
+		expected time.Duration
+	}{{
+		desc:     "empty",
+		value:    "",
+		expected: 0,
+	}, {
+		desc:     "seconds",
+		value:    "120",
+		expected: 120 * time.Second,
+	}, {
+		desc:     "negative seconds",
+		value:    "-5",
+		expected: 0,
+	}, {
+		desc:     "HTTP date in the future",
+		value:    "Thu, 02 Jan 2025 15:14:05 GMT",
+		expected: 10 * time.Minute,
+	}, {
+		desc:     "HTTP date in the past",
+		value:    "Thu, 02 Jan 2025 14:04:05 GMT",
+		expected: 0,
+	}, {
+		desc:     "invalid",
+		value:    "soon",
+		expected: 0,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			result := fetch.ParseRetryAfter(test.value, now)
+			if result != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
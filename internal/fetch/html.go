@@ -2,11 +2,14 @@ package fetch
 
 import (
 	"bytes"
+	"cmp"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/alnvdl/varys/internal/feed"
 	"golang.org/x/net/html"
@@ -14,19 +17,50 @@ import (
 
 // htmlParams defines the parameters for parseHTML.
 type htmlParams struct {
-	Encoding        string            `json:"encoding"`
-	ContainerTag    string            `json:"container_tag"`
-	ContainerAttrs  map[string]string `json:"container_attrs"`
-	TitlePos        int               `json:"title_pos"`
-	BaseURL         string            `json:"base_url"`
-	AllowedPrefixes []string          `json:"allowed_prefixes"`
+	Encoding          string            `json:"encoding"`
+	ContainerSelector string            `json:"container_selector"`
+	ContainerTag      string            `json:"container_tag"`
+	ContainerAttrs    map[string]string `json:"container_attrs"`
+	TitlePos          int               `json:"title_pos"`
+	BaseURL           string            `json:"base_url"`
+	AllowedPrefixes   []string          `json:"allowed_prefixes"`
+	NextPageSelector  string            `json:"next_page_selector"`
+
+	// If Link is set, each container is a single item, and its fields are
+	// extracted with the following rules instead of from its anchors.
+	Link    *htmlField `json:"link"`
+	Title   *htmlField `json:"title"`
+	Date    *htmlField `json:"date"`
+	Authors *htmlField `json:"authors"`
+	Summary *htmlField `json:"summary"`
+	Image   *htmlField `json:"image"`
+}
+
+// htmlField maps elements inside a container of an HTML feed onto a field of
+// a feed item. Selector selects the elements among the descendants of the
+// container, or the container itself if empty. The value of an element is its
+// Attr attribute if set, or else its text (or the datetime attribute of time
+// elements for dates). Layout is the Go time layout of dates (e.g.,
+// "02/01/2006"), and it is only accepted for dates, which are otherwise
+// expected in the usual feed formats.
+type htmlField struct {
+	Selector string `json:"selector"`
+	Attr     string `json:"attr"`
+	Layout   string `json:"layout"`
 }
 
 const unknownTitle = "Unknown title"
 
 func (p *htmlParams) Validate() error {
-	if p.ContainerTag == "" {
-		return errors.New("container_tag cannot be empty")
+	if p.ContainerSelector != "" {
+		if p.ContainerTag != "" || len(p.ContainerAttrs) > 0 {
+			return errors.New("container_selector cannot be combined with container_tag or container_attrs")
+		}
+		if _, err := parseSelector(p.ContainerSelector); err != nil {
+			return fmt.Errorf("cannot parse container_selector: %v", err)
+		}
+	} else if p.ContainerTag == "" {
+		return errors.New("container_selector or container_tag must be set")
 	}
 	if p.TitlePos < -1 {
 		return errors.New("title_pos cannot be less than -1")
@@ -40,9 +74,185 @@ func (p *htmlParams) Validate() error {
 	if len(p.AllowedPrefixes) == 0 {
 		return errors.New("allowed_prefixes cannot be empty")
 	}
+	if p.NextPageSelector != "" {
+		if _, err := parseSelector(p.NextPageSelector); err != nil {
+			return fmt.Errorf("cannot parse next_page_selector: %v", err)
+		}
+	}
+	fields := []struct {
+		name  string
+		field *htmlField
+	}{
+		{"link", p.Link},
+		{"title", p.Title},
+		{"date", p.Date},
+		{"authors", p.Authors},
+		{"summary", p.Summary},
+		{"image", p.Image},
+	}
+	for _, f := range fields {
+		if f.field == nil {
+			continue
+		}
+		if p.Link == nil {
+			return fmt.Errorf("%s cannot be set without link", f.name)
+		}
+		if f.field.Layout != "" && f.name != "date" {
+			return fmt.Errorf("invalid %s: layout can only be set for date", f.name)
+		}
+		if err := f.field.validate(); err != nil {
+			return fmt.Errorf("invalid %s: %v", f.name, err)
+		}
+	}
 	return nil
 }
 
+func (f *htmlField) validate() error {
+	if f.Selector == "" {
+		return nil
+	}
+	if _, err := parseSelector(f.Selector); err != nil {
+		return fmt.Errorf("cannot parse selector: %v", err)
+	}
+	return nil
+}
+
+// elements returns the elements selected by a validated field in container,
+// in document order. A nil field selects nothing.
+func (f *htmlField) elements(container *html.Node) []*html.Node {
+	if f == nil {
+		return nil
+	}
+	if f.Selector == "" {
+		return []*html.Node{container}
+	}
+	sel, _ := parseSelector(f.Selector)
+	var elems []*html.Node
+	for n := range container.Descendants() {
+		if sel.match(n) {
+			elems = append(elems, n)
+		}
+	}
+	return elems
+}
+
+// values returns the non-empty values of the elements selected by the field
+// in container. If the field has no Attr, the first of attrs found in each
+// element is used, or else its text.
+func (f *htmlField) values(container *html.Node, attrs ...string) []string {
+	if f != nil && f.Attr != "" {
+		attrs = []string{f.Attr}
+	}
+	var values []string
+	for _, n := range f.elements(container) {
+		var value string
+		for _, attr := range attrs {
+			if value = strings.TrimSpace(attrValue(n, attr)); value != "" {
+				break
+			}
+		}
+		if len(attrs) == 0 {
+			value = nodeText(n)
+		}
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// value returns the first value of the field in container. See
+// [htmlField.values].
+func (f *htmlField) value(container *html.Node, attrs ...string) string {
+	if values := f.values(container, attrs...); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// date returns the date selected by the field in container as a Unix
+// timestamp, or 0 if there is none or it cannot be parsed. If the field has no
+// Attr, the datetime attribute of time elements is used like the href of
+// links.
+func (f *htmlField) date(container *html.Node) int64 {
+	v := f.dateValue(container)
+	if v == "" {
+		return 0
+	}
+	var date int64
+	if f.Layout == "" {
+		date = parseDate(v)
+	} else if t, err := time.Parse(f.Layout, v); err == nil && !t.Before(minDate) {
+		date = t.Unix()
+	}
+	if date == 0 {
+		slog.Info("cannot parse item date", slog.String("date", v), slog.String("layout", f.Layout))
+	}
+	return date
+}
+
+// dateValue returns the first date value of the field in container.
+func (f *htmlField) dateValue(container *html.Node) string {
+	if f != nil && f.Attr != "" {
+		return f.value(container)
+	}
+	for _, n := range f.elements(container) {
+		var v string
+		if n.Type == html.ElementNode && n.Data == "time" {
+			v = strings.TrimSpace(attrValue(n, "datetime"))
+		}
+		if v = cmp.Or(v, nodeText(n)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// html returns the HTML content of the first element selected by the field in
+// container. If the field has an Attr, its value is escaped instead.
+func (f *htmlField) html(container *html.Node) string {
+	if f != nil && f.Attr != "" {
+		return html.EscapeString(f.value(container))
+	}
+	elems := f.elements(container)
+	if len(elems) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	for c := elems[0].FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&buf, c)
+	}
+	return buf.String()
+}
+
+// nodeText returns the text inside n with whitespace collapsed, ignoring
+// scripts and styles.
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	for d := range n.Descendants() {
+		if d.Type == html.TextNode && d.Parent.Data != "script" && d.Parent.Data != "style" {
+			b.WriteString(d.Data)
+			b.WriteByte(' ')
+		}
+	}
+	return collapseSpace(b.String())
+}
+
+// containerSelector returns the selector matching the containers of items,
+// which is either container_selector or the equivalent of container_tag and
+// container_attrs. The params must have been validated.
+func (p *htmlParams) containerSelector() selector {
+	if p.ContainerSelector != "" {
+		sel, _ := parseSelector(p.ContainerSelector)
+		return sel
+	}
+	c := compoundSelector{tag: p.ContainerTag}
+	for key, val := range p.ContainerAttrs {
+		c.attrs = append(c.attrs, attrSelector{key: key, op: "=", val: val})
+	}
+	return selector{{c}}
+}
+
 // candidateItem is a candidate feed item extracted from HTML content.
 type candidateItem struct {
 	url string
@@ -116,7 +326,7 @@ func extractCandidateItem(anchor *html.Node, baseURL *url.URL, allowedPrefixes [
 
 // parseHTML parses an HTML page and extracts feed items based on the given
 // params.
-func parseHTML(data []byte, params any) ([]feed.RawItem, error) {
+func parseHTML(data []byte, params any) (*parseResult, error) {
 	var p htmlParams
 	if err := feed.ParseParams(params, &p); err != nil {
 		return nil, fmt.Errorf("cannot parse HTML feed params: %v", err)
@@ -135,16 +345,17 @@ func parseHTML(data []byte, params any) ([]feed.RawItem, error) {
 		}
 	}
 
-	cisByURL := make(map[string]*candidateItem)
 	doc, err := html.ParseWithOptions(bytes.NewReader(data), html.ParseOptionEnableScripting(false))
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse HTML: %v", err)
 	}
 
+	// Containers nested in other containers are not searched again.
+	containerSelector := p.containerSelector()
 	var containers []*html.Node
 	var findContainers func(*html.Node)
 	findContainers = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == p.ContainerTag && matchAttrs(n, p.ContainerAttrs) {
+		if containerSelector.match(n) {
 			containers = append(containers, n)
 			return
 		}
@@ -154,6 +365,46 @@ func parseHTML(data []byte, params any) ([]feed.RawItem, error) {
 	}
 	findContainers(doc)
 
+	var rawItems []feed.RawItem
+	if p.Link != nil {
+		rawItems = p.fieldItems(containers, baseURL)
+	} else {
+		rawItems = p.anchorItems(containers, baseURL)
+	}
+
+	return &parseResult{
+		Items:    rawItems,
+		Metadata: htmlMetadata(doc, baseURL),
+		NextPage: p.nextPage(doc, baseURL),
+	}, nil
+}
+
+// nextPage returns the URL in the href attribute of the first element in doc
+// matching the next page selector, or an empty string if there is no such
+// element or its URL is not allowed.
+func (p *htmlParams) nextPage(doc *html.Node, baseURL *url.URL) string {
+	if p.NextPageSelector == "" {
+		return ""
+	}
+	// The parseParams call should have validated the selector already.
+	sel, _ := parseSelector(p.NextPageSelector)
+	for n := range doc.Descendants() {
+		if sel.match(n) {
+			href := strings.TrimSpace(attrValue(n, "href"))
+			if href == "" {
+				return ""
+			}
+			return urlToString(resolveURL(href, baseURL, p.AllowedPrefixes))
+		}
+	}
+	return ""
+}
+
+// anchorItems extracts feed items from the anchors inside containers. Anchors
+// with the same URL are merged into a single item, and the title of items is
+// picked from their parts according to title_pos.
+func (p *htmlParams) anchorItems(containers []*html.Node, baseURL *url.URL) []feed.RawItem {
+	cisByURL := make(map[string]*candidateItem)
 	var position int
 	for _, container := range containers {
 		var findCandidateItems func(*html.Node)
@@ -203,8 +454,50 @@ func parseHTML(data []byte, params any) ([]feed.RawItem, error) {
 			Position: ci.position,
 		})
 	}
+	return rawItems
+}
 
-	return rawItems, nil
+// fieldItems extracts a feed item from each container using the field rules.
+// Containers without a valid link are skipped, as are containers linking to
+// items already extracted from previous ones.
+func (p *htmlParams) fieldItems(containers []*html.Node, baseURL *url.URL) []feed.RawItem {
+	var rawItems []feed.RawItem
+	seen := make(map[string]bool)
+	for _, container := range containers {
+		var link *html.Node
+		var itemURL string
+		attr := cmp.Or(p.Link.Attr, "href")
+		for _, n := range p.Link.elements(container) {
+			itemURL = urlToString(resolveURL(strings.TrimSpace(attrValue(n, attr)), baseURL, p.AllowedPrefixes))
+			if itemURL != "" {
+				link = n
+				break
+			}
+		}
+		if itemURL == "" || seen[itemURL] {
+			continue
+		}
+		seen[itemURL] = true
+
+		title := nodeText(link)
+		if p.Title != nil {
+			title = p.Title.value(container)
+		}
+		var atts []feed.Attachment
+		if image := p.Image.value(container, "src", "data-src"); image != "" {
+			atts = append(atts, feed.Attachment{URL: image, Thumbnail: image})
+		}
+		rawItems = append(rawItems, feed.RawItem{
+			URL:         itemURL,
+			Title:       cmp.Or(title, unknownTitle),
+			Authors:     strings.Join(p.Authors.values(container), ", "),
+			Content:     silentlySanitizeHTML(p.Summary.html(container), baseURL),
+			Published:   p.Date.date(container),
+			Attachments: sanitizeAttachments(atts, baseURL),
+			Position:    len(rawItems),
+		})
+	}
+	return rawItems
 }
 
 func longestNonImagePart(parts []string) string {
@@ -217,15 +510,3 @@ func longestNonImagePart(parts []string) string {
 	}
 	return longest
 }
-
-// matchAttrs returns true if the given node n has all the attributes specified
-// in attrs with the same values.
-func matchAttrs(n *html.Node, attrs map[string]string) bool {
-	nMatches := 0
-	for _, attr := range n.Attr {
-		if val, ok := attrs[attr.Key]; ok && val == attr.Val {
-			nMatches++
-		}
-	}
-	return nMatches == len(attrs)
-}
@@ -1,6 +1,7 @@
 package fetch
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/alnvdl/varys/internal/feed"
@@ -22,7 +23,7 @@ func TestParseHTML(t *testing.T) {
 		params: `{`,
 		err:    "cannot parse HTML feed params: cannot unmarshal: json: cannot unmarshal string into Go value of type fetch.htmlParams",
 	}, {
-		desc: "error: container tag cannot be empty",
+		desc: "error: container selector or tag must be set",
 		html: `<html><body>
 			<div class="target-container"></div>
 		</body></html>`,
@@ -32,7 +33,30 @@ func TestParseHTML(t *testing.T) {
 			"base_url":         "https://example.com",
 			"allowed_prefixes": []string{"https://example.com"},
 		},
-		err: "cannot parse HTML feed params: cannot validate: container_tag cannot be empty",
+		err: "cannot parse HTML feed params: cannot validate: container_selector or container_tag must be set",
+	}, {
+		desc: "error: container selector cannot be combined with container tag",
+		html: `<html><body>
+			<div class="target-container"></div>
+		</body></html>`,
+		params: map[string]any{
+			"container_selector": "div.target-container",
+			"container_tag":      "div",
+			"base_url":           "https://example.com",
+			"allowed_prefixes":   []string{"https://example.com"},
+		},
+		err: "cannot parse HTML feed params: cannot validate: container_selector cannot be combined with container_tag or container_attrs",
+	}, {
+		desc: "error: invalid container selector",
+		html: `<html><body>
+			<div class="target-container"></div>
+		</body></html>`,
+		params: map[string]any{
+			"container_selector": "div:hover",
+			"base_url":           "https://example.com",
+			"allowed_prefixes":   []string{"https://example.com"},
+		},
+		err: `cannot parse HTML feed params: cannot validate: cannot parse container_selector: unsupported pseudo-class "hover" at position 4 in selector "div:hover"`,
 	}, {
 		desc: "error: title position cannot be less than -1",
 		html: `<html><body>
@@ -178,6 +202,34 @@ func TestParseHTML(t *testing.T) {
 			Title:   "Unknown title",
 			Content: "",
 		}},
+	}, {
+		desc: "success: container selector matching one of many classes inside another element",
+		html: `<html><body>
+			<div class="card news featured"><a href="/outside">Outside</a></div>
+			<main id="main">
+				<div class="card news featured">
+					<a href="/url1">Title 1</a>
+					<div class="featured"><a href="/url2">Title 2</a></div>
+				</div>
+				<div class="card"><a href="/url3">Title 3</a></div>
+				<section class="featured"><a href="/url4">Title 4</a></section>
+			</main>
+		</body></html>`,
+		params: map[string]any{
+			"container_selector": "#main div.featured",
+			"title_pos":          0,
+			"base_url":           "https://example.com",
+			"allowed_prefixes":   []string{"https://example.com"},
+		},
+		expected: []feed.RawItem{{
+			URL:     "https://example.com/url1",
+			Title:   "Title 1",
+			Content: "<p>Title 1</p>",
+		}, {
+			URL:     "https://example.com/url2",
+			Title:   "Title 2",
+			Content: "<p>Title 2</p>",
+		}},
 	}, {
 		desc: "success: img tag with invalid src URL should not crash",
 		html: `<html><body>
@@ -201,18 +253,19 @@ func TestParseHTML(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
-			rawItems, err := parseHTML([]byte(test.html), test.params)
+			res, err := parseHTML([]byte(test.html), test.params)
 			if test.err != "" {
 				if err == nil || err.Error() != test.err {
 					t.Fatalf("expected error: %v, got: %v", test.err, err)
 				}
-				if rawItems != nil {
-					t.Fatalf("expected no items, got %d", len(rawItems))
+				if res != nil {
+					t.Fatalf("expected no items, got %d", len(res.Items))
 				}
 			} else {
 				if err != nil {
 					t.Fatalf("expected no error, got: %v", err)
 				}
+				rawItems := res.Items
 				if len(rawItems) != len(test.expected) {
 					t.Fatalf("expected %d items, got %d", len(test.expected), len(rawItems))
 				}
@@ -227,3 +280,224 @@ func TestParseHTML(t *testing.T) {
 		})
 	}
 }
+
+func TestParseHTMLFields(t *testing.T) {
+	page := `<html><body>
+		<main id="main">
+			<article class="post">
+				<h2><a href="/news/1"> First <em>post</em> </a></h2>
+				<time datetime="2024-05-01T10:00:00Z">May 1</time>
+				<span class="byline"><a href="/u/ann">Ann</a> and <a href="/u/bob">Bob</a></span>
+				<p class="teaser">A <b>short</b> teaser with a <a href="/more">link</a>.<script>alert(1)</script></p>
+				<img data-src="/img/1.png">
+			</article>
+			<article class="post">
+				<h2><a href="https://other.com/ad">Ad</a><a href="/news/2">Second post</a></h2>
+				<span class="date">02/05/2024</span>
+			</article>
+			<article class="post">
+				<h2>No link</h2>
+			</article>
+			<article class="post">
+				<h2><a href="/news/1">Duplicate</a></h2>
+			</article>
+		</main>
+	</body></html>`
+
+	tests := []struct {
+		desc     string
+		params   map[string]any
+		expected []feed.RawItem
+		err      string
+	}{{
+		desc: "success: all fields",
+		params: map[string]any{
+			"container_selector": "#main article.post",
+			"link":               map[string]any{"selector": "h2 a"},
+			"title":              map[string]any{"selector": "h2 > a:last-child"},
+			"date":               map[string]any{"selector": "time", "attr": "datetime"},
+			"authors":            map[string]any{"selector": ".byline a"},
+			"summary":            map[string]any{"selector": ".teaser"},
+			"image":              map[string]any{"selector": "img"},
+		},
+		expected: []feed.RawItem{{
+			URL:       "https://example.com/news/1",
+			Title:     "First post",
+			Authors:   "Ann, Bob",
+			Content:   `A <b>short</b> teaser with a <a href="https://example.com/more">link</a>.`,
+			Published: 1714557600,
+			Attachments: []feed.Attachment{{
+				URL:       "https://example.com/img/1.png",
+				Thumbnail: "https://example.com/img/1.png",
+			}},
+			Position: 0,
+		}, {
+			URL:      "https://example.com/news/2",
+			Title:    "Second post",
+			Position: 1,
+		}},
+	}, {
+		desc: "success: title defaults to the text of the link and dates use the layout",
+		params: map[string]any{
+			"container_selector": "#main article.post",
+			"link":               map[string]any{"selector": "h2 a"},
+			"date":               map[string]any{"selector": ".date", "layout": "02/01/2006"},
+		},
+		expected: []feed.RawItem{{
+			URL:      "https://example.com/news/1",
+			Title:    "First post",
+			Position: 0,
+		}, {
+			URL:       "https://example.com/news/2",
+			Title:     "Second post",
+			Published: 1714608000,
+			Position:  1,
+		}},
+	}, {
+		desc: "success: dates default to the datetime of time elements",
+		params: map[string]any{
+			"container_selector": "#main article.post",
+			"link":               map[string]any{"selector": "h2 a"},
+			"date":               map[string]any{"selector": "time, .date"},
+		},
+		expected: []feed.RawItem{{
+			URL:       "https://example.com/news/1",
+			Title:     "First post",
+			Published: 1714557600,
+			Position:  0,
+		}, {
+			URL:      "https://example.com/news/2",
+			Title:    "Second post",
+			Position: 1,
+		}},
+	}, {
+		desc: "success: link from the container itself",
+		params: map[string]any{
+			"container_selector": "h2 > a[href^='/news/']",
+			"link":               map[string]any{},
+			"summary":            map[string]any{"attr": "href"},
+		},
+		expected: []feed.RawItem{{
+			URL:      "https://example.com/news/1",
+			Title:    "First post",
+			Content:  "/news/1",
+			Position: 0,
+		}, {
+			URL:      "https://example.com/news/2",
+			Title:    "Second post",
+			Content:  "/news/2",
+			Position: 1,
+		}},
+	}, {
+		desc: "error: fields cannot be set without link",
+		params: map[string]any{
+			"container_selector": "article",
+			"title":              map[string]any{"selector": "h2"},
+		},
+		err: "cannot parse HTML feed params: cannot validate: title cannot be set without link",
+	}, {
+		desc: "error: layout can only be set for dates",
+		params: map[string]any{
+			"container_selector": "article",
+			"link":               map[string]any{"selector": "a"},
+			"title":              map[string]any{"selector": "h2", "layout": "2006"},
+		},
+		err: "cannot parse HTML feed params: cannot validate: invalid title: layout can only be set for date",
+	}, {
+		desc: "error: invalid field selector",
+		params: map[string]any{
+			"container_selector": "article",
+			"link":               map[string]any{"selector": "a["},
+		},
+		err: `cannot parse HTML feed params: cannot validate: invalid link: cannot parse selector: expected attribute name at the end in selector "a["`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.params["base_url"] = "https://example.com"
+			test.params["allowed_prefixes"] = []string{"https://example.com"}
+			res, err := parseHTML([]byte(page), test.params)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error: %v, got: %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if !reflect.DeepEqual(res.Items, test.expected) {
+				t.Errorf("expected items %#v, got %#v", test.expected, res.Items)
+			}
+		})
+	}
+}
+
+func TestParseHTMLNextPage(t *testing.T) {
+	page := `<html><body>
+		<div class="item"><a href="/news/1">News 1</a></div>
+		<nav class="pagination">
+			<a class="prev" href="/news?page=0">Previous</a>
+			<a class="next" href=" /news?page=2 ">Next</a>
+			<a class="external" href="https://other.com/news?page=2">Other</a>
+			<span class="current">1</span>
+		</nav>
+	</body></html>`
+
+	tests := []struct {
+		desc             string
+		nextPageSelector string
+		expected         string
+		err              string
+	}{{
+		desc:     "no next page selector",
+		expected: "",
+	}, {
+		desc:             "next page link",
+		nextPageSelector: ".pagination a.next",
+		expected:         "https://example.com/news?page=2",
+	}, {
+		desc:             "first matching link",
+		nextPageSelector: ".pagination a",
+		expected:         "https://example.com/news?page=0",
+	}, {
+		desc:             "next page selector matching nothing",
+		nextPageSelector: "a[rel=next]",
+		expected:         "",
+	}, {
+		desc:             "next page selector matching an element without href",
+		nextPageSelector: ".pagination .current",
+		expected:         "",
+	}, {
+		desc:             "next page link not allowed",
+		nextPageSelector: ".pagination .external",
+		expected:         "",
+	}, {
+		desc:             "invalid next page selector",
+		nextPageSelector: "a:hover",
+		err:              `cannot parse HTML feed params: cannot validate: cannot parse next_page_selector: unsupported pseudo-class "hover" at position 2 in selector "a:hover"`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res, err := parseHTML([]byte(page), map[string]any{
+				"container_selector": ".item",
+				"base_url":           "https://example.com",
+				"allowed_prefixes":   []string{"https://example.com"},
+				"next_page_selector": test.nextPageSelector,
+			})
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error: %v, got: %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if res.NextPage != test.expected {
+				t.Errorf("expected next page %q, got %q", test.expected, res.NextPage)
+			}
+		})
+	}
+}
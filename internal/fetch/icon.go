@@ -0,0 +1,73 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"github.com/alnvdl/varys/internal/timeutil"
+)
+
+// maxIconSize is the maximum size of feed icons, in bytes.
+const maxIconSize = 256 * 1024
+
+// iconTypes are the MIME types accepted for feed icons.
+var iconTypes = map[string]bool{
+	"image/avif":    true,
+	"image/bmp":     true,
+	"image/gif":     true,
+	"image/jpeg":    true,
+	"image/png":     true,
+	"image/svg+xml": true,
+	"image/webp":    true,
+	"image/x-icon":  true,
+}
+
+// FetchIcon fetches the icon at iconURL using a Fetcher with the default
+// client params. See [Fetcher.FetchIcon].
+func FetchIcon(ctx context.Context, iconURL string) (*feed.Icon, error) {
+	return defaultFetcher.FetchIcon(ctx, iconURL)
+}
+
+// FetchIcon fetches the icon of a feed at iconURL. Only common image formats
+// are accepted, and their type is detected from the content of the icon when
+// possible, as servers often get it wrong (e.g., by serving a web page in
+// place of a missing favicon).
+func (f *Fetcher) FetchIcon(ctx context.Context, iconURL string) (*feed.Icon, error) {
+	log := slog.With(slog.String("iconURL", iconURL))
+	log.Info("fetching icon")
+
+	p, err := f.get(ctx, f.params, iconURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch icon: %w", err)
+	}
+	if len(p.data) == 0 {
+		return nil, fmt.Errorf("icon is empty")
+	}
+	if len(p.data) > maxIconSize {
+		return nil, fmt.Errorf("icon exceeds %d bytes", maxIconSize)
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(p.data))
+	if mimeType == "text/xml" || mimeType == "text/plain" {
+		// SVG images are XML documents, so they can only be told apart by
+		// the type declared by the server.
+		if declared, _, _ := mime.ParseMediaType(p.contentType); declared == "image/svg+xml" {
+			mimeType = declared
+		}
+	}
+	if !iconTypes[mimeType] {
+		return nil, fmt.Errorf("unsupported icon type %s", mimeType)
+	}
+
+	log.Info("icon fetched", slog.String("mimeType", mimeType), slog.Int("size", len(p.data)))
+	return &feed.Icon{
+		URL:       iconURL,
+		MimeType:  mimeType,
+		Data:      p.data,
+		FetchedAt: timeutil.Now(),
+	}, nil
+}
@@ -0,0 +1,102 @@
+package fetch_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alnvdl/varys/internal/fetch"
+)
+
+func TestFetchIcon(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01")
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1"><rect width="1" height="1"/></svg>`)
+
+	tests := []struct {
+		desc             string
+		contentType      string
+		data             []byte
+		status           int
+		expectedMimeType string
+		expectedError    string
+	}{{
+		desc:             "PNG icon",
+		contentType:      "image/png",
+		data:             png,
+		expectedMimeType: "image/png",
+	}, {
+		desc:             "type is detected from the content",
+		contentType:      "application/octet-stream",
+		data:             png,
+		expectedMimeType: "image/png",
+	}, {
+		desc:             "SVG icon declared by the server",
+		contentType:      "image/svg+xml; charset=utf-8",
+		data:             svg,
+		expectedMimeType: "image/svg+xml",
+	}, {
+		desc:          "SVG icon not declared by the server",
+		contentType:   "text/plain",
+		data:          svg,
+		expectedError: "unsupported icon type text/plain",
+	}, {
+		desc:          "web page served as an icon",
+		contentType:   "image/x-icon",
+		data:          []byte("<html><body>Not found</body></html>"),
+		expectedError: "unsupported icon type text/html",
+	}, {
+		desc:          "empty icon",
+		contentType:   "image/png",
+		data:          []byte{},
+		expectedError: "icon is empty",
+	}, {
+		desc:          "icon too large",
+		contentType:   "image/png",
+		data:          append(png, make([]byte, 256*1024)...),
+		expectedError: "icon exceeds 262144 bytes",
+	}, {
+		desc:          "icon not found",
+		status:        http.StatusNotFound,
+		expectedError: "cannot fetch icon: unexpected HTTP status 404 Not Found",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+					return
+				}
+				w.Header().Set("Content-Type", test.contentType)
+				w.Write(test.data)
+			}))
+			defer server.Close()
+
+			icon, err := fetch.FetchIcon(context.Background(), server.URL+"/favicon.ico")
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if icon.URL != server.URL+"/favicon.ico" {
+				t.Errorf("expected icon URL %q, got %q", server.URL+"/favicon.ico", icon.URL)
+			}
+			if icon.MimeType != test.expectedMimeType {
+				t.Errorf("expected MIME type %q, got %q", test.expectedMimeType, icon.MimeType)
+			}
+			if !bytes.Equal(icon.Data, test.data) {
+				t.Errorf("expected icon data %q, got %q", test.data, icon.Data)
+			}
+			if icon.FetchedAt == 0 {
+				t.Errorf("expected icon fetch time to be set")
+			}
+		})
+	}
+}
@@ -34,7 +34,7 @@ func (p *imageParams) Validate() error {
 
 // parseImage parses image data and returns a single RawItem. This can be used
 // for images hosted in the same URL that get updated frequently.
-func parseImage(data []byte, params any) ([]feed.RawItem, error) {
+func parseImage(data []byte, params any) (*parseResult, error) {
 	var p imageParams
 	if err := feed.ParseParams(params, &p); err != nil {
 		return nil, fmt.Errorf("cannot parse image params: %v", err)
@@ -67,5 +67,5 @@ func parseImage(data []byte, params any) ([]feed.RawItem, error) {
 		Content: silentlySanitizeHTML(buf.String(), nil),
 	}
 
-	return []feed.RawItem{rawItem}, nil
+	return &parseResult{Items: []feed.RawItem{rawItem}}, nil
 }
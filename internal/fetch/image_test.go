@@ -61,18 +61,19 @@ func TestParseImage(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
 			now := time.Now()
-			rawItems, err := fetch.ParseImage(test.data, test.params)
+			res, err := fetch.ParseImage(test.data, test.params)
 			if test.err != "" {
 				if err == nil || err.Error() != test.err {
 					t.Fatalf("expected error: %v, got: %v", test.err, err)
 				}
-				if rawItems != nil {
-					t.Fatalf("expected no items, got %d", len(rawItems))
+				if res != nil {
+					t.Fatalf("expected no items, got %d", len(res.Items))
 				}
 			} else {
 				if err != nil {
 					t.Fatalf("expected no error, got: %v", err)
 				}
+				rawItems := res.Items
 				expectedItems := test.expected(now)
 				if len(rawItems) != len(expectedItems) {
 					t.Fatalf("expected %d items, got %d", len(expectedItems), len(rawItems))
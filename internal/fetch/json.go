@@ -0,0 +1,161 @@
+package fetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/alnvdl/varys/internal/feed"
+)
+
+// JSONFeed is a JSON Feed document (https://jsonfeed.org/), version 1.0 or
+// 1.1.
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	HomePageURL string         `json:"home_page_url"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Language    string         `json:"language"`
+	Items       []JSONFeedItem `json:"items"`
+	Authors     []JSONAuthor   `json:"authors"`
+	// Author is the single author of JSON Feed 1.0, replaced by Authors in
+	// 1.1.
+	Author *JSONAuthor `json:"author"`
+}
+
+type JSONFeedItem struct {
+	ID            json.RawMessage `json:"id"`
+	URL           string          `json:"url"`
+	ExternalURL   string          `json:"external_url"`
+	Title         string          `json:"title"`
+	ContentHTML   string          `json:"content_html"`
+	ContentText   string          `json:"content_text"`
+	Summary       string          `json:"summary"`
+	DatePublished string          `json:"date_published"`
+	DateModified  string          `json:"date_modified"`
+	Authors       []JSONAuthor    `json:"authors"`
+	Author        *JSONAuthor     `json:"author"`
+	Image         string          `json:"image"`
+	Attachments   []struct {
+		URL               string  `json:"url"`
+		MimeType          string  `json:"mime_type"`
+		SizeInBytes       float64 `json:"size_in_bytes"`
+		DurationInSeconds float64 `json:"duration_in_seconds"`
+	} `json:"attachments"`
+}
+
+type JSONAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonParams struct{}
+
+func (p *jsonParams) Validate() error {
+	return nil
+}
+
+// maxTextTitleLength is the maximum length of titles taken from the text of
+// items without a title, in runes.
+const maxTextTitleLength = 80
+
+// parseJSON parses a JSON Feed document. Relative URLs are resolved against
+// the home page URL of the feed.
+func parseJSON(data []byte, params any) (*parseResult, error) {
+	var p jsonParams
+	if err := feed.ParseParams(params, &p); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON feed params: %v", err)
+	}
+
+	var jf JSONFeed
+	if err := json.Unmarshal(data, &jf); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON feed: %v", err)
+	}
+	if !strings.Contains(jf.Version, "jsonfeed.org/version/") {
+		return nil, fmt.Errorf("cannot parse JSON feed: unknown version %q", jf.Version)
+	}
+
+	baseURL := absoluteURL(jf.HomePageURL)
+	feedAuthors := jsonAuthors(jf.Authors, jf.Author)
+	var feedItems []feed.RawItem
+	for pos, item := range jf.Items {
+		itemURL := coalesce(item.URL, item.ExternalURL)
+		if id := jsonID(item.ID); itemURL == "" && absoluteURL(id) != nil {
+			// The id is often the permalink of items without a url.
+			itemURL = id
+		}
+		resolvedItemURL := resolveURL(itemURL, baseURL, nil)
+		content := item.ContentHTML
+		if content == "" {
+			content = inferParagraphs(html.EscapeString(item.ContentText))
+		}
+		var atts []feed.Attachment
+		for _, a := range item.Attachments {
+			atts = append(atts, feed.Attachment{
+				URL:       a.URL,
+				MimeType:  a.MimeType,
+				Length:    int64(a.SizeInBytes),
+				Duration:  int64(a.DurationInSeconds),
+				Thumbnail: item.Image,
+			})
+		}
+		feedItems = append(feedItems, feed.RawItem{
+			GUID:        strings.TrimSpace(jsonID(item.ID)),
+			URL:         urlToString(resolvedItemURL),
+			Title:       strings.TrimSpace(coalesce(item.Title, item.Summary, textTitle(item.ContentText))),
+			Authors:     coalesce(jsonAuthors(item.Authors, item.Author), feedAuthors),
+			Content:     silentlySanitizeHTML(content, resolvedItemURL),
+			Published:   parseDate(item.DatePublished),
+			Updated:     parseDate(item.DateModified),
+			Attachments: sanitizeAttachments(atts, resolvedItemURL),
+			Position:    pos,
+		})
+	}
+	// The favicon is preferred, as icons are shown in small sizes.
+	metadata := newMetadata(jf.Title, jf.Description, jf.HomePageURL, jf.Language, baseURL, jf.Favicon, jf.Icon)
+	return &parseResult{Items: feedItems, Metadata: metadata}, nil
+}
+
+// jsonID returns the id of a JSON Feed item as a string. The spec requires ids
+// to be strings, but some publishers use numbers.
+func jsonID(raw json.RawMessage) string {
+	var id any
+	if err := json.Unmarshal(raw, &id); err != nil {
+		return ""
+	}
+	switch id := id.(type) {
+	case string:
+		return id
+	case float64:
+		return strings.TrimSpace(string(raw))
+	}
+	return ""
+}
+
+// jsonAuthors returns the names of the given JSON Feed authors, falling back
+// to the single author of JSON Feed 1.0.
+func jsonAuthors(authors []JSONAuthor, author *JSONAuthor) string {
+	if len(authors) == 0 && author != nil {
+		authors = []JSONAuthor{*author}
+	}
+	var names []string
+	for _, a := range authors {
+		if name := strings.TrimSpace(a.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// textTitle returns a title for an item without one, based on the first line
+// of its text. JSON Feed allows items without titles (e.g., microblog posts).
+func textTitle(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	runes := []rune(strings.TrimSpace(line))
+	if len(runes) > maxTextTitleLength {
+		return strings.TrimSpace(string(runes[:maxTextTitleLength])) + "…"
+	}
+	return string(runes)
+}
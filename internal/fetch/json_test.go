@@ -0,0 +1,203 @@
+package fetch_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"github.com/alnvdl/varys/internal/fetch"
+)
+
+func TestParseJSON(t *testing.T) {
+	date := func(s string) int64 {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t.Unix()
+	}
+
+	tests := []struct {
+		desc   string
+		json   string
+		params any
+
+		expected []feed.RawItem
+		err      string
+	}{{
+		desc:   "error: corrupted params",
+		json:   "",
+		params: "{",
+		err:    "cannot parse JSON feed params: cannot unmarshal: json: cannot unmarshal string into Go value of type fetch.jsonParams",
+	}, {
+		desc: "error: invalid JSON",
+		json: "{",
+		err:  "cannot parse JSON feed: unexpected end of JSON input",
+	}, {
+		desc: "error: not a JSON feed",
+		json: `{"items": []}`,
+		err:  `cannot parse JSON feed: unknown version ""`,
+	}, {
+		desc: "feed with 0 items",
+		json: `{
+			"version": "https://jsonfeed.org/version/1.1",
+			"home_page_url": "http://example.com",
+			"items": []
+		}`,
+		expected: []feed.RawItem{},
+	}, {
+		desc: "feed with relative item URLs and HTML content",
+		json: `{
+			"version": "https://jsonfeed.org/version/1.1",
+			"home_page_url": "http://example.com/blog/",
+			"items": [{
+				"id": "1",
+				"url": "posts/item1",
+				"title": " Item 1 ",
+				"content_html": "<p>Content 1 <a href=\"/about\">about</a></p><script>alert(1)</script>",
+				"date_published": "2025-03-02T09:30:15-03:00",
+				"date_modified": "2025-03-03T10:00:00Z",
+				"authors": [{"name": "Author 1"}, {"name": "Author 2"}]
+			}, {
+				"id": "http://example.com/blog/posts/item2",
+				"title": "Item 2",
+				"content_html": "Content 2"
+			}]
+		}`,
+		expected: []feed.RawItem{{
+			GUID:      "1",
+			URL:       "http://example.com/blog/posts/item1",
+			Title:     "Item 1",
+			Authors:   "Author 1, Author 2",
+			Content:   `<p>Content 1 <a href="http://example.com/about">about</a></p>`,
+			Published: date("2025-03-02T12:30:15Z"),
+			Updated:   date("2025-03-03T10:00:00Z"),
+			Position:  0,
+		}, {
+			GUID:     "http://example.com/blog/posts/item2",
+			URL:      "http://example.com/blog/posts/item2",
+			Title:    "Item 2",
+			Content:  "Content 2",
+			Position: 1,
+		}},
+	}, {
+		desc: "feed with text content and no titles",
+		json: `{
+			"version": "https://jsonfeed.org/version/1",
+			"home_page_url": "http://example.com",
+			"author": {"name": "Feed Author"},
+			"items": [{
+				"id": 42,
+				"external_url": "http://other.example.com/article",
+				"content_text": "First line <b>escaped</b>\n\nSecond line"
+			}, {
+				"id": "3",
+				"url": "/item3",
+				"summary": "Summary 3",
+				"content_text": "Content 3",
+				"author": {"name": "Item Author"}
+			}, {
+				"id": "4",
+				"url": "/item4",
+				"content_text": "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore"
+			}]
+		}`,
+		expected: []feed.RawItem{{
+			GUID:     "42",
+			URL:      "http://other.example.com/article",
+			Title:    "First line <b>escaped</b>",
+			Authors:  "Feed Author",
+			Content:  "<p>First line &lt;b&gt;escaped&lt;/b&gt;</p><p>Second line</p>",
+			Position: 0,
+		}, {
+			GUID:     "3",
+			URL:      "http://example.com/item3",
+			Title:    "Summary 3",
+			Authors:  "Item Author",
+			Content:  "<p>Content 3</p>",
+			Position: 1,
+		}, {
+			GUID:     "4",
+			URL:      "http://example.com/item4",
+			Title:    "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor i…",
+			Authors:  "Feed Author",
+			Content:  "<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore</p>",
+			Position: 2,
+		}},
+	}, {
+		desc: "feed with attachments",
+		json: `{
+			"version": "https://jsonfeed.org/version/1.1",
+			"home_page_url": "http://example.com",
+			"items": [{
+				"id": "1",
+				"url": "/ep1",
+				"title": "Episode 1",
+				"image": "/ep1.jpg",
+				"attachments": [{
+					"url": "/ep1.m4a",
+					"mime_type": "audio/x-m4a",
+					"size_in_bytes": 89970236,
+					"duration_in_seconds": 6629
+				}, {
+					"url": "ftp://example.com/ep1.m4a",
+					"mime_type": "audio/x-m4a"
+				}]
+			}]
+		}`,
+		expected: []feed.RawItem{{
+			GUID:  "1",
+			URL:   "http://example.com/ep1",
+			Title: "Episode 1",
+			Attachments: []feed.Attachment{{
+				URL:       "http://example.com/ep1.m4a",
+				MimeType:  "audio/x-m4a",
+				Length:    89970236,
+				Duration:  6629,
+				Thumbnail: "http://example.com/ep1.jpg",
+			}},
+			Position: 0,
+		}},
+	}, {
+		desc: "feed with an item without URL",
+		json: `{
+			"version": "https://jsonfeed.org/version/1.1",
+			"items": [{
+				"id": "not-a-url",
+				"title": "Item 1",
+				"content_html": "Content 1"
+			}]
+		}`,
+		expected: []feed.RawItem{{
+			GUID:     "not-a-url",
+			Title:    "Item 1",
+			Content:  "Content 1",
+			Position: 0,
+		}},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res, err := fetch.ParseJSON([]byte(test.json), test.params)
+			if err != nil {
+				if test.err == "" {
+					t.Errorf("unexpected error: %v", err)
+				} else if err.Error() != test.err {
+					t.Errorf("expected error %q, got %q", test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatalf("expected error %q, got none", test.err)
+			}
+			items := res.Items
+			if len(items) != len(test.expected) {
+				t.Errorf("expected %d items, got %d", len(test.expected), len(items))
+				return
+			}
+			for i, item := range items {
+				if !reflect.DeepEqual(item, test.expected[i]) {
+					t.Errorf("expected item %#v, got %#v", test.expected[i], item)
+				}
+			}
+		})
+	}
+}
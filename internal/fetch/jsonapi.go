@@ -0,0 +1,208 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/alnvdl/varys/internal/feed"
+)
+
+// jsonAPIField maps values of a JSON API item onto a field of a feed item.
+// Either Path or Template must be set. Paths are relative to the item, and
+// all values selected by them are joined with ", ". Templates contain paths
+// in double braces (e.g., "{{name}} {{version}}") that are replaced by the
+// values they select.
+type jsonAPIField struct {
+	Path     string `json:"path"`
+	Template string `json:"template"`
+}
+
+// jsonAPIParams defines the parameters for parseJSONAPI.
+type jsonAPIParams struct {
+	ItemsPath string        `json:"items_path"`
+	BaseURL   string        `json:"base_url"`
+	ID        *jsonAPIField `json:"id"`
+	URL       *jsonAPIField `json:"url"`
+	Title     *jsonAPIField `json:"title"`
+	Authors   *jsonAPIField `json:"authors"`
+	Content   *jsonAPIField `json:"content"`
+	Date      *jsonAPIField `json:"date"`
+}
+
+// templatePlaceholder matches the paths in a jsonAPIField template.
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+func (p *jsonAPIParams) Validate() error {
+	if p.ItemsPath == "" {
+		return errors.New("items_path cannot be empty")
+	}
+	if _, err := parseJSONPath(p.ItemsPath); err != nil {
+		return fmt.Errorf("cannot parse items_path: %v", err)
+	}
+	if p.BaseURL != "" {
+		if _, err := url.Parse(p.BaseURL); err != nil {
+			return fmt.Errorf("cannot parse base_url: %v", err)
+		}
+	}
+	if p.URL == nil {
+		return errors.New("url cannot be empty")
+	}
+	if p.Title == nil {
+		return errors.New("title cannot be empty")
+	}
+	fields := []struct {
+		name  string
+		field *jsonAPIField
+	}{
+		{"id", p.ID},
+		{"url", p.URL},
+		{"title", p.Title},
+		{"authors", p.Authors},
+		{"content", p.Content},
+		{"date", p.Date},
+	}
+	for _, f := range fields {
+		if f.field == nil {
+			continue
+		}
+		if err := f.field.validate(); err != nil {
+			return fmt.Errorf("invalid %s: %v", f.name, err)
+		}
+	}
+	return nil
+}
+
+func (f *jsonAPIField) validate() error {
+	if (f.Path == "") == (f.Template == "") {
+		return errors.New("exactly one of path or template must be set")
+	}
+	if f.Path != "" {
+		if _, err := parseJSONPath(f.Path); err != nil {
+			return fmt.Errorf("cannot parse path: %v", err)
+		}
+		return nil
+	}
+	for _, m := range templatePlaceholder.FindAllStringSubmatch(f.Template, -1) {
+		if _, err := parseJSONPath(m[1]); err != nil {
+			return fmt.Errorf("cannot parse template: %v", err)
+		}
+	}
+	return nil
+}
+
+// value returns the value of the field for the given item. Values replacing
+// paths in templates are passed through escape along with the part of the
+// template preceding them. The field must have been validated. A nil field has
+// an empty value.
+func (f *jsonAPIField) value(item any, escape func(before, s string) string) string {
+	if f == nil {
+		return ""
+	}
+	if f.Path != "" {
+		return pathValue(f.Path, item)
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range templatePlaceholder.FindAllStringSubmatchIndex(f.Template, -1) {
+		b.WriteString(f.Template[last:m[0]])
+		b.WriteString(escape(f.Template[:m[0]], pathValue(f.Template[m[2]:m[3]], item)))
+		last = m[1]
+	}
+	b.WriteString(f.Template[last:])
+	return b.String()
+}
+
+// pathValue returns the values selected by a valid path in item, joined with
+// ", ".
+func pathValue(s string, item any) string {
+	path, _ := parseJSONPath(s)
+	var values []string
+	for _, v := range path.eval(item) {
+		if s := strings.TrimSpace(jsonString(v)); s != "" {
+			values = append(values, s)
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// parseJSONAPI parses the response of a JSON API and extracts feed items from
+// it based on the given params.
+func parseJSONAPI(data []byte, params any) (*parseResult, error) {
+	var p jsonAPIParams
+	if err := feed.ParseParams(params, &p); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON API feed params: %v", err)
+	}
+	// The ParseParams call should have validated the paths and base URL
+	// already.
+	itemsPath, _ := parseJSONPath(p.ItemsPath)
+	baseURL := absoluteURL(p.BaseURL)
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON: %v", err)
+	}
+
+	items := itemsPath.eval(doc)
+	if len(items) == 1 {
+		// A path selecting a single array selects its elements.
+		if elems, ok := items[0].([]any); ok {
+			items = elems
+		}
+	}
+
+	var feedItems []feed.RawItem
+	for pos, item := range items {
+		resolvedItemURL := resolveURL(p.URL.value(item, escapeURL), baseURL, nil)
+		feedItems = append(feedItems, feed.RawItem{
+			GUID:      strings.TrimSpace(p.ID.value(item, noEscape)),
+			URL:       urlToString(resolvedItemURL),
+			Title:     strings.TrimSpace(p.Title.value(item, noEscape)),
+			Authors:   strings.TrimSpace(p.Authors.value(item, noEscape)),
+			Content:   silentlySanitizeHTML(p.Content.value(item, escapeHTML), resolvedItemURL),
+			Published: parseJSONDate(p.Date.value(item, noEscape)),
+			Position:  pos,
+		})
+	}
+	return &parseResult{Items: feedItems}, nil
+}
+
+func noEscape(_, s string) string {
+	return s
+}
+
+func escapeHTML(_, s string) string {
+	return html.EscapeString(s)
+}
+
+// escapeURL escapes s for use in a URL after before: as a query component if
+// before has a query or fragment, or else as a path segment.
+func escapeURL(before, s string) string {
+	if strings.ContainsAny(before, "?#") {
+		return url.QueryEscape(s)
+	}
+	return url.PathEscape(s)
+}
+
+// parseJSONDate parses a date found in a JSON API item. Besides the formats
+// accepted by parseDate, Unix timestamps in seconds or milliseconds are
+// accepted.
+func parseJSONDate(s string) int64 {
+	if n, err := json.Number(s).Int64(); err == nil {
+		if n > 1e12 {
+			n /= 1000
+		}
+		if n < minDate.Unix() {
+			return 0
+		}
+		return n
+	}
+	return parseDate(s)
+}
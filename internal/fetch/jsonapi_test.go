@@ -0,0 +1,235 @@
+package fetch_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"github.com/alnvdl/varys/internal/fetch"
+)
+
+func TestParseJSONAPI(t *testing.T) {
+	date := func(s string) int64 {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t.Unix()
+	}
+
+	data := `{
+		"data": {
+			"items": [{
+				"id": 1,
+				"attributes": {
+					"name": "pkg one",
+					"version": "1.0.0",
+					"url": "/packages/one",
+					"title": "Package One",
+					"description": "<b>First</b> package",
+					"maintainers": [{"name": "Alice"}, {"name": "Bob"}],
+					"created_at": "2025-03-02T09:30:15Z",
+					"created_ts": 1740907815000
+				}
+			}, {
+				"id": 2,
+				"attributes": {
+					"name": "pkg/two",
+					"version": "2.0.0",
+					"url": "https://other.example.com/two",
+					"title": "Package Two",
+					"maintainers": [],
+					"created_ts": 1740907815
+				}
+			}]
+		}
+	}`
+
+	tests := []struct {
+		desc   string
+		json   string
+		params any
+
+		expected []feed.RawItem
+		err      string
+	}{{
+		desc:   "error: corrupted params",
+		params: "{",
+		err:    "cannot parse JSON API feed params: cannot unmarshal: json: cannot unmarshal string into Go value of type fetch.jsonAPIParams",
+	}, {
+		desc:   "error: missing items_path",
+		params: map[string]any{},
+		err:    "cannot parse JSON API feed params: cannot validate: items_path cannot be empty",
+	}, {
+		desc:   "error: invalid items_path",
+		params: map[string]any{"items_path": "data.items[x]"},
+		err:    `cannot parse JSON API feed params: cannot validate: cannot parse items_path: invalid index "x" in path "data.items[x]"`,
+	}, {
+		desc:   "error: unclosed bracket in items_path",
+		params: map[string]any{"items_path": "data.items["},
+		err:    `cannot parse JSON API feed params: cannot validate: cannot parse items_path: unclosed bracket in path "data.items["`,
+	}, {
+		desc:   "error: unclosed second bracket in items_path",
+		params: map[string]any{"items_path": "data.items[0]["},
+		err:    `cannot parse JSON API feed params: cannot validate: cannot parse items_path: unclosed bracket in path "data.items[0]["`,
+	}, {
+		desc:   "error: bracket without key in items_path",
+		params: map[string]any{"items_path": "["},
+		err:    `cannot parse JSON API feed params: cannot validate: cannot parse items_path: unclosed bracket in path "["`,
+	}, {
+		desc: "error: missing url",
+		params: map[string]any{
+			"items_path": "data.items[*]",
+			"title":      map[string]any{"path": "attributes.title"},
+		},
+		err: "cannot parse JSON API feed params: cannot validate: url cannot be empty",
+	}, {
+		desc: "error: missing title",
+		params: map[string]any{
+			"items_path": "data.items[*]",
+			"url":        map[string]any{"path": "attributes.url"},
+		},
+		err: "cannot parse JSON API feed params: cannot validate: title cannot be empty",
+	}, {
+		desc: "error: field with path and template",
+		params: map[string]any{
+			"items_path": "data.items[*]",
+			"url":        map[string]any{"path": "attributes.url"},
+			"title":      map[string]any{"path": "attributes.title", "template": "{{attributes.title}}"},
+		},
+		err: "cannot parse JSON API feed params: cannot validate: invalid title: exactly one of path or template must be set",
+	}, {
+		desc: "error: invalid path in template",
+		params: map[string]any{
+			"items_path": "data.items[*]",
+			"url":        map[string]any{"path": "attributes.url"},
+			"title":      map[string]any{"template": "{{attributes..title}}"},
+		},
+		err: `cannot parse JSON API feed params: cannot validate: invalid title: cannot parse template: empty key in path "attributes..title"`,
+	}, {
+		desc: "error: invalid JSON",
+		json: "{",
+		params: map[string]any{
+			"items_path": "data.items[*]",
+			"url":        map[string]any{"path": "attributes.url"},
+			"title":      map[string]any{"path": "attributes.title"},
+		},
+		err: "cannot parse JSON: unexpected EOF",
+	}, {
+		desc: "paths",
+		json: data,
+		params: map[string]any{
+			"items_path": "data.items[*]",
+			"base_url":   "https://example.com",
+			"id":         map[string]any{"path": "id"},
+			"url":        map[string]any{"path": "attributes.url"},
+			"title":      map[string]any{"path": "attributes.title"},
+			"authors":    map[string]any{"path": "attributes.maintainers[*].name"},
+			"content":    map[string]any{"path": "attributes.description"},
+			"date":       map[string]any{"path": "attributes.created_at"},
+		},
+		expected: []feed.RawItem{{
+			GUID:      "1",
+			URL:       "https://example.com/packages/one",
+			Title:     "Package One",
+			Authors:   "Alice, Bob",
+			Content:   "<b>First</b> package",
+			Published: date("2025-03-02T09:30:15Z"),
+			Position:  0,
+		}, {
+			GUID:     "2",
+			URL:      "https://other.example.com/two",
+			Title:    "Package Two",
+			Position: 1,
+		}},
+	}, {
+		desc: "templates",
+		json: data,
+		params: map[string]any{
+			"items_path": "data.items",
+			"url":        map[string]any{"template": "https://example.com/p/{{ attributes.name }}/{{attributes.version}}"},
+			"title":      map[string]any{"template": "{{attributes.name}} {{attributes.version}} (#{{id}})"},
+			"authors":    map[string]any{"path": "attributes.maintainers[0].name"},
+			"content":    map[string]any{"template": "<p>{{attributes.description}}</p>"},
+			"date":       map[string]any{"path": "attributes.created_ts"},
+		},
+		expected: []feed.RawItem{{
+			URL:       "https://example.com/p/pkg%20one/1.0.0",
+			Title:     "pkg one 1.0.0 (#1)",
+			Authors:   "Alice",
+			Content:   "<p>&lt;b&gt;First&lt;/b&gt; package</p>",
+			Published: date("2025-03-02T09:30:15Z"),
+			Position:  0,
+		}, {
+			URL:       "https://example.com/p/pkg%2Ftwo/2.0.0",
+			Title:     "pkg/two 2.0.0 (#2)",
+			Content:   "<p></p>",
+			Published: date("2025-03-02T09:30:15Z"),
+			Position:  1,
+		}},
+	}, {
+		desc: "url template with query",
+		json: data,
+		params: map[string]any{
+			"items_path": "data.items",
+			"url":        map[string]any{"template": "https://example.com/p/{{attributes.name}}?v={{attributes.version}}&q={{attributes.name}}#{{id}}"},
+			"title":      map[string]any{"path": "attributes.name"},
+		},
+		expected: []feed.RawItem{{
+			URL:      "https://example.com/p/pkg%20one?v=1.0.0&q=pkg+one#1",
+			Title:    "pkg one",
+			Position: 0,
+		}, {
+			URL:      "https://example.com/p/pkg%2Ftwo?v=2.0.0&q=pkg%2Ftwo#2",
+			Title:    "pkg/two",
+			Position: 1,
+		}},
+	}, {
+		desc: "top-level array",
+		json: `[{"link": "https://example.com/1", "name": "One"}]`,
+		params: map[string]any{
+			"items_path": "[*]",
+			"url":        map[string]any{"path": "link"},
+			"title":      map[string]any{"path": "name"},
+		},
+		expected: []feed.RawItem{{
+			URL:      "https://example.com/1",
+			Title:    "One",
+			Position: 0,
+		}},
+	}, {
+		desc: "items_path not matching the document",
+		json: data,
+		params: map[string]any{
+			"items_path": "data.entries[*]",
+			"url":        map[string]any{"path": "attributes.url"},
+			"title":      map[string]any{"path": "attributes.title"},
+		},
+		expected: []feed.RawItem{},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res, err := fetch.ParseJSONAPI([]byte(test.json), test.params)
+			if err != nil {
+				if test.err == "" {
+					t.Errorf("unexpected error: %v", err)
+				} else if err.Error() != test.err {
+					t.Errorf("expected error %q, got %q", test.err, err.Error())
+				}
+				return
+			}
+			if test.err != "" {
+				t.Fatalf("expected error %q, got none", test.err)
+			}
+			items := res.Items
+			if len(items) != len(test.expected) {
+				t.Errorf("expected %d items, got %d", len(test.expected), len(items))
+				return
+			}
+			for i, item := range items {
+				if !reflect.DeepEqual(item, test.expected[i]) {
+					t.Errorf("expected item %#v, got %#v", test.expected[i], item)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package fetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is a step in a jsonPath. It selects either a key of an object,
+// an index of an array, or all elements of an array (if wildcard is set).
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// jsonPath is a path selecting values in a JSON document, such as
+// "data.items[*].attributes.title". Keys are separated by dots, and array
+// elements are selected with [n] for a single index or [*] for all of them.
+type jsonPath []jsonPathStep
+
+// parseJSONPath parses a path in the syntax described in jsonPath. An empty
+// path selects the whole document.
+func parseJSONPath(s string) (jsonPath, error) {
+	var path jsonPath
+	if s == "" {
+		return path, nil
+	}
+	for part := range strings.SplitSeq(s, ".") {
+		key, rest, bracket := strings.Cut(part, "[")
+		if key == "" && (!bracket || len(path) > 0) {
+			return nil, fmt.Errorf("empty key in path %q", s)
+		}
+		if key != "" {
+			path = append(path, jsonPathStep{key: key})
+		}
+		for bracket {
+			var sel string
+			var ok bool
+			sel, rest, ok = strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("unclosed bracket in path %q", s)
+			}
+			if sel == "*" {
+				path = append(path, jsonPathStep{wildcard: true})
+			} else if n, err := strconv.Atoi(sel); err == nil && n >= 0 {
+				path = append(path, jsonPathStep{index: n, isIndex: true})
+			} else {
+				return nil, fmt.Errorf("invalid index %q in path %q", sel, s)
+			}
+			if rest == "" {
+				break
+			}
+			if !strings.HasPrefix(rest, "[") {
+				return nil, fmt.Errorf("unexpected %q after bracket in path %q", rest, s)
+			}
+			rest = rest[1:]
+		}
+	}
+	return path, nil
+}
+
+// eval returns the values selected by the path in v, which must have been
+// decoded by encoding/json. Steps that do not match the document select
+// nothing.
+func (p jsonPath) eval(v any) []any {
+	values := []any{v}
+	for _, step := range p {
+		var next []any
+		for _, v := range values {
+			switch v := v.(type) {
+			case map[string]any:
+				if elem, ok := v[step.key]; ok && !step.isIndex && !step.wildcard {
+					next = append(next, elem)
+				}
+			case []any:
+				if step.wildcard {
+					next = append(next, v...)
+				} else if step.isIndex && step.index < len(v) {
+					next = append(next, v[step.index])
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// jsonString returns the string representation of a scalar JSON value. Objects,
+// arrays and nulls are represented as an empty string.
+func jsonString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package fetch
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/alnvdl/varys/internal/feed"
+)
+
+// Enclosure is an RSS enclosure.
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// MediaContent is a Media RSS content element
+// (https://www.rssboard.org/media-rss).
+type MediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`
+	FileSize   string           `xml:"fileSize,attr"`
+	Duration   string           `xml:"duration,attr"`
+	Thumbnails []MediaThumbnail `xml:"thumbnail"`
+}
+
+// MediaThumbnail is a Media RSS thumbnail element.
+type MediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// MediaGroup is a Media RSS group element, holding different versions of the
+// same media (e.g., YouTube videos).
+type MediaGroup struct {
+	Contents   []MediaContent   `xml:"content"`
+	Thumbnails []MediaThumbnail `xml:"thumbnail"`
+}
+
+// Media holds the media attached to an RSS item or Atom entry.
+type Media struct {
+	Enclosures []Enclosure
+	Contents   []MediaContent
+	Groups     []MediaGroup
+	Thumbnails []MediaThumbnail
+	// Duration is the iTunes duration of the item, which applies to its
+	// enclosure.
+	Duration string
+}
+
+// attachments returns the attachments for the media, with URLs resolved
+// against baseURL and sanitized. Thumbnails of groups and items apply to all
+// their contents, and a thumbnail alone is attached as an image.
+func (m *Media) attachments(baseURL *url.URL) []feed.Attachment {
+	var atts []feed.Attachment
+	for _, e := range m.Enclosures {
+		atts = append(atts, feed.Attachment{
+			URL:      e.URL,
+			MimeType: e.Type,
+			Length:   parseSize(e.Length),
+			Duration: parseDuration(m.Duration),
+		})
+	}
+	groups := append([]MediaGroup{{Contents: m.Contents}}, m.Groups...)
+	for _, g := range groups {
+		for _, c := range g.Contents {
+			atts = append(atts, feed.Attachment{
+				URL:       c.URL,
+				MimeType:  c.Type,
+				Length:    parseSize(c.FileSize),
+				Duration:  parseDuration(c.Duration),
+				Thumbnail: firstThumbnail(c.Thumbnails, g.Thumbnails, m.Thumbnails),
+			})
+		}
+		if len(g.Contents) == 0 && len(g.Thumbnails) > 0 {
+			atts = append(atts, feed.Attachment{
+				URL:       g.Thumbnails[0].URL,
+				Thumbnail: g.Thumbnails[0].URL,
+			})
+		}
+	}
+	atts = sanitizeAttachments(atts, baseURL)
+	if thumbnail := firstThumbnail(m.Thumbnails); thumbnail != "" {
+		if len(atts) == 0 {
+			atts = sanitizeAttachments([]feed.Attachment{{URL: thumbnail, Thumbnail: thumbnail}}, baseURL)
+		}
+		for i := range atts {
+			if atts[i].Thumbnail == "" {
+				atts[i].Thumbnail = sanitizeAttachmentURL(thumbnail, baseURL, true)
+			}
+		}
+	}
+	return atts
+}
+
+func firstThumbnail(lists ...[]MediaThumbnail) string {
+	for _, thumbnails := range lists {
+		for _, t := range thumbnails {
+			if t.URL != "" {
+				return t.URL
+			}
+		}
+	}
+	return ""
+}
+
+// parseSize parses a size in bytes, returning 0 if it is invalid.
+func parseSize(s string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// parseDuration parses a duration in seconds, either as a number (e.g., "90"
+// or "90.5") or in the iTunes format (e.g., "1:30" or "01:01:30"). It returns
+// 0 if the duration is invalid.
+func parseDuration(s string) int64 {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0
+	}
+	var seconds float64
+	for _, part := range parts {
+		// Only plain numbers are accepted, not NaN, Inf or exponents.
+		if strings.Trim(part, "0123456789.") != "" {
+			return 0
+		}
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return int64(seconds)
+}
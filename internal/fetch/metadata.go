@@ -0,0 +1,106 @@
+package fetch
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"golang.org/x/net/html"
+)
+
+// maxDescriptionLength is the maximum length of feed descriptions, in runes.
+const maxDescriptionLength = 500
+
+// newMetadata returns the metadata of a feed with whitespace in the title and
+// description collapsed, and with the site and icon URLs resolved against
+// baseURL. The icon is the first of icons that is not empty.
+func newMetadata(title, description, siteURL, language string, baseURL *url.URL, icons ...string) *feed.Metadata {
+	description = collapseSpace(description)
+	if runes := []rune(description); len(runes) > maxDescriptionLength {
+		description = string(runes[:maxDescriptionLength]) + "…"
+	}
+	var iconURL string
+	for _, icon := range icons {
+		if iconURL = urlToString(resolveURL(strings.TrimSpace(icon), baseURL, nil)); iconURL != "" {
+			break
+		}
+	}
+	return &feed.Metadata{
+		Title:       collapseSpace(title),
+		Description: description,
+		SiteURL:     urlToString(resolveURL(strings.TrimSpace(siteURL), baseURL, nil)),
+		Language:    strings.TrimSpace(language),
+		IconURL:     iconURL,
+	}
+}
+
+// completeMetadata resolves relative URLs in m against docURL, the URL from
+// which the feed was fetched, and discards URLs that are not web URLs. If the
+// feed declares no icon, the favicon of the website (or else of the host of
+// the feed) is used.
+func completeMetadata(m *feed.Metadata, docURL *url.URL) {
+	if m == nil {
+		return
+	}
+	site := webURL(resolveURL(m.SiteURL, docURL, nil))
+	m.SiteURL = urlToString(site)
+	icon := webURL(resolveURL(m.IconURL, docURL, nil))
+	if icon == nil {
+		if site == nil {
+			site = webURL(docURL)
+		}
+		if site != nil {
+			icon = site.ResolveReference(&url.URL{Path: "/favicon.ico"})
+		}
+	}
+	m.IconURL = urlToString(icon)
+}
+
+// webURL returns u if it is an absolute HTTP or HTTPS URL, or nil otherwise.
+func webURL(u *url.URL) *url.URL {
+	if u == nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+	return u
+}
+
+// htmlMetadata returns the metadata of an HTML page: its title, description,
+// language and icon. Relative URLs are resolved against baseURL, which is
+// also used as the site URL.
+func htmlMetadata(doc *html.Node, baseURL *url.URL) *feed.Metadata {
+	var title, description, language, icon, touchIcon string
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		switch n.Data {
+		case "html":
+			language = attrValue(n, "lang")
+		case "title":
+			if title == "" && n.FirstChild != nil {
+				title = n.FirstChild.Data
+			}
+		case "meta":
+			name := strings.ToLower(coalesce(attrValue(n, "name"), attrValue(n, "property")))
+			if description == "" && (name == "description" || name == "og:description") {
+				description = attrValue(n, "content")
+			}
+		case "link":
+			rels := strings.Fields(strings.ToLower(attrValue(n, "rel")))
+			if icon == "" && slices.Contains(rels, "icon") {
+				icon = attrValue(n, "href")
+			}
+			if touchIcon == "" && slices.Contains(rels, "apple-touch-icon") {
+				touchIcon = attrValue(n, "href")
+			}
+		}
+	}
+	return newMetadata(title, description, urlToString(baseURL), language, baseURL, icon, touchIcon)
+}
+
+// collapseSpace trims s and replaces runs of whitespace in it with a single
+// space.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,296 @@
+package fetch_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"github.com/alnvdl/varys/internal/fetch"
+)
+
+func TestParseMetadata(t *testing.T) {
+	htmlParams := map[string]any{
+		"container_tag":    "div",
+		"base_url":         "https://example.com/news/",
+		"allowed_prefixes": []string{"https://example.com"},
+	}
+
+	tests := []struct {
+		desc             string
+		parser           func(data []byte, params any) (*feed.Metadata, error)
+		input            string
+		params           any
+		expectedMetadata *feed.Metadata
+	}{{
+		desc:   "RSS channel",
+		parser: parseXMLMetadata,
+		input: `<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+			<channel>
+				<title> Example
+					Blog </title>
+				<link>https://example.com/</link>
+				<description>A &lt;b&gt;blog&lt;/b&gt; about things.</description>
+				<language>en-us</language>
+				<image><url>/logo.png</url><title>Example</title></image>
+				<itunes:image href="https://example.com/cover.jpg"/>
+				<item><title>Item 1</title><link>https://example.com/item1</link></item>
+			</channel>
+		</rss>`,
+		expectedMetadata: &feed.Metadata{
+			Title:       "Example Blog",
+			Description: "A blog about things.",
+			SiteURL:     "https://example.com/",
+			Language:    "en-us",
+			IconURL:     "https://example.com/logo.png",
+		},
+	}, {
+		desc:   "RSS channel with iTunes image only",
+		parser: parseXMLMetadata,
+		input: `<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+			<channel>
+				<title>Podcast</title>
+				<itunes:image href="https://example.com/cover.jpg"/>
+				<item><title>Episode 1</title><link>https://example.com/ep1</link></item>
+			</channel>
+		</rss>`,
+		expectedMetadata: &feed.Metadata{
+			Title:   "Podcast",
+			IconURL: "https://example.com/cover.jpg",
+		},
+	}, {
+		desc:   "RSS channel with Atom links after its link",
+		parser: parseXMLMetadata,
+		input: `<rss xmlns:atom="http://www.w3.org/2005/Atom">
+			<channel>
+				<title>Example</title>
+				<link>https://example.com/</link>
+				<atom:link rel="self" href="https://example.com/feed.xml"/>
+				<item><title>Item 1</title><link>https://example.com/item1</link></item>
+			</channel>
+		</rss>`,
+		expectedMetadata: &feed.Metadata{
+			Title:   "Example",
+			SiteURL: "https://example.com/",
+		},
+	}, {
+		desc:   "RDF channel with image outside of the channel",
+		parser: parseXMLMetadata,
+		input: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+			<channel>
+				<title>RDF Feed</title>
+				<link>https://example.org/</link>
+				<description>News</description>
+				<dc:language>pt-br</dc:language>
+			</channel>
+			<image><url>https://example.org/image.gif</url></image>
+			<item><title>Item 1</title><link>https://example.org/item1</link></item>
+		</rdf:RDF>`,
+		expectedMetadata: &feed.Metadata{
+			Title:       "RDF Feed",
+			Description: "News",
+			SiteURL:     "https://example.org/",
+			Language:    "pt-br",
+			IconURL:     "https://example.org/image.gif",
+		},
+	}, {
+		desc:   "Atom feed",
+		parser: parseXMLMetadata,
+		input: `<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en">
+			<title type="html">Atom &lt;i&gt;Feed&lt;/i&gt;</title>
+			<subtitle>Updates from the project</subtitle>
+			<link rel="self" href="https://example.com/feed.atom"/>
+			<link rel="alternate" type="application/json" href="/feed.json"/>
+			<link rel="alternate" type="text/html" href="/"/>
+			<icon>/favicon.png</icon>
+			<logo>/logo.png</logo>
+			<entry><title>Entry 1</title><link href="https://example.com/entry1"/></entry>
+		</feed>`,
+		expectedMetadata: &feed.Metadata{
+			Title:       "Atom Feed",
+			Description: "Updates from the project",
+			SiteURL:     "https://example.com/",
+			Language:    "en",
+			IconURL:     "https://example.com/favicon.png",
+		},
+	}, {
+		desc:   "JSON feed",
+		parser: parseJSONMetadata,
+		input: `{
+			"version": "https://jsonfeed.org/version/1.1",
+			"title": "JSON Feed",
+			"description": "A JSON feed",
+			"home_page_url": "https://example.com/",
+			"icon": "/icon-512.png",
+			"favicon": "/favicon-64.png",
+			"language": "en",
+			"items": [{"id": "1", "url": "https://example.com/1", "title": "Item 1"}]
+		}`,
+		expectedMetadata: &feed.Metadata{
+			Title:       "JSON Feed",
+			Description: "A JSON feed",
+			SiteURL:     "https://example.com/",
+			Language:    "en",
+			IconURL:     "https://example.com/favicon-64.png",
+		},
+	}, {
+		desc:   "HTML page",
+		parser: parseHTMLMetadata,
+		params: htmlParams,
+		input: `<html lang="en">
+			<head>
+				<title>News</title>
+				<meta name="description" content="The latest news">
+				<link rel="apple-touch-icon" href="/touch.png">
+				<link rel="shortcut icon" href="favicon.ico">
+			</head>
+			<body><div><a href="https://example.com/news/1">News 1</a></div></body>
+		</html>`,
+		expectedMetadata: &feed.Metadata{
+			Title:       "News",
+			Description: "The latest news",
+			SiteURL:     "https://example.com/news/",
+			Language:    "en",
+			IconURL:     "https://example.com/news/favicon.ico",
+		},
+	}, {
+		desc:   "HTML page with Open Graph description and touch icon",
+		parser: parseHTMLMetadata,
+		params: htmlParams,
+		input: `<html>
+			<head>
+				<meta property="og:description" content="Latest">
+				<link rel="apple-touch-icon" href="/touch.png">
+			</head>
+			<body><div><a href="https://example.com/news/1">News 1</a></div></body>
+		</html>`,
+		expectedMetadata: &feed.Metadata{
+			Description: "Latest",
+			SiteURL:     "https://example.com/news/",
+			IconURL:     "https://example.com/touch.png",
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			metadata, err := test.parser([]byte(test.input), test.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(metadata, test.expectedMetadata) {
+				t.Errorf("expected metadata %#v, got %#v", test.expectedMetadata, metadata)
+			}
+		})
+	}
+}
+
+func parseXMLMetadata(data []byte, params any) (*feed.Metadata, error) {
+	res, err := fetch.ParseXML(data, params)
+	if err != nil {
+		return nil, err
+	}
+	return res.Metadata, nil
+}
+
+func parseJSONMetadata(data []byte, params any) (*feed.Metadata, error) {
+	res, err := fetch.ParseJSON(data, params)
+	if err != nil {
+		return nil, err
+	}
+	return res.Metadata, nil
+}
+
+func parseHTMLMetadata(data []byte, params any) (*feed.Metadata, error) {
+	res, err := fetch.ParseHTML(data, params)
+	if err != nil {
+		return nil, err
+	}
+	return res.Metadata, nil
+}
+
+func TestFetchMetadata(t *testing.T) {
+	tests := []struct {
+		desc             string
+		serverData       string
+		feedType         string
+		feedParams       any
+		expectedMetadata *feed.Metadata
+	}{{
+		desc: "relative URLs are resolved against the feed URL",
+		serverData: `<rss><channel>
+			<title>Feed</title>
+			<link>/blog</link>
+			<image><url>/logo.png</url></image>
+			<item><title>Item 1</title><link>http://example.com/item1</link></item>
+		</channel></rss>`,
+		feedType: "xml",
+		expectedMetadata: &feed.Metadata{
+			Title:   "Feed",
+			SiteURL: "{server}/blog",
+			IconURL: "{server}/logo.png",
+		},
+	}, {
+		desc: "favicon of the site is used if no icon is declared",
+		serverData: `<rss><channel>
+			<title>Feed</title>
+			<link>https://example.com/blog/</link>
+			<item><title>Item 1</title><link>http://example.com/item1</link></item>
+		</channel></rss>`,
+		feedType: "xml",
+		expectedMetadata: &feed.Metadata{
+			Title:   "Feed",
+			SiteURL: "https://example.com/blog/",
+			IconURL: "https://example.com/favicon.ico",
+		},
+	}, {
+		desc: "favicon of the feed host is used if the site is unknown",
+		serverData: `<rss><channel>
+			<title>Feed</title>
+			<image><url>javascript:alert(1)</url></image>
+			<item><title>Item 1</title><link>http://example.com/item1</link></item>
+		</channel></rss>`,
+		feedType: "xml",
+		expectedMetadata: &feed.Metadata{
+			Title:   "Feed",
+			IconURL: "{server}/favicon.ico",
+		},
+	}, {
+		desc:             "feeds without metadata",
+		serverData:       "image data",
+		feedType:         "img",
+		feedParams:       map[string]any{"title": "Image", "url": "http://example.com/image.png", "mime_type": "image/png"},
+		expectedMetadata: nil,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(test.serverData))
+			}))
+			defer server.Close()
+
+			res, err := fetch.Fetch(context.Background(), fetch.FetchParams{
+				URL:        server.URL + "/feed",
+				FeedName:   test.desc,
+				FeedType:   test.feedType,
+				FeedParams: test.feedParams,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expectedMetadata := test.expectedMetadata
+			if expectedMetadata != nil {
+				m := *expectedMetadata
+				m.SiteURL = strings.ReplaceAll(m.SiteURL, "{server}", server.URL)
+				m.IconURL = strings.ReplaceAll(m.IconURL, "{server}", server.URL)
+				expectedMetadata = &m
+			}
+			if !reflect.DeepEqual(res.Metadata, expectedMetadata) {
+				t.Errorf("expected metadata %#v, got %#v", expectedMetadata, res.Metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/alnvdl/varys/internal/feed"
+)
+
+// maxPagesLimit is the maximum value of the max_pages param.
+const maxPagesLimit = 10
+
+// pageParams defines the feed params for fetching more than one page of a
+// feed.
+type pageParams struct {
+	MaxPages int `json:"max_pages"`
+}
+
+func (p *pageParams) Validate() error {
+	if p.MaxPages < 0 {
+		return errors.New("max_pages cannot be negative")
+	}
+	if p.MaxPages > maxPagesLimit {
+		return fmt.Errorf("max_pages cannot be greater than %d", maxPagesLimit)
+	}
+	return nil
+}
+
+// fetchMorePages follows the next page links of the feed identified by p,
+// starting from the first page parsed into first from pageURL, until maxPages
+// pages are fetched, and returns the items of all pages. Pages are fetched one
+// at a time, waiting for each request as defined by p. Items already found in
+// previous pages are skipped. If a page cannot be fetched or parsed, the items
+// found so far are returned.
+func (f *Fetcher) fetchMorePages(ctx context.Context, cp ClientParams, parser parser, p *FetchParams, pageURL *url.URL, first *parseResult, maxPages int, log *slog.Logger) []feed.RawItem {
+	items := first.Items
+	seenItems := make(map[string]bool)
+	for _, item := range items {
+		seenItems[item.UID()] = true
+	}
+	seenPages := map[string]bool{pageURL.String(): true}
+
+	nextPage := first.NextPage
+	for n := 1; n < maxPages && nextPage != ""; n++ {
+		nextURL := resolveURL(nextPage, pageURL, nil)
+		if nextURL == nil || (nextURL.Scheme != "http" && nextURL.Scheme != "https") {
+			log.Info("invalid next page URL", slog.String("nextPage", nextPage))
+			break
+		}
+		if seenPages[nextURL.String()] {
+			break
+		}
+		seenPages[nextURL.String()] = true
+
+		if err := p.wait(ctx, nextURL.String()); err != nil {
+			log.Info("not fetching next page", slog.String("err", err.Error()))
+			break
+		}
+		log.Info("fetching next page", slog.String("pageURL", nextURL.String()))
+		page, err := f.get(ctx, cp, nextURL.String())
+		if err != nil {
+			log.Info("cannot fetch next page", slog.String("err", err.Error()))
+			break
+		}
+		parsed, err := parser(page.data, p.FeedParams)
+		if err != nil {
+			log.Info("cannot parse next page", slog.String("err", err.Error()))
+			break
+		}
+		for _, item := range parsed.Items {
+			if item.IsValid() {
+				if seenItems[item.UID()] {
+					continue
+				}
+				seenItems[item.UID()] = true
+			}
+			// Items in later pages are older, so they come after the items
+			// of previous pages.
+			item.Position = len(items)
+			items = append(items, item)
+		}
+		pageURL = page.url
+		nextPage = parsed.NextPage
+	}
+	return items
+}
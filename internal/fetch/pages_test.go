@@ -0,0 +1,199 @@
+package fetch_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alnvdl/varys/internal/feed"
+	"github.com/alnvdl/varys/internal/fetch"
+)
+
+func TestFetchPages(t *testing.T) {
+	rssPage := func(next string, items ...string) string {
+		var b strings.Builder
+		b.WriteString(`<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel>`)
+		if next != "" {
+			b.WriteString(`<atom:link rel="next" href="` + next + `"/>`)
+		}
+		for _, item := range items {
+			b.WriteString(`<item><title>` + item + `</title><link>https://example.com/` + item + `</link></item>`)
+		}
+		b.WriteString(`</channel></rss>`)
+		return b.String()
+	}
+	rssPages := map[string]string{
+		"/feed":        rssPage("/feed?page=2", "a", "b"),
+		"/feed?page=2": rssPage("/feed?page=3", "b", "c"),
+		"/feed?page=3": rssPage("/feed", "d"),
+	}
+
+	tests := []struct {
+		desc string
+		// pages maps paths with queries to the documents served in them.
+		// Paths not in the map return 404.
+		pages         map[string]string
+		feedType      string
+		feedParams    map[string]any
+		knownItems    []string
+		waitErr       error
+		expectedItems []string
+		expectedPages []string
+		expectedError string
+	}{{
+		desc:          "single page by default",
+		pages:         rssPages,
+		feedType:      feed.TypeXML,
+		feedParams:    map[string]any{},
+		expectedItems: []string{"a", "b"},
+		expectedPages: []string{"/feed"},
+	}, {
+		desc:          "pages up to max_pages",
+		pages:         rssPages,
+		feedType:      feed.TypeXML,
+		feedParams:    map[string]any{"max_pages": 2},
+		expectedItems: []string{"a", "b", "c"},
+		expectedPages: []string{"/feed", "/feed?page=2"},
+	}, {
+		desc:          "pages are not fetched again",
+		pages:         rssPages,
+		feedType:      feed.TypeXML,
+		feedParams:    map[string]any{"max_pages": 10},
+		expectedItems: []string{"a", "b", "c", "d"},
+		expectedPages: []string{"/feed", "/feed?page=2", "/feed?page=3"},
+	}, {
+		desc:          "only the first page is fetched for feeds with known items",
+		pages:         rssPages,
+		feedType:      feed.TypeXML,
+		feedParams:    map[string]any{"max_pages": 10},
+		knownItems:    []string{"a"},
+		expectedItems: []string{"a", "b"},
+		expectedPages: []string{"/feed"},
+	}, {
+		desc: "items of previous pages are kept if a page cannot be fetched",
+		pages: map[string]string{
+			"/feed":        rssPage("/feed?page=2", "a"),
+			"/feed?page=2": rssPage("/feed?page=3", "b"),
+		},
+		feedType:      feed.TypeXML,
+		feedParams:    map[string]any{"max_pages": 5},
+		expectedItems: []string{"a", "b"},
+		expectedPages: []string{"/feed", "/feed?page=2", "/feed?page=3"},
+	}, {
+		desc:          "pages are not fetched if waiting fails",
+		pages:         rssPages,
+		feedType:      feed.TypeXML,
+		feedParams:    map[string]any{"max_pages": 10},
+		waitErr:       context.Canceled,
+		expectedItems: []string{"a", "b"},
+		expectedPages: []string{"/feed"},
+	}, {
+		desc: "HTML pages",
+		pages: map[string]string{
+			"/feed":        `<div class="item"><a href="/a">a</a></div><a class="next" href="/feed?page=2">Next</a>`,
+			"/feed?page=2": `<div class="item"><a href="/b">b</a></div>`,
+		},
+		feedType: feed.TypeHTML,
+		feedParams: map[string]any{
+			"max_pages":          3,
+			"container_selector": ".item",
+			"next_page_selector": "a.next",
+			"base_url":           "{server}",
+			"allowed_prefixes":   []string{"{server}"},
+		},
+		expectedItems: []string{"a", "b"},
+		expectedPages: []string{"/feed", "/feed?page=2"},
+	}, {
+		desc:          "negative max_pages",
+		pages:         rssPages,
+		feedType:      feed.TypeXML,
+		feedParams:    map[string]any{"max_pages": -1},
+		expectedError: "max_pages cannot be negative",
+	}, {
+		desc:          "max_pages too large",
+		pages:         rssPages,
+		feedType:      feed.TypeXML,
+		feedParams:    map[string]any{"max_pages": 11},
+		expectedError: "max_pages cannot be greater than 10",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var pages []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pages = append(pages, r.URL.RequestURI())
+				data, ok := test.pages[r.URL.RequestURI()]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(data))
+			}))
+			defer server.Close()
+
+			params := make(map[string]any)
+			for k, v := range test.feedParams {
+				switch v := v.(type) {
+				case string:
+					params[k] = strings.ReplaceAll(v, "{server}", server.URL)
+				case []string:
+					params[k] = []string{strings.ReplaceAll(v[0], "{server}", server.URL)}
+				default:
+					params[k] = v
+				}
+			}
+			knownItems := make(map[string]bool)
+			for _, item := range test.knownItems {
+				knownItems[feed.UID("https://example.com/"+item)] = true
+			}
+
+			var waits []string
+			res, err := fetch.Fetch(context.Background(), fetch.FetchParams{
+				URL:        server.URL + "/feed",
+				FeedName:   "Feed",
+				FeedType:   test.feedType,
+				FeedParams: params,
+				KnownItems: knownItems,
+				Wait: func(ctx context.Context, rawURL string) error {
+					waits = append(waits, strings.TrimPrefix(rawURL, server.URL))
+					return test.waitErr
+				},
+			})
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(pages, test.expectedPages) {
+				t.Errorf("expected pages %v, got %v", test.expectedPages, pages)
+			}
+			// Every page but the first one is waited for.
+			expectedWaits := test.expectedPages[1:]
+			if test.waitErr != nil {
+				expectedWaits = []string{"/feed?page=2"}
+			}
+			if !slices.Equal(waits, expectedWaits) {
+				t.Errorf("expected waits %v, got %v", expectedWaits, waits)
+			}
+			var items []string
+			for i, item := range res.Items {
+				items = append(items, item.Title)
+				if item.Position != i {
+					t.Errorf("expected item %s in position %d, got %d", item.Title, i, item.Position)
+				}
+			}
+			if !reflect.DeepEqual(items, test.expectedItems) {
+				t.Errorf("expected items %v, got %v", test.expectedItems, items)
+			}
+		})
+	}
+}
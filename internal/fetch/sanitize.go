@@ -2,10 +2,13 @@ package fetch
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 
+	"github.com/alnvdl/varys/internal/feed"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
@@ -174,3 +177,67 @@ func sanitizeNode(node, newParent *html.Node, allowedTags map[string]bool, allow
 		}
 	}
 }
+
+// maxAttachments is the maximum number of attachments kept per item.
+const maxAttachments = 16
+
+// mimeTypePattern matches MIME types without parameters, as allowed by RFC
+// 6838.
+var mimeTypePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9!#$&^_.+-]*/[a-z0-9][a-z0-9!#$&^_.+-]*$`)
+
+// sanitizeAttachments returns the given attachments with their URLs resolved
+// against baseURL. Attachments must have http or https URLs, and thumbnails
+// may also be data: URLs of safe image types. Invalid attachments are
+// dropped, invalid thumbnails and MIME types are cleared, and attachments with
+// the same URL are merged.
+func sanitizeAttachments(atts []feed.Attachment, baseURL *url.URL) []feed.Attachment {
+	var sanitized []feed.Attachment
+	seen := make(map[string]int)
+	for _, a := range atts {
+		a.URL = sanitizeAttachmentURL(a.URL, baseURL, false)
+		if a.URL == "" {
+			continue
+		}
+		a.Thumbnail = sanitizeAttachmentURL(a.Thumbnail, baseURL, true)
+		a.MimeType, _, _ = strings.Cut(strings.ToLower(a.MimeType), ";")
+		a.MimeType = strings.TrimSpace(a.MimeType)
+		if !mimeTypePattern.MatchString(a.MimeType) {
+			a.MimeType = ""
+		}
+		a.Length = max(a.Length, 0)
+		a.Duration = max(a.Duration, 0)
+
+		if i, ok := seen[a.URL]; ok {
+			prev := &sanitized[i]
+			prev.MimeType = cmp.Or(prev.MimeType, a.MimeType)
+			prev.Length = cmp.Or(prev.Length, a.Length)
+			prev.Duration = cmp.Or(prev.Duration, a.Duration)
+			prev.Thumbnail = cmp.Or(prev.Thumbnail, a.Thumbnail)
+			continue
+		}
+		if len(sanitized) == maxAttachments {
+			continue
+		}
+		seen[a.URL] = len(sanitized)
+		sanitized = append(sanitized, a)
+	}
+	return sanitized
+}
+
+// sanitizeAttachmentURL resolves the attachment URL u against baseURL and
+// returns it if it is an http or https URL, or a safe data: URL if image is
+// set. Otherwise, it returns an empty string.
+func sanitizeAttachmentURL(u string, baseURL *url.URL, image bool) string {
+	u = strings.TrimSpace(u)
+	if image && isSafeDataURL(u) {
+		return u
+	}
+	resolved := resolveURL(u, baseURL, nil)
+	if resolved == nil || !resolved.IsAbs() || resolved.Host == "" {
+		return ""
+	}
+	if scheme := strings.ToLower(resolved.Scheme); scheme != "http" && scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
@@ -0,0 +1,463 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Combinators between the compound selectors of a complexSelector.
+const (
+	descendantCombinator = ' '
+	childCombinator      = '>'
+)
+
+// attrSelector matches elements by one of their attributes. op is one of the
+// CSS attribute operators ("=", "~=", "|=", "^=", "$=" or "*="), or empty to
+// match elements that have the attribute regardless of its value.
+type attrSelector struct {
+	key string
+	op  string
+	val string
+}
+
+// nthSelector matches elements that are the (a*n+b)-th child of their parent
+// for some n >= 0, counting only elements. If fromEnd is set, children are
+// counted from the last one.
+type nthSelector struct {
+	a, b    int
+	fromEnd bool
+}
+
+// compoundSelector matches elements satisfying all of its conditions, such
+// as "article.card[data-id]". combinator is the relationship between the
+// element and the one matched by the previous compoundSelector in a
+// complexSelector.
+type compoundSelector struct {
+	combinator byte
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrSelector
+	nths       []nthSelector
+}
+
+// complexSelector is a sequence of compound selectors joined by combinators,
+// such as "#main > article a".
+type complexSelector []compoundSelector
+
+// selector is a CSS selector matching elements of HTML documents, such as
+// "#main article.card, div[data-type^='news'] > a:nth-child(2n+1)". It
+// supports a subset of CSS:
+//   - type (e.g., "div") and universal ("*") selectors;
+//   - class (".card") and ID ("#main") selectors;
+//   - attribute selectors ("[attr]", "[attr=val]", "[attr~=val]",
+//     "[attr|=val]", "[attr^=val]", "[attr$=val]" and "[attr*=val]");
+//   - the ":first-child", ":last-child", ":nth-child()" and
+//     ":nth-last-child()" pseudo-classes;
+//   - descendant (" ") and child (">") combinators;
+//   - lists of selectors separated by commas, matching elements matched by
+//     any of them.
+type selector []complexSelector
+
+// selectorParser parses selectors in the syntax described in selector.
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+// parseSelector parses a selector in the syntax described in selector.
+func parseSelector(s string) (selector, error) {
+	p := &selectorParser{s: s}
+	var sel selector
+	for {
+		p.skipSpace()
+		c, err := p.parseComplex()
+		if err != nil {
+			return nil, fmt.Errorf("%v in selector %q", err, s)
+		}
+		sel = append(sel, c)
+		if p.eof() {
+			return sel, nil
+		}
+		// parseComplex only stops before commas.
+		p.pos++
+	}
+}
+
+func (p *selectorParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *selectorParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *selectorParser) errorf(format string, args ...any) error {
+	if p.eof() {
+		return fmt.Errorf(format+" at the end", args...)
+	}
+	return fmt.Errorf(format+" at position %d", append(args, p.pos)...)
+}
+
+// skipSpace skips whitespace, returning true if there was any.
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for !p.eof() && strings.IndexByte(" \t\n\r\f", p.peek()) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// parseComplex parses a complex selector, stopping at the end of the input or
+// before a comma.
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	var c complexSelector
+	var combinator byte
+	for {
+		compound, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		compound.combinator = combinator
+		c = append(c, compound)
+
+		hadSpace := p.skipSpace()
+		switch {
+		case p.eof() || p.peek() == ',':
+			return c, nil
+		case p.peek() == childCombinator:
+			combinator = childCombinator
+			p.pos++
+			p.skipSpace()
+		case hadSpace:
+			combinator = descendantCombinator
+		default:
+			return nil, p.errorf("unexpected %q", p.peek())
+		}
+	}
+}
+
+// parseCompound parses a compound selector.
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var c compoundSelector
+	start := p.pos
+	if p.peek() == '*' {
+		p.pos++
+	} else {
+		c.tag = strings.ToLower(p.ident())
+	}
+	for !p.eof() {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			if c.id = p.ident(); c.id == "" {
+				return c, p.errorf("expected ID")
+			}
+		case '.':
+			p.pos++
+			class := p.ident()
+			if class == "" {
+				return c, p.errorf("expected class")
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			p.pos++
+			attr, err := p.parseAttr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, attr)
+		case ':':
+			p.pos++
+			nth, err := p.parsePseudoClass()
+			if err != nil {
+				return c, err
+			}
+			c.nths = append(c.nths, nth)
+		default:
+			if p.pos == start {
+				return c, p.errorf("expected selector")
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return c, p.errorf("expected selector")
+	}
+	return c, nil
+}
+
+// parseAttr parses an attribute selector after its opening bracket.
+func (p *selectorParser) parseAttr() (attrSelector, error) {
+	var attr attrSelector
+	p.skipSpace()
+	if attr.key = strings.ToLower(p.ident()); attr.key == "" {
+		return attr, p.errorf("expected attribute name")
+	}
+	p.skipSpace()
+	if p.peek() == ']' {
+		p.pos++
+		return attr, nil
+	}
+	for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.s[p.pos:], op) {
+			attr.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if attr.op == "" {
+		return attr, p.errorf("expected attribute operator")
+	}
+	p.skipSpace()
+	if q := p.peek(); q == '"' || q == '\'' {
+		val, err := p.quoted()
+		if err != nil {
+			return attr, err
+		}
+		attr.val = val
+	} else if attr.val = p.ident(); attr.val == "" {
+		return attr, p.errorf("expected attribute value")
+	}
+	p.skipSpace()
+	if p.peek() != ']' {
+		return attr, p.errorf("expected ]")
+	}
+	p.pos++
+	return attr, nil
+}
+
+// parsePseudoClass parses a pseudo-class after its colon.
+func (p *selectorParser) parsePseudoClass() (nthSelector, error) {
+	start := p.pos
+	name := strings.ToLower(p.ident())
+	switch name {
+	case "first-child":
+		return nthSelector{b: 1}, nil
+	case "last-child":
+		return nthSelector{b: 1, fromEnd: true}, nil
+	case "nth-child", "nth-last-child":
+		if p.peek() != '(' {
+			return nthSelector{}, p.errorf("expected (")
+		}
+		p.pos++
+		end := strings.IndexByte(p.s[p.pos:], ')')
+		if end < 0 {
+			return nthSelector{}, p.errorf("expected )")
+		}
+		nth, err := parseNth(p.s[p.pos : p.pos+end])
+		if err != nil {
+			return nth, p.errorf("%v", err)
+		}
+		nth.fromEnd = name == "nth-last-child"
+		p.pos += end + 1
+		return nth, nil
+	}
+	p.pos = start
+	return nthSelector{}, p.errorf("unsupported pseudo-class %q", name)
+}
+
+// parseNth parses the argument of :nth-child(), which is either "odd",
+// "even", or a formula like "2n+1", "-n+3" or "4".
+func parseNth(s string) (nthSelector, error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), ""))
+	switch s {
+	case "odd":
+		return nthSelector{a: 2, b: 1}, nil
+	case "even":
+		return nthSelector{a: 2, b: 0}, nil
+	}
+	var nth nthSelector
+	a, b, hasN := strings.Cut(s, "n")
+	if !hasN {
+		if s == "" {
+			return nth, errors.New("empty nth-child argument")
+		}
+		a, b = "0", s
+	}
+	switch a {
+	case "", "+":
+		nth.a = 1
+	case "-":
+		nth.a = -1
+	default:
+		n, err := strconv.Atoi(a)
+		if err != nil {
+			return nth, fmt.Errorf("invalid nth-child argument %q", s)
+		}
+		nth.a = n
+	}
+	if b != "" {
+		if hasN && b[0] != '+' && b[0] != '-' {
+			return nth, fmt.Errorf("invalid nth-child argument %q", s)
+		}
+		n, err := strconv.Atoi(b)
+		if err != nil {
+			return nth, fmt.Errorf("invalid nth-child argument %q", s)
+		}
+		nth.b = n
+	}
+	return nth, nil
+}
+
+// ident reads an identifier (e.g., a tag or class name), returning it with
+// escapes resolved. An empty string is returned if there is none.
+func (p *selectorParser) ident() string {
+	var b strings.Builder
+	for !p.eof() {
+		r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+		switch {
+		case r == '\\' && p.pos+size < len(p.s):
+			// Escaped characters are taken literally (e.g., "md\:flex").
+			p.pos += size
+			r, size = utf8.DecodeRuneInString(p.s[p.pos:])
+		case r == '-' || r == '_' || r >= utf8.RuneSelf ||
+			('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9'):
+		default:
+			return b.String()
+		}
+		b.WriteRune(r)
+		p.pos += size
+	}
+	return b.String()
+}
+
+// quoted reads a string in single or double quotes, returning it with escapes
+// resolved.
+func (p *selectorParser) quoted() (string, error) {
+	q := p.peek()
+	p.pos++
+	var b strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		p.pos++
+		switch {
+		case c == q:
+			return b.String(), nil
+		case c == '\\' && !p.eof():
+			c = p.peek()
+			p.pos++
+		}
+		b.WriteByte(c)
+	}
+	return "", errors.New("unclosed string")
+}
+
+// match returns true if the element n is matched by the selector.
+func (s selector) match(n *html.Node) bool {
+	return slices.ContainsFunc(s, func(c complexSelector) bool {
+		return c.matchAt(n, len(c)-1)
+	})
+}
+
+// matchAt returns true if n is matched by the i-th compound selector of c and
+// its ancestors are matched by the previous ones as required by the
+// combinators.
+func (c complexSelector) matchAt(n *html.Node, i int) bool {
+	if !c[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch c[i].combinator {
+	case childCombinator:
+		return n.Parent != nil && c.matchAt(n.Parent, i-1)
+	default:
+		for a := n.Parent; a != nil; a = a.Parent {
+			if c.matchAt(a, i-1) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// match returns true if n is an element satisfying all conditions of c.
+func (c *compoundSelector) match(n *html.Node) bool {
+	if n.Type != html.ElementNode || (c.tag != "" && n.Data != c.tag) {
+		return false
+	}
+	if c.id != "" && attrValue(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(attrValue(n, "class"))
+		for _, class := range c.classes {
+			if !slices.Contains(classes, class) {
+				return false
+			}
+		}
+	}
+	for _, attr := range c.attrs {
+		if !attr.match(n) {
+			return false
+		}
+	}
+	for _, nth := range c.nths {
+		if !nth.match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns true if n has an attribute satisfying a.
+func (a *attrSelector) match(n *html.Node) bool {
+	i := slices.IndexFunc(n.Attr, func(attr html.Attribute) bool {
+		return attr.Namespace == "" && attr.Key == a.key
+	})
+	if i < 0 {
+		return false
+	}
+	v := n.Attr[i].Val
+	switch a.op {
+	case "=":
+		return v == a.val
+	case "~=":
+		return slices.Contains(strings.Fields(v), a.val)
+	case "|=":
+		return v == a.val || strings.HasPrefix(v, a.val+"-")
+	case "^=":
+		return a.val != "" && strings.HasPrefix(v, a.val)
+	case "$=":
+		return a.val != "" && strings.HasSuffix(v, a.val)
+	case "*=":
+		return a.val != "" && strings.Contains(v, a.val)
+	}
+	return true
+}
+
+// match returns true if n is in a position satisfying nth among the element
+// children of its parent.
+func (nth *nthSelector) match(n *html.Node) bool {
+	if n.Parent == nil {
+		return false
+	}
+	pos := 1
+	sibling := func(s *html.Node) *html.Node {
+		if nth.fromEnd {
+			return s.NextSibling
+		}
+		return s.PrevSibling
+	}
+	for s := sibling(n); s != nil; s = sibling(s) {
+		if s.Type == html.ElementNode {
+			pos++
+		}
+	}
+	if nth.a == 0 {
+		return pos == nth.b
+	}
+	d := pos - nth.b
+	return d%nth.a == 0 && d/nth.a >= 0
+}
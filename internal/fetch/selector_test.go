@@ -0,0 +1,231 @@
+package fetch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestSelector(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body>
+		<div id="main">
+			<article id="a1" class="card news featured" data-id="1" lang="en-US">
+				<a id="l1" href="/news/1" rel="bookmark external">News 1</a>
+			</article>
+			<article id="a2" class="card" data-id="2" lang="en">
+				<p id="p1"><a id="l2" href="https://example.com/news/2.html">News 2</a></p>
+			</article>
+			<article id="a3" class="news" lang="pt-BR">
+				<a id="l3" href="/other/3">Other 3</a>
+			</article>
+			<article id="a4" class="md:flex"></article>
+		</div>
+		<aside id="side">
+			<article id="a5" class="card news"><a id="l4" href="/news/4">News 4</a></article>
+		</aside>
+	</body></html>`))
+	if err != nil {
+		t.Fatalf("cannot parse document: %v", err)
+	}
+
+	tests := []struct {
+		desc     string
+		selector string
+		expected []string
+	}{{
+		desc:     "type",
+		selector: "article",
+		expected: []string{"a1", "a2", "a3", "a4", "a5"},
+	}, {
+		desc:     "type is case-insensitive",
+		selector: "ARTICLE",
+		expected: []string{"a1", "a2", "a3", "a4", "a5"},
+	}, {
+		desc:     "universal",
+		selector: "#main > *",
+		expected: []string{"a1", "a2", "a3", "a4"},
+	}, {
+		desc:     "one of many classes",
+		selector: ".featured",
+		expected: []string{"a1"},
+	}, {
+		desc:     "multiple classes",
+		selector: "article.card.news",
+		expected: []string{"a1", "a5"},
+	}, {
+		desc:     "escaped class",
+		selector: `.md\:flex`,
+		expected: []string{"a4"},
+	}, {
+		desc:     "ID",
+		selector: "#l2",
+		expected: []string{"l2"},
+	}, {
+		desc:     "descendant combinator",
+		selector: "#main article a",
+		expected: []string{"l1", "l2", "l3"},
+	}, {
+		desc:     "child combinator",
+		selector: "#main>article>a",
+		expected: []string{"l1", "l3"},
+	}, {
+		desc:     "mixed combinators",
+		selector: "div > article p a",
+		expected: []string{"l2"},
+	}, {
+		desc:     "selector list",
+		selector: "#a3, aside .card",
+		expected: []string{"a3", "a5"},
+	}, {
+		desc:     "attribute presence",
+		selector: "[data-id]",
+		expected: []string{"a1", "a2"},
+	}, {
+		desc:     "attribute equals",
+		selector: `article[data-id="2"]`,
+		expected: []string{"a2"},
+	}, {
+		desc:     "attribute contains word",
+		selector: "a[rel~=external]",
+		expected: []string{"l1"},
+	}, {
+		desc:     "attribute language prefix",
+		selector: "[lang|=en]",
+		expected: []string{"a1", "a2"},
+	}, {
+		desc:     "attribute starts with",
+		selector: "a[href^='/news/']",
+		expected: []string{"l1", "l4"},
+	}, {
+		desc:     "attribute ends with",
+		selector: "a[ href $= '.html' ]",
+		expected: []string{"l2"},
+	}, {
+		desc:     "attribute contains",
+		selector: "a[href*=news]",
+		expected: []string{"l1", "l2", "l4"},
+	}, {
+		desc:     "attribute operators with empty values match nothing",
+		selector: "a[href^=''], a[href$=''], a[href*='']",
+		expected: nil,
+	}, {
+		desc:     "first child",
+		selector: "#main > article:first-child",
+		expected: []string{"a1"},
+	}, {
+		desc:     "last child",
+		selector: "#main > :last-child",
+		expected: []string{"a4"},
+	}, {
+		desc:     "nth child",
+		selector: "#main > article:nth-child(2)",
+		expected: []string{"a2"},
+	}, {
+		desc:     "nth child odd",
+		selector: "#main > article:nth-child(odd)",
+		expected: []string{"a1", "a3"},
+	}, {
+		desc:     "nth child even",
+		selector: "#main > article:nth-child(even)",
+		expected: []string{"a2", "a4"},
+	}, {
+		desc:     "nth child formula",
+		selector: "#main > article:nth-child(3n + 2)",
+		expected: []string{"a2"},
+	}, {
+		desc:     "nth child negative formula",
+		selector: "#main > article:nth-child(-n+2)",
+		expected: []string{"a1", "a2"},
+	}, {
+		desc:     "nth last child",
+		selector: "#main > article:nth-last-child(2)",
+		expected: []string{"a3"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			sel, err := parseSelector(test.selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var matched []string
+			for n := range doc.Descendants() {
+				if sel.match(n) {
+					matched = append(matched, attrValue(n, "id"))
+				}
+			}
+			if !reflect.DeepEqual(matched, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, matched)
+			}
+		})
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	tests := []struct {
+		selector string
+		err      string
+	}{{
+		selector: "",
+		err:      `expected selector at the end in selector ""`,
+	}, {
+		selector: "div,",
+		err:      `expected selector at the end in selector "div,"`,
+	}, {
+		selector: "div >",
+		err:      `expected selector at the end in selector "div >"`,
+	}, {
+		selector: "div+p",
+		err:      `unexpected '+' at position 3 in selector "div+p"`,
+	}, {
+		selector: "div.",
+		err:      `expected class at the end in selector "div."`,
+	}, {
+		selector: "#",
+		err:      `expected ID at the end in selector "#"`,
+	}, {
+		selector: "[=a]",
+		err:      `expected attribute name at position 1 in selector "[=a]"`,
+	}, {
+		selector: "[a!=b]",
+		err:      `expected attribute operator at position 2 in selector "[a!=b]"`,
+	}, {
+		selector: "[a=]",
+		err:      `expected attribute value at position 3 in selector "[a=]"`,
+	}, {
+		selector: "[a='b]",
+		err:      `unclosed string in selector "[a='b]"`,
+	}, {
+		selector: "[a=b",
+		err:      `expected ] at the end in selector "[a=b"`,
+	}, {
+		selector: "a:hover",
+		err:      `unsupported pseudo-class "hover" at position 2 in selector "a:hover"`,
+	}, {
+		selector: "a:nth-child",
+		err:      `expected ( at the end in selector "a:nth-child"`,
+	}, {
+		selector: "a:nth-child(2",
+		err:      `expected ) at position 12 in selector "a:nth-child(2"`,
+	}, {
+		selector: "a:nth-child(x)",
+		err:      `invalid nth-child argument "x" at position 12 in selector "a:nth-child(x)"`,
+	}, {
+		selector: "a:nth-child(2n1)",
+		err:      `invalid nth-child argument "2n1" at position 12 in selector "a:nth-child(2n1)"`,
+	}, {
+		selector: "a:nth-child()",
+		err:      `empty nth-child argument at position 12 in selector "a:nth-child()"`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			_, err := parseSelector(test.selector)
+			if err == nil || err.Error() != test.err {
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
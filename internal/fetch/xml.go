@@ -2,57 +2,118 @@ package fetch
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alnvdl/varys/internal/feed"
 	"golang.org/x/net/html/charset"
 )
 
+// RSS is an RSS 2.0 document, or an RSS 1.0 (RDF) or 0.90 document, in which
+// items are siblings of the channel under the rdf:RDF root element.
 type RSS struct {
+	XMLName xml.Name
 	Channel struct {
 		Items []RSSItem `xml:"item"`
-		Link  string    `xml:"link"`
+		// AtomLinks must precede Link, otherwise the atom:link elements
+		// would be decoded into Link.
+		AtomLinks   []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Link        string     `xml:"link"`
+		Title       string     `xml:"title"`
+		Description string     `xml:"description"`
+		Language    string     `xml:"language"`
+		Images      []RSSImage `xml:"image"`
+		TTL         string     `xml:"ttl"`
+		SkipHours   []string   `xml:"skipHours>hour"`
+		SkipDays    []string   `xml:"skipDays>day"`
+		Syndication
 	} `xml:"channel"`
-	Items []RSSItem `xml:"item"`
+	// Items and Images are the items and images outside of the channel, as
+	// used in RDF.
+	Items  []RSSItem  `xml:"item"`
+	Images []RSSImage `xml:"image"`
+}
+
+// RSSImage is the image of an RSS channel, or an iTunes image, which has the
+// URL in an attribute instead.
+type RSSImage struct {
+	URL  string `xml:"url"`
+	Href string `xml:"href,attr"`
+}
+
+// metadata returns the metadata declared in the channel.
+func (r *RSS) metadata() *feed.Metadata {
+	var icons []string
+	for _, image := range append(r.Channel.Images, r.Images...) {
+		icons = append(icons, image.URL, image.Href)
+	}
+	link := strings.TrimSpace(r.Channel.Link)
+	return newMetadata(r.Channel.Title, htmlToText(r.Channel.Description), link, r.Channel.Language, absoluteURL(link), icons...)
+}
+
+// isRDF returns true if the document is an RSS 1.0 (RDF) or 0.90 document.
+func (r *RSS) isRDF() bool {
+	return r.XMLName.Local == "RDF"
+}
+
+// Syndication holds the elements of the RSS syndication module
+// (http://purl.org/rss/1.0/modules/syndication/).
+type Syndication struct {
+	UpdatePeriod    string `xml:"updatePeriod"`
+	UpdateFrequency string `xml:"updateFrequency"`
 }
 
 type RSSItem struct {
-	ID           string   `xml:"id"`
-	GUID         string   `xml:"guid"`
-	Link         string   `xml:"link"`
-	Title        string   `xml:"title"`
-	PubDate      string   `xml:"pubDate"`
-	Date         string   `xml:"date"`
-	Creator      []string `xml:"creator"`
-	Authors      []string `xml:"author>name"`
-	Encoded      string   `xml:"encoded"`
-	Descriptions []string `xml:"description"`
-}
-
-type Atom struct {
-	Entries []AtomEntry `xml:"entry"`
-	Link    struct {
-		Href string `xml:"href,attr"`
-	} `xml:"link"`
-}
-
-type AtomEntry struct {
-	ID    string `xml:"id"`
-	GUID  string `xml:"guid"`
-	Links []struct {
-		Href string `xml:"href,attr"`
-		Rel  string `xml:"rel,attr"`
-	} `xml:"link"`
-	Title     string   `xml:"title"`
-	Published string   `xml:"published"`
-	Updated   string   `xml:"updated"`
-	Authors   []string `xml:"author>name"`
-	Content   string   `xml:"content"`
-	Summary   string   `xml:"summary"`
+	// About is the URI of the item in RDF, usually the same as its link.
+	About        string      `xml:"about,attr"`
+	ID           string      `xml:"id"`
+	GUID         string      `xml:"guid"`
+	Link         string      `xml:"link"`
+	Title        string      `xml:"title"`
+	PubDate      string      `xml:"pubDate"`
+	Date         string      `xml:"date"`
+	Creator      []DCCreator `xml:"creator"`
+	Authors      []string    `xml:"author>name"`
+	Encoded      string      `xml:"encoded"`
+	Descriptions []string    `xml:"description"`
+
+	Enclosures      []Enclosure      `xml:"enclosure"`
+	MediaContents   []MediaContent   `xml:"content"`
+	MediaGroups     []MediaGroup     `xml:"group"`
+	MediaThumbnails []MediaThumbnail `xml:"thumbnail"`
+	Duration        string           `xml:"duration"`
+}
+
+// DCCreator is a Dublin Core creator. In RDF, it may hold a list of creators
+// as an rdf:Seq or rdf:Bag.
+type DCCreator struct {
+	Name string   `xml:",chardata"`
+	Seq  []string `xml:"Seq>li"`
+	Bag  []string `xml:"Bag>li"`
+}
+
+// authors returns the authors of the item, including all Dublin Core
+// creators.
+func (i *RSSItem) authors() string {
+	var authors []string
+	for _, name := range i.Authors {
+		authors = append(authors, strings.TrimSpace(name))
+	}
+	for _, c := range i.Creator {
+		authors = append(authors, strings.TrimSpace(c.Name))
+		for _, name := range append(c.Seq, c.Bag...) {
+			authors = append(authors, strings.TrimSpace(name))
+		}
+	}
+	authors = slices.DeleteFunc(authors, func(name string) bool { return name == "" })
+	return strings.Join(authors, ", ")
 }
 
 type xmlParams struct {
@@ -70,34 +131,58 @@ func tryParseFeed(data []byte, v any) error {
 	return dec.Decode(&v)
 }
 
-func parseXML(data []byte, params any) ([]feed.RawItem, error) {
+func parseXML(data []byte, params any) (*parseResult, error) {
 	var p xmlParams
 	if err := feed.ParseParams(params, &p); err != nil {
 		return nil, fmt.Errorf("cannot parse XML feed params: %v", err)
 	}
 
 	var feedItems []feed.RawItem
+	var hints *feed.UpdateHints
+	var metadata *feed.Metadata
+	var nextPage string
 
 	rss := RSS{}
 	rssErr := tryParseFeed(data, &rss)
 	if rssErr == nil && (len(rss.Channel.Items) > 0 || len(rss.Items) > 0) {
-		baseURL := absoluteURL(rss.Channel.Link)
+		hints = parseHints(rss.Channel.TTL, rss.Channel.SkipHours, rss.Channel.SkipDays, rss.Channel.Syndication)
+		metadata = rss.metadata()
+		nextPage = nextPageHref(rss.Channel.AtomLinks, nil)
+		baseURL := absoluteURL(strings.TrimSpace(rss.Channel.Link))
 		items := rss.Channel.Items
 		if len(items) == 0 {
 			items = rss.Items
 		}
 		for pos, item := range items {
-			resolvedItemURL := resolveURL(item.Link, baseURL, nil)
+			itemURL := strings.TrimSpace(item.Link)
+			if rss.isRDF() {
+				itemURL = coalesce(itemURL, strings.TrimSpace(item.About))
+			}
+			resolvedItemURL := resolveURL(itemURL, baseURL, nil)
 			content := coalesce(item.Encoded, strings.Join(item.Descriptions, "\n"))
 			if p.InferParagraphs {
 				content = inferParagraphs(content)
 			}
+			media := Media{
+				Enclosures: item.Enclosures,
+				Contents:   item.MediaContents,
+				Groups:     item.MediaGroups,
+				Thumbnails: item.MediaThumbnails,
+				Duration:   item.Duration,
+			}
+			guid := coalesce(strings.TrimSpace(item.GUID), strings.TrimSpace(item.ID))
+			if rss.isRDF() {
+				guid = coalesce(guid, strings.TrimSpace(item.About))
+			}
 			feedItems = append(feedItems, feed.RawItem{
-				URL:      urlToString(resolvedItemURL),
-				Title:    strings.TrimSpace(item.Title),
-				Authors:  strings.TrimSpace(strings.Join(append(item.Authors, item.Creator...), ", ")),
-				Content:  silentlySanitizeHTML(content, resolvedItemURL),
-				Position: pos,
+				GUID:        guid,
+				URL:         urlToString(resolvedItemURL),
+				Title:       strings.TrimSpace(item.Title),
+				Authors:     item.authors(),
+				Content:     silentlySanitizeHTML(content, resolvedItemURL),
+				Published:   cmp.Or(parseDate(item.PubDate), parseDate(item.Date)),
+				Attachments: media.attachments(resolvedItemURL),
+				Position:    pos,
 			})
 		}
 	}
@@ -105,41 +190,85 @@ func parseXML(data []byte, params any) ([]feed.RawItem, error) {
 	atom := Atom{}
 	atomErr := tryParseFeed(data, &atom)
 	if atomErr == nil && len(atom.Entries) > 0 {
-		baseURL := absoluteURL(atom.Link.Href)
-		for pos, entry := range atom.Entries {
-			// Atom may have multiple links for entries, prefer the most
-			// logical one (with rel="self" or no rel attribute), or pick the
-			// first one if none of the above are found.
-			var itemURL string
-			for _, link := range entry.Links {
-				if link.Rel == "self" || link.Rel == "" {
-					itemURL = link.Href
-					break
-				}
-			}
-			if itemURL == "" && len(entry.Links) > 0 {
-				itemURL = entry.Links[0].Href
-			}
-			resolvedItemURL := resolveURL(itemURL, baseURL, nil)
-			content := coalesce(entry.Content, entry.Summary)
-			if p.InferParagraphs {
-				content = inferParagraphs(content)
-			}
-			feedItems = append(feedItems, feed.RawItem{
-				URL:      urlToString(resolvedItemURL),
-				Title:    strings.TrimSpace(entry.Title),
-				Authors:  strings.TrimSpace(strings.Join(entry.Authors, ", ")),
-				Content:  silentlySanitizeHTML(content, resolvedItemURL),
-				Position: pos,
-			})
-		}
+		hints = parseHints("", nil, nil, atom.Syndication)
+		metadata = atom.metadata()
+		nextPage = nextPageHref(atom.Links, xmlBase(atom.Base, nil))
+		feedItems = append(feedItems, parseAtomEntries(&atom, p)...)
 	}
 
 	err := errors.Join(rssErr, atomErr)
 	if err != nil {
-		err = fmt.Errorf("cannot parse XML as either RSS or Atom: %v", errors.Join(rssErr, atomErr))
+		return nil, fmt.Errorf("cannot parse XML as either RSS or Atom: %v", errors.Join(rssErr, atomErr))
+	}
+	return &parseResult{Items: feedItems, Hints: hints, Metadata: metadata, NextPage: nextPage}, nil
+}
+
+// nextPageHref returns the URL of the next page of a paged or archived feed
+// (RFC 5005), resolved against baseURL, or an empty string if there is none.
+// Paged feeds link to their next page, while archived feeds link to their
+// previous archive.
+func nextPageHref(links []AtomLink, baseURL *url.URL) string {
+	href := coalesce(
+		strings.TrimSpace(atomLinkHref(links, "next")),
+		strings.TrimSpace(atomLinkHref(links, "prev-archive")),
+	)
+	if href == "" {
+		return ""
+	}
+	return urlToString(resolveURL(href, baseURL, nil))
+}
+
+// updatePeriods maps the values of sy:updatePeriod to their durations in
+// seconds.
+var updatePeriods = map[string]int64{
+	"hourly":  60 * 60,
+	"daily":   24 * 60 * 60,
+	"weekly":  7 * 24 * 60 * 60,
+	"monthly": 30 * 24 * 60 * 60,
+	"yearly":  365 * 24 * 60 * 60,
+}
+
+// skipDays maps the values of RSS skipDays to weekdays.
+var skipDays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseHints converts the update hints declared in a feed. Invalid values are
+// ignored, as publishers get them wrong often enough that failing the whole
+// feed would do more harm than good. It returns nil if the feed declares no
+// valid hints.
+func parseHints(ttl string, hours, days []string, sy Syndication) *feed.UpdateHints {
+	h := &feed.UpdateHints{}
+	if minutes, err := strconv.ParseInt(strings.TrimSpace(ttl), 10, 64); err == nil && minutes > 0 {
+		h.TTL = minutes * 60
+	}
+	if period, ok := updatePeriods[strings.ToLower(strings.TrimSpace(sy.UpdatePeriod))]; ok {
+		frequency, err := strconv.ParseInt(strings.TrimSpace(sy.UpdateFrequency), 10, 64)
+		if err != nil || frequency < 1 {
+			frequency = 1
+		}
+		h.UpdatePeriod = period / frequency
+	}
+	for _, hour := range hours {
+		if n, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil && n >= 0 && n <= 23 {
+			h.SkipHours = append(h.SkipHours, n)
+		}
+	}
+	for _, day := range days {
+		if d, ok := skipDays[strings.ToLower(strings.TrimSpace(day))]; ok {
+			h.SkipDays = append(h.SkipDays, d)
+		}
+	}
+	if h.TTL == 0 && h.UpdatePeriod == 0 && len(h.SkipHours) == 0 && len(h.SkipDays) == 0 {
+		return nil
 	}
-	return feedItems, err
+	return h
 }
 
 // absoluteURL returns the URL for input if it is a valid absolute URL,
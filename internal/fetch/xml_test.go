@@ -1,13 +1,20 @@
 package fetch_test
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/alnvdl/varys/internal/feed"
 	"github.com/alnvdl/varys/internal/fetch"
 )
 
 func TestParseXML(t *testing.T) {
+	date := func(s string) int64 {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t.Unix()
+	}
+
 	tests := []struct {
 		desc   string
 		xml    string
@@ -47,11 +54,41 @@ func TestParseXML(t *testing.T) {
 				</channel>
 			</rss>`,
 		expected: []feed.RawItem{{
-			URL:      "http://example.com/content/item1",
+			URL:       "http://example.com/content/item1",
+			Title:     "Item 1",
+			Authors:   "Author 1",
+			Content:   "Content 1",
+			Published: date("2025-03-02T12:30:15Z"),
+			Position:  0,
+		}},
+	}, {
+		desc: "RSS with GUIDs",
+		xml: `
+			<rss>
+				<channel>
+					<link>http://example.com</link>
+					<item>
+						<title>Item 1</title>
+						<link>http://example.com/item1?utm_source=rss</link>
+						<guid isPermaLink="false"> 1234@example.com </guid>
+					</item>
+					<item>
+						<title>Item 2</title>
+						<link>http://example.com/item2</link>
+						<guid>http://example.com/item2</guid>
+					</item>
+				</channel>
+			</rss>`,
+		expected: []feed.RawItem{{
+			GUID:     "1234@example.com",
+			URL:      "http://example.com/item1?utm_source=rss",
 			Title:    "Item 1",
-			Authors:  "Author 1",
-			Content:  "Content 1",
 			Position: 0,
+		}, {
+			GUID:     "http://example.com/item2",
+			URL:      "http://example.com/item2",
+			Title:    "Item 2",
+			Position: 1,
 		}},
 	}, {
 		desc: "RSS with 1 invalid item",
@@ -103,23 +140,26 @@ func TestParseXML(t *testing.T) {
 				</channel>
 			</rss>`,
 		expected: []feed.RawItem{{
-			URL:      "http://example.com/item1",
-			Title:    "Item 1",
-			Authors:  "Author 1",
-			Content:  "Content 1",
-			Position: 0,
+			URL:       "http://example.com/item1",
+			Title:     "Item 1",
+			Authors:   "Author 1",
+			Content:   "Content 1",
+			Published: date("2025-01-02T22:04:05Z"),
+			Position:  0,
 		}, {
-			URL:      "http://example.com/item2",
-			Title:    "Item 2",
-			Authors:  "Author 2",
-			Content:  "Content 2",
-			Position: 1,
+			URL:       "http://example.com/item2",
+			Title:     "Item 2",
+			Authors:   "Author 2",
+			Content:   "Content 2",
+			Published: date("2025-01-03T22:04:05Z"),
+			Position:  1,
 		}, {
-			URL:      "http://example.com/item3",
-			Title:    "Item 3",
-			Authors:  "Author 3, Author 4",
-			Content:  "Content 3",
-			Position: 2,
+			URL:       "http://example.com/item3",
+			Title:     "Item 3",
+			Authors:   "Author 3, Author 4",
+			Content:   "Content 3",
+			Published: date("2025-01-04T15:04:05Z"),
+			Position:  2,
 		}},
 	}, {
 		desc: "Atom with 0 items",
@@ -144,11 +184,12 @@ func TestParseXML(t *testing.T) {
 						</entry>
 					</feed>`,
 		expected: []feed.RawItem{{
-			URL:      "http://example.com/content/item1",
-			Title:    "Item 1",
-			Authors:  "Author 1",
-			Content:  "Content 1",
-			Position: 0,
+			URL:       "http://example.com/content/item1",
+			Title:     "Item 1",
+			Authors:   "Author 1",
+			Content:   "Content 1",
+			Published: date("2025-01-02T15:04:05Z"),
+			Position:  0,
 		}},
 	}, {
 		desc: "Atom with 3 items",
@@ -185,23 +226,26 @@ func TestParseXML(t *testing.T) {
 				</entry>
 			</feed>`,
 		expected: []feed.RawItem{{
-			URL:      "http://example.com/item1",
-			Title:    "Item 1",
-			Authors:  "Author 1",
-			Content:  "Content 1",
-			Position: 0,
+			URL:       "http://example.com/item1",
+			Title:     "Item 1",
+			Authors:   "Author 1",
+			Content:   "Content 1",
+			Published: date("2025-01-02T15:04:05Z"),
+			Position:  0,
 		}, {
-			URL:      "http://example.com/item2",
-			Title:    "Item 2",
-			Authors:  "Author 2",
-			Content:  "Content 2",
-			Position: 1,
+			URL:       "http://example.com/item2",
+			Title:     "Item 2",
+			Authors:   "Author 2",
+			Content:   "Content 2",
+			Published: date("2025-01-03T22:04:05Z"),
+			Position:  1,
 		}, {
-			URL:      "http://example.com/item3",
-			Title:    "Item 3",
-			Authors:  "Author 3",
-			Content:  "Content 3",
-			Position: 2,
+			URL:       "http://example.com/item3",
+			Title:     "Item 3",
+			Authors:   "Author 3",
+			Content:   "Content 3",
+			Published: date("2025-01-04T15:04:05Z"),
+			Position:  2,
 		}},
 	}, {
 		desc: "RSS with mixed valid and invalid items",
@@ -238,17 +282,19 @@ func TestParseXML(t *testing.T) {
 				</channel>
 			</rss>`,
 		expected: []feed.RawItem{{
-			URL:      "http://example.com/item1",
-			Title:    "Item 1",
-			Authors:  "Author 1, Author 2",
-			Content:  "<div>should be in output</div>\n\t\t\t\t\t\t\t\n\t\t\t\t\t\t\t<b>Content 1</b>",
-			Position: 0,
+			URL:       "http://example.com/item1",
+			Title:     "Item 1",
+			Authors:   "Author 1, Author 2",
+			Content:   "<div>should be in output</div>\n\t\t\t\t\t\t\t\n\t\t\t\t\t\t\t<b>Content 1</b>",
+			Published: date("2025-01-02T22:04:05Z"),
+			Position:  0,
 		}, {
-			URL:      "/item2",
-			Title:    "Item 2",
-			Authors:  "Author 3",
-			Content:  "Content 2",
-			Position: 1,
+			URL:       "/item2",
+			Title:     "Item 2",
+			Authors:   "Author 3",
+			Content:   "Content 2",
+			Published: date("2025-01-02T22:04:05Z"),
+			Position:  1,
 		}},
 	}, {
 		desc: "Atom with mixed valid and invalid items",
@@ -287,20 +333,22 @@ func TestParseXML(t *testing.T) {
 				</entry>
 			</feed>`,
 		expected: []feed.RawItem{{
-			URL:      "http://example.com/item1",
-			Title:    "Item 1",
-			Authors:  "Author 1, Author 2",
-			Content:  "Content 1",
-			Position: 0,
+			URL:       "http://example.com/item1",
+			Title:     "Item 1",
+			Authors:   "Author 1, Author 2",
+			Content:   "Content 1",
+			Published: date("2025-01-02T15:04:05Z"),
+			Position:  0,
 		}, {
 			// Empty item.
 			Position: 1,
 		}, {
-			URL:      "http://example.com/item2",
-			Title:    "Item 2",
-			Authors:  "Author 3",
-			Content:  "Content 2",
-			Position: 2,
+			URL:       "http://example.com/item2",
+			Title:     "Item 2",
+			Authors:   "Author 3",
+			Content:   "Content 2",
+			Published: date("2025-01-03T22:04:05Z"),
+			Position:  2,
 		}},
 	}, {
 		desc: "RSS with HTML in fields",
@@ -346,6 +394,198 @@ func TestParseXML(t *testing.T) {
 			Content:  "<div>should be in output</div><b>Content 1</b>",
 			Position: 0,
 		}},
+	}, {
+		desc: "Atom with escaped markup in untyped text",
+		xml: `
+			<feed xmlns="http://www.w3.org/2005/Atom">
+				<entry>
+					<title>Using &lt;br&gt; tags</title>
+					<link href="http://example.com/1"/>
+					<summary>Write &lt;b&gt;bold&lt;/b&gt; text</summary>
+				</entry>
+			</feed>`,
+		expected: []feed.RawItem{{
+			URL:      "http://example.com/1",
+			Title:    "Using <br> tags",
+			Content:  "Write &lt;b&gt;bold&lt;/b&gt; text",
+			Position: 0,
+		}},
+	}, {
+		desc: "Atom with alternate, self and other links",
+		xml: `
+			<feed xmlns="http://www.w3.org/2005/Atom">
+				<link rel="self" type="application/atom+xml" href="http://example.com/feed.atom"/>
+				<link rel="alternate" type="text/html" href="http://example.com/"/>
+				<entry>
+					<title>Item 1</title>
+					<link rel="self" href="http://example.com/api/entries/1"/>
+					<link rel="edit" href="http://example.com/api/entries/1/edit"/>
+					<link rel="alternate" type="application/json" href="/entries/1.json"/>
+					<link rel="alternate" type="text/html" href="/entries/1"/>
+				</entry>
+				<entry>
+					<title>Item 2</title>
+					<link rel="replies" href="http://example.com/entries/2/comments"/>
+					<link href="entries/2"/>
+				</entry>
+				<entry>
+					<title>Item 3</title>
+					<id>https://example.com/entries/3</id>
+					<link rel="self" href="http://example.com/api/entries/3"/>
+				</entry>
+				<entry>
+					<title>Item 4</title>
+					<id>tag:example.com,2025:entry-4</id>
+					<link rel="self" href="http://example.com/api/entries/4"/>
+					<link rel="related" href="http://other.example.com/4"/>
+				</entry>
+			</feed>`,
+		expected: []feed.RawItem{{
+			URL:      "http://example.com/entries/1",
+			Title:    "Item 1",
+			Position: 0,
+		}, {
+			URL:      "http://example.com/entries/2",
+			Title:    "Item 2",
+			Position: 1,
+		}, {
+			GUID:     "https://example.com/entries/3",
+			URL:      "https://example.com/entries/3",
+			Title:    "Item 3",
+			Position: 2,
+		}, {
+			GUID:     "tag:example.com,2025:entry-4",
+			URL:      "http://other.example.com/4",
+			Title:    "Item 4",
+			Position: 3,
+		}},
+	}, {
+		desc: "Atom with xml:base",
+		xml: `
+			<feed xmlns="http://www.w3.org/2005/Atom" xml:base="http://example.com/blog/">
+				<link rel="self" href="http://feeds.example.net/blog.atom"/>
+				<entry>
+					<title>Item 1</title>
+					<link href="2025/item1"/>
+					<content type="html">&lt;img src="images/1.png"&gt;</content>
+				</entry>
+				<entry xml:base="/archive/">
+					<title>Item 2</title>
+					<link href="item2"/>
+					<content type="html" xml:base="http://cdn.example.com/">&lt;a href="file.pdf"&gt;File&lt;/a&gt;</content>
+				</entry>
+				<entry>
+					<title>Item 3</title>
+					<link xml:base="http://other.example.com/" href="item3"/>
+				</entry>
+			</feed>`,
+		expected: []feed.RawItem{{
+			URL:      "http://example.com/blog/2025/item1",
+			Title:    "Item 1",
+			Content:  `<img src="http://example.com/blog/images/1.png"/>`,
+			Position: 0,
+		}, {
+			URL:      "http://example.com/archive/item2",
+			Title:    "Item 2",
+			Content:  `<a href="http://cdn.example.com/file.pdf">File</a>`,
+			Position: 1,
+		}, {
+			URL:      "http://other.example.com/item3",
+			Title:    "Item 3",
+			Position: 2,
+		}},
+	}, {
+		desc: "Atom with text, html and xhtml constructs",
+		xml: `
+			<feed xmlns="http://www.w3.org/2005/Atom" xmlns:xh="http://www.w3.org/1999/xhtml">
+				<link href="http://example.com/"/>
+				<entry>
+					<title type="text">Less &lt;than&gt; &amp; more</title>
+					<link href="/1"/>
+					<content type="text">Use &lt;b&gt; for bold &amp; more</content>
+				</entry>
+				<entry>
+					<title type="html">Bold &lt;b&gt;move&lt;/b&gt; &amp;amp; more</title>
+					<link href="/2"/>
+					<summary type="html">&lt;p&gt;Summary &lt;em&gt;2&lt;/em&gt;&lt;/p&gt;</summary>
+				</entry>
+				<entry>
+					<title type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">XHTML <i>title</i></div></title>
+					<link href="/3"/>
+					<content type="xhtml">
+						<div xmlns="http://www.w3.org/1999/xhtml">
+							<p class="intro">First<br/>line &amp; more</p>
+							<p><img src="/3.png" alt="3"/> <a href="/4">next</a></p>
+						</div>
+					</content>
+				</entry>
+				<entry>
+					<title>Item 4</title>
+					<link href="/4"/>
+					<content type="xhtml"><xh:div><xh:p>Prefixed <xh:strong>XHTML</xh:strong></xh:p></xh:div></content>
+				</entry>
+				<entry>
+					<title>Item 5</title>
+					<link href="/5"/>
+					<content type="video/mp4" src="http://example.com/5.mp4"/>
+					<summary>Summary 5</summary>
+				</entry>
+			</feed>`,
+		expected: []feed.RawItem{{
+			URL:      "http://example.com/1",
+			Title:    "Less <than> & more",
+			Content:  "Use &lt;b&gt; for bold &amp; more",
+			Position: 0,
+		}, {
+			URL:      "http://example.com/2",
+			Title:    "Bold move & more",
+			Content:  "<p>Summary <em>2</em></p>",
+			Position: 1,
+		}, {
+			URL:   "http://example.com/3",
+			Title: "XHTML title",
+			Content: `<p>First<br/>line &amp; more</p>
+							<p><img src="http://example.com/3.png" alt="3"/> <a href="http://example.com/4">next</a></p>`,
+			Position: 2,
+		}, {
+			URL:      "http://example.com/4",
+			Title:    "Item 4",
+			Content:  "<p>Prefixed <strong>XHTML</strong></p>",
+			Position: 3,
+		}, {
+			URL:      "http://example.com/5",
+			Title:    "Item 5",
+			Content:  "Summary 5",
+			Position: 4,
+		}},
+	}, {
+		desc: "Atom with author names, emails and URIs",
+		xml: `
+			<feed xmlns="http://www.w3.org/2005/Atom">
+				<author><name>Feed Author</name><uri>http://example.com/</uri></author>
+				<entry>
+					<title>Item 1</title>
+					<link href="http://example.com/1"/>
+					<author><name> Author 1 </name><email>author1@example.com</email></author>
+					<author><email>author2@example.com</email></author>
+					<author><uri>http://example.com/author3</uri></author>
+				</entry>
+				<entry>
+					<title>Item 2</title>
+					<link href="http://example.com/2"/>
+				</entry>
+			</feed>`,
+		expected: []feed.RawItem{{
+			URL:      "http://example.com/1",
+			Title:    "Item 1",
+			Authors:  "Author 1, author2@example.com, http://example.com/author3",
+			Position: 0,
+		}, {
+			URL:      "http://example.com/2",
+			Title:    "Item 2",
+			Authors:  "Feed Author",
+			Position: 1,
+		}},
 	}, {
 		desc: "rss with infer_paragraphs",
 		params: map[string]any{
@@ -401,6 +641,252 @@ func TestParseXML(t *testing.T) {
 			Content:  "<p>first line</p><p>second line</p>",
 			Position: 0,
 		}},
+	}, {
+		desc: "RDF (RSS 1.0) with Dublin Core dates and creators",
+		xml: `<?xml version="1.0" encoding="ISO-8859-1"?>
+			<rdf:RDF
+				xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+				xmlns="http://purl.org/rss/1.0/"
+				xmlns:content="http://purl.org/rss/1.0/modules/content/"
+				xmlns:dc="http://purl.org/dc/elements/1.1/"
+				xmlns:slash="http://purl.org/rss/1.0/modules/slash/"
+				xmlns:syn="http://purl.org/rss/1.0/modules/syndication/">
+				<channel rdf:about="https://example.com/">
+					<title>Example</title>
+					<link>
+						https://example.com/
+					</link>
+					<description>News for nerds</description>
+					<dc:language>en-us</dc:language>
+					<dc:date>2025-03-02T16:00:00+00:00</dc:date>
+					<syn:updatePeriod>hourly</syn:updatePeriod>
+					<items>
+						<rdf:Seq>
+							<rdf:li rdf:resource="https://example.com/story/1"/>
+							<rdf:li rdf:resource="https://example.com/story/2"/>
+						</rdf:Seq>
+					</items>
+					<image rdf:resource="https://example.com/logo.png"/>
+				</channel>
+				<image rdf:about="https://example.com/logo.png">
+					<title>Example</title>
+					<url>https://example.com/logo.png</url>
+					<link>https://example.com/</link>
+				</image>
+				<item rdf:about="https://example.com/story/1">
+					<title>Story 1</title>
+					<link>https://example.com/story/1?utm_source=rss1.0</link>
+					<description>Description &lt;b&gt;1&lt;/b&gt;</description>
+					<dc:creator>editor1</dc:creator>
+					<dc:subject>news</dc:subject>
+					<dc:date>2025-03-02T15:40:00+00:00</dc:date>
+					<slash:comments>42</slash:comments>
+				</item>
+				<item rdf:about="https://example.com/story/2">
+					<title>Story 2</title>
+					<link>/story/2</link>
+					<description>Description 2</description>
+					<content:encoded><![CDATA[<p>Content <em>2</em></p>]]></content:encoded>
+					<dc:creator>editor2</dc:creator>
+					<dc:creator>editor3</dc:creator>
+					<dc:date>2025-03-02T12:00:00-03:00</dc:date>
+				</item>
+				<textinput rdf:about="https://example.com/search">
+					<title>Search</title>
+					<link>https://example.com/search</link>
+				</textinput>
+			</rdf:RDF>`,
+		expected: []feed.RawItem{{
+			GUID:      "https://example.com/story/1",
+			URL:       "https://example.com/story/1?utm_source=rss1.0",
+			Title:     "Story 1",
+			Authors:   "editor1",
+			Content:   "Description <b>1</b>",
+			Published: date("2025-03-02T15:40:00Z"),
+			Position:  0,
+		}, {
+			GUID:      "https://example.com/story/2",
+			URL:       "https://example.com/story/2",
+			Title:     "Story 2",
+			Authors:   "editor2, editor3",
+			Content:   "<p>Content <em>2</em></p>",
+			Published: date("2025-03-02T15:00:00Z"),
+			Position:  1,
+		}},
+	}, {
+		desc: "RDF with creator lists and items without links",
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+			<rdf:RDF
+				xmlns="http://purl.org/rss/1.0/"
+				xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+				xmlns:dc="http://purl.org/dc/elements/1.1/">
+				<channel rdf:about="https://blog.example.jp/index.rdf">
+					<title>Blog</title>
+					<link>https://blog.example.jp/</link>
+				</channel>
+				<item rdf:about="https://blog.example.jp/entry/1">
+					<title>Entry 1</title>
+					<dc:creator>
+						<rdf:Seq>
+							<rdf:li>Alice</rdf:li>
+							<rdf:li>Bob</rdf:li>
+						</rdf:Seq>
+					</dc:creator>
+					<dc:date>2025-03-02T21:00:00+09:00</dc:date>
+				</item>
+				<item rdf:about="https://blog.example.jp/entry/2">
+					<title>Entry 2</title>
+					<dc:creator><rdf:Bag><rdf:li>Carol</rdf:li></rdf:Bag></dc:creator>
+				</item>
+			</rdf:RDF>`,
+		expected: []feed.RawItem{{
+			GUID:      "https://blog.example.jp/entry/1",
+			URL:       "https://blog.example.jp/entry/1",
+			Title:     "Entry 1",
+			Authors:   "Alice, Bob",
+			Published: date("2025-03-02T12:00:00Z"),
+			Position:  0,
+		}, {
+			GUID:     "https://blog.example.jp/entry/2",
+			URL:      "https://blog.example.jp/entry/2",
+			Title:    "Entry 2",
+			Authors:  "Carol",
+			Position: 1,
+		}},
+	}, {
+		desc: "RSS 0.90",
+		xml: `<?xml version="1.0"?>
+			<rdf:RDF
+				xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+				xmlns="http://my.netscape.com/rdf/simple/0.9/">
+				<channel>
+					<title>Mozilla Dot Org</title>
+					<link>http://www.mozilla.org</link>
+					<description>the Mozilla Organization web site</description>
+				</channel>
+				<item>
+					<title>New Status Updates</title>
+					<link>http://www.mozilla.org/status/</link>
+				</item>
+				<item>
+					<title>Bugzilla Reorganized</title>
+					<link>http://www.mozilla.org/bugs/</link>
+				</item>
+			</rdf:RDF>`,
+		expected: []feed.RawItem{{
+			URL:      "http://www.mozilla.org/status/",
+			Title:    "New Status Updates",
+			Position: 0,
+		}, {
+			URL:      "http://www.mozilla.org/bugs/",
+			Title:    "Bugzilla Reorganized",
+			Position: 1,
+		}},
+	}, {
+		desc: "RSS with enclosures and Media RSS",
+		xml: `
+			<rss xmlns:media="http://search.yahoo.com/mrss/" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+				<channel>
+					<link>http://example.com</link>
+					<item>
+						<title>Episode 1</title>
+						<link>http://example.com/ep1</link>
+						<enclosure url="/ep1.mp3" length="12345" type="audio/mpeg"/>
+						<enclosure url="javascript:alert(1)" type="audio/mpeg"/>
+						<itunes:duration>1:02:03</itunes:duration>
+						<media:thumbnail url="http://example.com/ep1.jpg"/>
+					</item>
+					<item>
+						<title>Video 2</title>
+						<link>http://example.com/video2</link>
+						<enclosure url="http://example.com/video2.mp4" type="Video/MP4; codecs=avc1"/>
+						<media:content url="http://example.com/video2.mp4" fileSize="999" duration="90.5">
+							<media:thumbnail url="data:image/png;base64,iVBORw0KGgo="/>
+						</media:content>
+						<media:content url="http://example.com/video2.webm" type="video/webm">
+							<media:thumbnail url="data:image/svg+xml;base64,PHN2Zz4="/>
+						</media:content>
+					</item>
+					<item>
+						<title>Photo 3</title>
+						<link>http://example.com/photo3</link>
+						<media:thumbnail url="photo3.jpg"/>
+					</item>
+				</channel>
+			</rss>`,
+		expected: []feed.RawItem{{
+			URL:   "http://example.com/ep1",
+			Title: "Episode 1",
+			Attachments: []feed.Attachment{{
+				URL:       "http://example.com/ep1.mp3",
+				MimeType:  "audio/mpeg",
+				Length:    12345,
+				Duration:  3723,
+				Thumbnail: "http://example.com/ep1.jpg",
+			}},
+			Position: 0,
+		}, {
+			URL:   "http://example.com/video2",
+			Title: "Video 2",
+			Attachments: []feed.Attachment{{
+				URL:       "http://example.com/video2.mp4",
+				MimeType:  "video/mp4",
+				Length:    999,
+				Duration:  90,
+				Thumbnail: "data:image/png;base64,iVBORw0KGgo=",
+			}, {
+				URL:      "http://example.com/video2.webm",
+				MimeType: "video/webm",
+			}},
+			Position: 1,
+		}, {
+			URL:   "http://example.com/photo3",
+			Title: "Photo 3",
+			Attachments: []feed.Attachment{{
+				URL:       "http://example.com/photo3.jpg",
+				Thumbnail: "http://example.com/photo3.jpg",
+			}},
+			Position: 2,
+		}},
+	}, {
+		desc: "Atom with enclosure links and media groups",
+		xml: `
+			<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+				<entry>
+					<title>Video 1</title>
+					<link rel="alternate" href="https://www.youtube.com/watch?v=abc"/>
+					<media:group>
+						<media:title>Video 1</media:title>
+						<media:content url="https://www.youtube.com/v/abc?version=3" type="application/x-shockwave-flash" width="640" height="390"/>
+						<media:thumbnail url="https://i.ytimg.com/vi/abc/hqdefault.jpg" width="480" height="360"/>
+						<media:description>Video description</media:description>
+					</media:group>
+				</entry>
+				<entry>
+					<title>Episode 2</title>
+					<link href="http://example.com/ep2"/>
+					<link rel="enclosure" href="http://example.com/ep2.ogg" type="audio/ogg" length="42"/>
+				</entry>
+			</feed>`,
+		expected: []feed.RawItem{{
+			URL:   "https://www.youtube.com/watch?v=abc",
+			Title: "Video 1",
+			Attachments: []feed.Attachment{{
+				URL:       "https://www.youtube.com/v/abc?version=3",
+				MimeType:  "application/x-shockwave-flash",
+				Thumbnail: "https://i.ytimg.com/vi/abc/hqdefault.jpg",
+			}},
+			Position: 0,
+		}, {
+			URL:   "http://example.com/ep2",
+			Title: "Episode 2",
+			Attachments: []feed.Attachment{{
+				URL:      "http://example.com/ep2.ogg",
+				MimeType: "audio/ogg",
+				Length:   42,
+			}},
+			Position: 1,
+		}},
 	}, {
 		desc:     "malformed XML",
 		xml:      `<>`,
@@ -411,7 +897,7 @@ func TestParseXML(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
 			data := []byte(test.xml)
-			items, err := fetch.ParseXML(data, test.params)
+			res, err := fetch.ParseXML(data, test.params)
 			if err != nil {
 				if test.err == "" {
 					t.Errorf("unexpected error: %v", err)
@@ -420,15 +906,156 @@ func TestParseXML(t *testing.T) {
 				}
 				return
 			}
+			items := res.Items
 			if len(items) != len(test.expected) {
 				t.Errorf("expected %d items, got %d", len(test.expected), len(items))
 				return
 			}
 			for i, item := range items {
-				if item != test.expected[i] {
+				if !reflect.DeepEqual(item, test.expected[i]) {
 					t.Errorf("expected item %#v, got %#v", test.expected[i], item)
 				}
 			}
 		})
 	}
 }
+
+func TestParseXMLHints(t *testing.T) {
+	tests := []struct {
+		desc     string
+		xml      string
+		expected *feed.UpdateHints
+	}{{
+		desc: "RSS without hints",
+		xml: `<rss><channel>
+			<item><link>https://example.com/1</link></item>
+		</channel></rss>`,
+		expected: nil,
+	}, {
+		desc: "RSS with ttl, skipHours and skipDays",
+		xml: `<rss><channel>
+			<ttl>90</ttl>
+			<skipHours><hour>0</hour><hour>23</hour></skipHours>
+			<skipDays><day>Saturday</day><day>Sunday</day></skipDays>
+			<item><link>https://example.com/1</link></item>
+		</channel></rss>`,
+		expected: &feed.UpdateHints{
+			TTL:       90 * 60,
+			SkipHours: []int{0, 23},
+			SkipDays:  []time.Weekday{time.Saturday, time.Sunday},
+		},
+	}, {
+		desc: "RSS with syndication module",
+		xml: `<rss xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+			<sy:updatePeriod>daily</sy:updatePeriod>
+			<sy:updateFrequency>2</sy:updateFrequency>
+			<item><link>https://example.com/1</link></item>
+		</channel></rss>`,
+		expected: &feed.UpdateHints{UpdatePeriod: 12 * 60 * 60},
+	}, {
+		desc: "RSS with syndication module without frequency",
+		xml: `<rss xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+			<sy:updatePeriod> Hourly </sy:updatePeriod>
+			<item><link>https://example.com/1</link></item>
+		</channel></rss>`,
+		expected: &feed.UpdateHints{UpdatePeriod: 60 * 60},
+	}, {
+		desc: "RSS with invalid hints",
+		xml: `<rss><channel>
+			<ttl>soon</ttl>
+			<skipHours><hour>24</hour></skipHours>
+			<skipDays><day>Someday</day></skipDays>
+			<sy:updatePeriod xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">often</sy:updatePeriod>
+			<item><link>https://example.com/1</link></item>
+		</channel></rss>`,
+		expected: nil,
+	}, {
+		desc: "RDF with syndication module",
+		xml: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:syn="http://purl.org/rss/1.0/modules/syndication/">
+			<channel rdf:about="https://example.com/">
+				<syn:updatePeriod>hourly</syn:updatePeriod>
+				<syn:updateFrequency>1</syn:updateFrequency>
+			</channel>
+			<item rdf:about="https://example.com/1"><title>Item 1</title></item>
+		</rdf:RDF>`,
+		expected: &feed.UpdateHints{UpdatePeriod: 60 * 60},
+	}, {
+		desc: "Atom with syndication module",
+		xml: `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+			<sy:updatePeriod>weekly</sy:updatePeriod>
+			<sy:updateFrequency>7</sy:updateFrequency>
+			<entry><link href="https://example.com/1"/></entry>
+		</feed>`,
+		expected: &feed.UpdateHints{UpdatePeriod: 24 * 60 * 60},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res, err := fetch.ParseXML([]byte(test.xml), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(res.Hints, test.expected) {
+				t.Errorf("expected hints %#v, got %#v", test.expected, res.Hints)
+			}
+		})
+	}
+}
+
+func TestParseXMLNextPage(t *testing.T) {
+	tests := []struct {
+		desc     string
+		xml      string
+		expected string
+	}{{
+		desc: "RSS without pages",
+		xml: `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+			<atom:link rel="self" href="https://example.com/feed.xml"/>
+			<item><link>https://example.com/1</link></item>
+		</channel></rss>`,
+		expected: "",
+	}, {
+		desc: "RSS paged feed",
+		xml: `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+			<link>https://example.com/</link>
+			<atom:link rel="self" href="https://example.com/feed.xml"/>
+			<atom:link rel="next" href=" https://example.com/feed.xml?page=2 "/>
+			<item><link>https://example.com/1</link></item>
+		</channel></rss>`,
+		expected: "https://example.com/feed.xml?page=2",
+	}, {
+		desc: "RSS archived feed with relative link",
+		xml: `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+			<atom:link rel="prev-archive" href="/archive/2024.xml"/>
+			<item><link>https://example.com/1</link></item>
+		</channel></rss>`,
+		expected: "/archive/2024.xml",
+	}, {
+		desc: "Atom paged feed is preferred over archives",
+		xml: `<feed xmlns="http://www.w3.org/2005/Atom">
+			<link rel="prev-archive" href="https://example.com/archive/2024.xml"/>
+			<link rel="Next" href="https://example.com/feed.atom?page=2"/>
+			<entry><link href="https://example.com/1"/></entry>
+		</feed>`,
+		expected: "https://example.com/feed.atom?page=2",
+	}, {
+		desc: "Atom archived feed relative to xml:base",
+		xml: `<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://example.com/feeds/">
+			<link rel="prev-archive" href="archive/2024.atom"/>
+			<entry><link href="https://example.com/1"/></entry>
+		</feed>`,
+		expected: "https://example.com/feeds/archive/2024.atom",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res, err := fetch.ParseXML([]byte(test.xml), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.NextPage != test.expected {
+				t.Errorf("expected next page %q, got %q", test.expected, res.NextPage)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@
 package mem
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"log/slog"
 	"maps"
 	"os"
+	"reflect"
 	"sort"
 	"sync"
 	"time"
@@ -19,6 +21,7 @@ import (
 	"github.com/alnvdl/varys/internal/feed"
 	"github.com/alnvdl/varys/internal/fetch"
 	"github.com/alnvdl/varys/internal/list"
+	"github.com/alnvdl/varys/internal/timeutil"
 )
 
 // List is a feed list that is kept in memory and optionally backed by a
@@ -28,15 +31,39 @@ type List struct {
 	feeds   map[string]*feed.Feed
 	muFeeds sync.Mutex
 
-	refreshInterval time.Duration
-	refreshCallback func()
-	fetcher         func(p fetch.FetchParams) ([]feed.RawItem, int64, error)
-	wg              sync.WaitGroup
-	close           chan bool
+	// muRefresh serializes refreshes. It is never acquired while muFeeds is
+	// held.
+	muRefresh sync.Mutex
+
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	maxRefreshInterval time.Duration
+	refreshJitter      time.Duration
+	refreshCallback    func()
+	pool               fetchPool
+	fetcher            func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error)
+	iconFetcher        func(ctx context.Context, iconURL string) (*feed.Icon, error)
+	contentFetcher     func(ctx context.Context, pageURL, contentSelector string) (string, error)
+	wg                 sync.WaitGroup
+
+	// ctx is canceled when the list is closed, stopping the auto-refresh
+	// mechanism and aborting outstanding fetches.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// queue and scheduled hold the refresh schedule (see schedule.go). They
+	// are protected by muFeeds.
+	queue           schedule
+	scheduled       map[string]*scheduledFeed
+	scheduleChanged chan struct{}
 
 	autoSaver *autosave.AutoSaver
 }
 
+// defaultMinRefreshInterval is the lower bound of adaptive refresh intervals if
+// none is given.
+const defaultMinRefreshInterval = 1 * time.Minute
+
 type serializedList struct {
 	Feeds map[string]*feed.Feed `json:"feeds"`
 }
@@ -47,17 +74,52 @@ type ListParams struct {
 	// See LoadFeeds for more information on how this is used.
 	InitialFeeds []*list.InputFeed
 
-	// RefreshInterval is the interval at which feeds are refreshed. If 0,
+	// RefreshInterval is the default interval at which feeds are refreshed.
+	// Feeds may override it with the refresh_interval param. If 0,
 	// auto-refresh will be disabled.
 	RefreshInterval time.Duration
 
+	// MinRefreshInterval and MaxRefreshInterval bound the adaptive refresh
+	// interval of feeds, which is learned from how often they publish new
+	// items. Feeds whose interval cannot be learned yet use RefreshInterval,
+	// and feeds may opt out with the refresh_interval param. If
+	// MaxRefreshInterval is 0, adaptive refresh intervals are disabled. If
+	// MinRefreshInterval is 0, defaultMinRefreshInterval is used.
+	MinRefreshInterval time.Duration
+	MaxRefreshInterval time.Duration
+
+	// RefreshJitter is the maximum random delay added to the scheduled
+	// refresh time of each feed, so refreshes do not all happen at once.
+	RefreshJitter time.Duration
+
+	// RefreshConcurrency is the maximum number of feeds fetched at once. If
+	// 0, defaultRefreshConcurrency is used.
+	RefreshConcurrency int
+
+	// HostConcurrency is the maximum number of feeds fetched at once from the
+	// same host. If 0, defaultHostConcurrency is used.
+	HostConcurrency int
+
+	// HostDelay is the minimum delay between the start of requests to the
+	// same host, so feeds sharing a host are fetched politely.
+	HostDelay time.Duration
+
 	// RefreshCallback is an optional function to be called after each
 	// auto-refresh operation.
 	RefreshCallback func()
 
 	// Fetcher is the function used to fetch feeds. If nil, a default fetcher
-	// will be used.
-	Fetcher func(p fetch.FetchParams) ([]feed.RawItem, int64, error)
+	// will be used. The context it receives is canceled when the list is
+	// closed.
+	Fetcher func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error)
+
+	// IconFetcher is the function used to fetch the icons of feeds. If nil, a
+	// default icon fetcher will be used.
+	IconFetcher func(ctx context.Context, iconURL string) (*feed.Icon, error)
+
+	// ContentFetcher is the function used to fetch the main content of the
+	// pages items link to. If nil, a default content fetcher will be used.
+	ContentFetcher func(ctx context.Context, pageURL, contentSelector string) (string, error)
 
 	// AutoSaveParams is the configuration for auto-save. If FilePath is empty,
 	// auto-save will be disabled and the list will be entirely in-memory only.
@@ -75,14 +137,42 @@ func NewList(p ListParams) (*List, error) {
 	if p.Fetcher == nil {
 		p.Fetcher = fetch.Fetch
 	}
+	if p.IconFetcher == nil {
+		p.IconFetcher = fetch.FetchIcon
+	}
+	if p.ContentFetcher == nil {
+		p.ContentFetcher = fetch.FetchContent
+	}
+	if p.MinRefreshInterval == 0 {
+		p.MinRefreshInterval = defaultMinRefreshInterval
+	}
+	if p.RefreshConcurrency == 0 {
+		p.RefreshConcurrency = defaultRefreshConcurrency
+	}
+	if p.HostConcurrency == 0 {
+		p.HostConcurrency = defaultHostConcurrency
+	}
 	l := &List{
-		feeds:           make(map[string]*feed.Feed),
-		refreshInterval: p.RefreshInterval,
-		refreshCallback: p.RefreshCallback,
-		fetcher:         p.Fetcher,
-		close:           make(chan bool),
+		feeds:              make(map[string]*feed.Feed),
+		refreshInterval:    p.RefreshInterval,
+		minRefreshInterval: p.MinRefreshInterval,
+		maxRefreshInterval: p.MaxRefreshInterval,
+		refreshJitter:      p.RefreshJitter,
+		refreshCallback:    p.RefreshCallback,
+		fetcher:            p.Fetcher,
+		iconFetcher:        p.IconFetcher,
+		contentFetcher:     p.ContentFetcher,
+		scheduled:          make(map[string]*scheduledFeed),
+		scheduleChanged:    make(chan struct{}, 1),
+		pool: fetchPool{
+			concurrency:     p.RefreshConcurrency,
+			hostConcurrency: p.HostConcurrency,
+			hostDelay:       p.HostDelay,
+		},
 	}
 
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+
 	if p.AutoSaveParams.FilePath != "" {
 		p.AutoSaveParams.LoaderSaver = l
 
@@ -160,6 +250,94 @@ func (l *List) FeedItem(fuid, iuid string) *feed.ItemSummary {
 	return nil
 }
 
+// ItemDiff returns the difference between the revisions of the item with the
+// given UID numbered from and to (see feed.Item.Diff). If the item or any of
+// the revisions is not found, nil is returned.
+func (l *List) ItemDiff(fuid, iuid string, from, to int) *feed.ItemDiff {
+	l.muFeeds.Lock()
+	defer l.muFeeds.Unlock()
+
+	feed := l.feeds[fuid]
+	if feed != nil {
+		item := feed.Items[iuid]
+		if item != nil {
+			return item.Diff(from, to)
+		}
+	}
+
+	return nil
+}
+
+// ItemReadable returns the main content of the page linked by the item with
+// the given UID. The content is fetched on the first request and cached in the
+// item until its URL changes. If the item is not found, nil is returned with no
+// error. The fetch is aborted if ctx is canceled.
+func (l *List) ItemReadable(ctx context.Context, fuid, iuid string) (*feed.Readable, error) {
+	l.muFeeds.Lock()
+	item := l.item(fuid, iuid)
+	if item == nil {
+		l.muFeeds.Unlock()
+		return nil, nil
+	}
+	if item.Readable != nil && item.Readable.URL == item.URL {
+		readable := *item.Readable
+		l.muFeeds.Unlock()
+		return &readable, nil
+	}
+	pageURL := item.URL
+	l.muFeeds.Unlock()
+
+	// The lock is not held while fetching, so other operations on the list
+	// are not blocked by slow pages.
+	content, err := l.contentFetcher(ctx, pageURL, "")
+	if err != nil {
+		return nil, err
+	}
+	readable := &feed.Readable{
+		URL:       pageURL,
+		Content:   content,
+		FetchedAt: timeutil.Now(),
+	}
+
+	l.muFeeds.Lock()
+	// The item may have been removed or changed while its page was fetched.
+	item = l.item(fuid, iuid)
+	stored := item != nil && item.URL == pageURL
+	if stored {
+		cached := *readable
+		item.Readable = &cached
+	}
+	l.muFeeds.Unlock()
+	// Only new content must be saved.
+	if stored {
+		l.delayAutoSave()
+	}
+	return readable, nil
+}
+
+// item returns the item with the given UID, or nil if it is not found. It
+// must be called with muFeeds held.
+func (l *List) item(fuid, iuid string) *feed.Item {
+	if f := l.feeds[fuid]; f != nil {
+		return f.Items[iuid]
+	}
+	return nil
+}
+
+// FeedIcon returns the cached icon of the feed with the given UID. If the feed
+// is not found or has no cached icon, nil is returned.
+func (l *List) FeedIcon(fuid string) *feed.Icon {
+	l.muFeeds.Lock()
+	defer l.muFeeds.Unlock()
+
+	feed := l.feeds[fuid]
+	if feed == nil || feed.Icon == nil || len(feed.Icon.Data) == 0 {
+		return nil
+	}
+	// Icons are replaced rather than modified, so they can be shared.
+	return feed.Icon
+}
+
 // MarkRead marks the feed or item with the given UID as read. If iuid is
 // empty, only items whose timestamp is less than or equal to before are marked
 // read. If fuid is "all", all feeds are marked as read, also respecting the
@@ -213,7 +391,13 @@ func (l *List) LoadFeeds(inputFeeds []*list.InputFeed) {
 	for _, inputFeed := range inputFeeds {
 		if f, ok := l.feeds[feed.UID(inputFeed.URL)]; ok {
 			// Feed is already in the list and is part of the input, keep it,
-			// updating some fields.
+			// updating some fields. If the way the feed is parsed changed,
+			// the cache validators are discarded so the next refresh is not
+			// skipped by a conditional request.
+			if f.Type != inputFeed.Type || !reflect.DeepEqual(f.Params, inputFeed.Params) {
+				f.ETag = ""
+				f.LastModified = ""
+			}
 			f.Name = inputFeed.Name
 			f.Type = inputFeed.Type
 			f.Params = inputFeed.Params
@@ -247,6 +431,7 @@ func (l *List) LoadFeeds(inputFeeds []*list.InputFeed) {
 		slog.Int("feedCount", len(newFeeds)),
 	)
 	l.feeds = newFeeds
+	l.notifyScheduleChanged()
 }
 
 // Load deserializes the feed list from the given reader.
@@ -268,6 +453,7 @@ func (l *List) Load(r io.Reader) error {
 		return fmt.Errorf("cannot deserialize feed list: %w", err)
 	}
 	l.feeds = data.Feeds
+	l.notifyScheduleChanged()
 
 	return nil
 }
@@ -289,10 +475,10 @@ func (l *List) Save(w io.Writer) error {
 	return nil
 }
 
-// Close stops the auto-refresh and auto-save mechanisms and waits for them to
-// finish.
+// Close stops the auto-refresh and auto-save mechanisms, aborting any
+// outstanding fetches, and waits for them to finish.
 func (l *List) Close() {
-	close(l.close)
+	l.cancel()
 	l.wg.Wait()
 	if l.autoSaver != nil {
 		l.autoSaver.Close()
@@ -2,9 +2,12 @@ package mem_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/alnvdl/varys/internal/feed"
@@ -20,7 +23,7 @@ func checkFeedItems(t *testing.T, feed feed.Feed, expectedItems []feed.Item) {
 	}
 	sortedItems := feed.SortedItems()
 	for i, expectedItem := range expectedItems {
-		if sortedItems[i] != expectedItem {
+		if !reflect.DeepEqual(sortedItems[i], expectedItem) {
 			t.Errorf("expected item %#v, got %#v", expectedItem, sortedItems[i])
 		}
 	}
@@ -45,6 +48,12 @@ func checkFeed(t *testing.T, feed feed.Feed, expectedFeed feed.Feed) {
 	if feed.Params != expectedFeed.Params {
 		t.Errorf("expected feed params %v, got %v", expectedFeed.Params, feed.Params)
 	}
+	if feed.ETag != expectedFeed.ETag {
+		t.Errorf("expected ETag %v, got %v", expectedFeed.ETag, feed.ETag)
+	}
+	if feed.LastModified != expectedFeed.LastModified {
+		t.Errorf("expected last modified %v, got %v", expectedFeed.LastModified, feed.LastModified)
+	}
 
 	checkFeedItems(t, feed, expectedFeed.SortedItems())
 }
@@ -378,6 +387,160 @@ func TestListFeedItem(t *testing.T) {
 	}
 }
 
+func TestListItemDiff(t *testing.T) {
+	t.Parallel()
+	feeds := map[string]*feed.Feed{
+		"feed1": {
+			Name: "Feed 1",
+			Type: "xml",
+			URL:  "http://example.com/feed1",
+			Items: map[string]*feed.Item{
+				"item1": {
+					RawItem:   feed.RawItem{URL: "http://example.com/item1", Title: "Item 1 edited"},
+					Timestamp: 1633024800,
+					UpdatedAt: 1633025800,
+					Revisions: []feed.Revision{{Timestamp: 1633024800, Title: "Item 1"}},
+				},
+			},
+		},
+	}
+
+	var tests = []struct {
+		desc     string
+		fuid     string
+		iuid     string
+		from, to int
+		want     *feed.ItemDiff
+	}{{
+		desc: "feed does not exist",
+		fuid: "nonexistent",
+		iuid: "item1",
+		from: -1,
+		to:   -1,
+		want: nil,
+	}, {
+		desc: "item does not exist",
+		fuid: "feed1",
+		iuid: "nonexistent",
+		from: -1,
+		to:   -1,
+		want: nil,
+	}, {
+		desc: "revision does not exist",
+		fuid: "feed1",
+		iuid: "item1",
+		from: 0,
+		to:   2,
+		want: nil,
+	}, {
+		desc: "feed, item and revisions exist",
+		fuid: "feed1",
+		iuid: "item1",
+		from: -1,
+		to:   -1,
+		want: &feed.ItemDiff{
+			From:          0,
+			To:            1,
+			FromTimestamp: 1633024800,
+			ToTimestamp:   1633025800,
+			Title: []feed.DiffLine{
+				{Op: feed.DiffDelete, Text: "Item 1"},
+				{Op: feed.DiffInsert, Text: "Item 1 edited"},
+			},
+			Authors: []feed.DiffLine{{Op: feed.DiffEqual, Text: ""}},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			l, err := mem.NewList(mem.ListParams{})
+			if err != nil {
+				t.Fatalf("failed to create list: %v", err)
+			}
+			mem.SetFeedsMap(l, feeds)
+			got := l.ItemDiff(test.fuid, test.iuid, test.from, test.to)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected item diff %#v, got %#v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestListItemReadable(t *testing.T) {
+	t.Parallel()
+	var fetched []string
+	mockContentFetcher := func(ctx context.Context, pageURL, contentSelector string) (string, error) {
+		fetched = append(fetched, pageURL)
+		if strings.Contains(pageURL, "broken") {
+			return "", errors.New("cannot find content in page")
+		}
+		return "<p>Content of " + pageURL + "</p>", nil
+	}
+
+	l, err := mem.NewList(mem.ListParams{ContentFetcher: mockContentFetcher})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	mem.SetFeedsMap(l, map[string]*feed.Feed{
+		"feed1": {
+			Name: "Feed 1",
+			Type: "xml",
+			URL:  "http://example.com/feed1",
+			Items: map[string]*feed.Item{
+				"item1": {RawItem: feed.RawItem{URL: "http://example.com/item1", Title: "Item 1"}},
+				"item2": {RawItem: feed.RawItem{URL: "http://example.com/broken", Title: "Item 2"}},
+			},
+		},
+	})
+	ctx := context.Background()
+
+	readable, err := l.ItemReadable(ctx, "feed1", "nonexistent")
+	if readable != nil || err != nil {
+		t.Errorf("expected no readable content and no error for missing item, got %v, %v", readable, err)
+	}
+
+	// The content is fetched on the first request and cached afterwards.
+	for range 2 {
+		readable, err = l.ItemReadable(ctx, "feed1", "item1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if readable.URL != "http://example.com/item1" || readable.Content != "<p>Content of http://example.com/item1</p>" || readable.FetchedAt == 0 {
+			t.Errorf("unexpected readable content %#v", readable)
+		}
+	}
+	if !reflect.DeepEqual(fetched, []string{"http://example.com/item1"}) {
+		t.Errorf("expected a single fetch, got %v", fetched)
+	}
+	if cached := mem.FeedsMap(l)["feed1"].Items["item1"].Readable; !reflect.DeepEqual(cached, readable) {
+		t.Errorf("expected cached readable content %#v, got %#v", readable, cached)
+	}
+
+	// The cache is not used once the URL of the item changes.
+	mem.FeedsMap(l)["feed1"].Items["item1"].URL = "http://example.com/item1-moved"
+	readable, err = l.ItemReadable(ctx, "feed1", "item1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if readable.URL != "http://example.com/item1-moved" {
+		t.Errorf("expected content fetched from the new URL, got %#v", readable)
+	}
+
+	// Failures are not cached.
+	for range 2 {
+		readable, err = l.ItemReadable(ctx, "feed1", "item2")
+		if readable != nil || err == nil || err.Error() != "cannot find content in page" {
+			t.Errorf("expected error, got %v, %v", readable, err)
+		}
+	}
+	if mem.FeedsMap(l)["feed1"].Items["item2"].Readable != nil {
+		t.Errorf("expected no cached readable content for item that failed")
+	}
+	if len(fetched) != 4 {
+		t.Errorf("expected 4 fetches, got %v", fetched)
+	}
+}
+
 func TestAllFeed(t *testing.T) {
 	t.Parallel()
 	now := timeutil.Now()
@@ -583,7 +746,7 @@ func TestAllFeed(t *testing.T) {
 					t.Errorf("expected %d items, got %d", len(test.expectedSummary.Items), len(summary.Items))
 				}
 				for i, expectedItem := range test.expectedSummary.Items {
-					if *summary.Items[i] != *expectedItem {
+					if !reflect.DeepEqual(*summary.Items[i], *expectedItem) {
 						t.Errorf("expected item %#v, got %#v", expectedItem, summary.Items[i])
 					}
 				}
@@ -944,6 +1107,20 @@ func TestListLoadFeeds(t *testing.T) {
 		},
 		inputFeeds:    []*list.InputFeed{},
 		expectedFeeds: map[string]*feed.Feed{},
+	}, {
+		desc: "list has 2 feeds with cache validators, and inputFields changes the params of one of them",
+		initialFeeds: map[string]*feed.Feed{
+			feed.UID("http://example.com/feed1"): {Name: "Feed 1", URL: "http://example.com/feed1", Type: "xml", ETag: `"v1"`, LastModified: "yesterday"},
+			feed.UID("http://example.com/feed2"): {Name: "Feed 2", URL: "http://example.com/feed2", Type: "xml", ETag: `"v2"`, LastModified: "yesterday"},
+		},
+		inputFeeds: []*list.InputFeed{
+			{Name: "Feed 1 - Updated", URL: "http://example.com/feed1", Type: "xml"},
+			{Name: "Feed 2", URL: "http://example.com/feed2", Type: "xml", Params: "abc"},
+		},
+		expectedFeeds: map[string]*feed.Feed{
+			feed.UID("http://example.com/feed1"): {Name: "Feed 1 - Updated", URL: "http://example.com/feed1", Type: "xml", ETag: `"v1"`, LastModified: "yesterday"},
+			feed.UID("http://example.com/feed2"): {Name: "Feed 2", URL: "http://example.com/feed2", Type: "xml", Params: "abc"},
+		},
 	}}
 
 	for _, test := range tests {
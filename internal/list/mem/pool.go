@@ -0,0 +1,197 @@
+package mem
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRefreshConcurrency is the maximum number of feeds fetched at
+	// once if none is given.
+	defaultRefreshConcurrency = 8
+
+	// defaultHostConcurrency is the maximum number of feeds fetched at once
+	// from the same host if none is given.
+	defaultHostConcurrency = 2
+)
+
+// fetchPool fetches feeds with bounded concurrency: at most concurrency feeds
+// are fetched at once, at most hostConcurrency of them from the same host, and
+// requests to the same host start at least hostDelay apart.
+type fetchPool struct {
+	concurrency     int
+	hostConcurrency int
+	hostDelay       time.Duration
+
+	// hosts holds the state of the requests to each host that is in use or
+	// was recently requested. It is protected by muHosts.
+	hosts   map[string]*hostState
+	muHosts sync.Mutex
+}
+
+// hostState is the state of the requests to a host.
+type hostState struct {
+	// mu is held while waiting for a request to the host to start, so
+	// requests start one at a time.
+	mu sync.Mutex
+	// last is the time when the last request to the host started.
+	last time.Time
+	// users is the number of callers using the state. It is protected by
+	// fetchPool.muHosts.
+	users int
+}
+
+// acquireHost returns the state of the requests to the given host, which must
+// be released with releaseHost once no longer needed.
+func (p *fetchPool) acquireHost(host string) *hostState {
+	p.muHosts.Lock()
+	defer p.muHosts.Unlock()
+	if p.hosts == nil {
+		p.hosts = make(map[string]*hostState)
+	}
+	h := p.hosts[host]
+	if h == nil {
+		h = &hostState{}
+		p.hosts[host] = h
+	}
+	h.users++
+	return h
+}
+
+// releaseHost releases the state of the requests to the given host. The
+// states of hosts that are not in use and whose delay has passed are
+// forgotten, so the pool does not grow with every host ever requested.
+func (p *fetchPool) releaseHost(host string) {
+	p.muHosts.Lock()
+	defer p.muHosts.Unlock()
+	p.hosts[host].users--
+	for name, h := range p.hosts {
+		if h.users > 0 {
+			continue
+		}
+		// h.mu is not held by anyone since h has no users, and no one can
+		// start using h while muHosts is held.
+		h.mu.Lock()
+		stale := time.Since(h.last) >= p.hostDelay
+		h.mu.Unlock()
+		if stale {
+			delete(p.hosts, name)
+		}
+	}
+}
+
+// wait waits until a request to the host of rawURL may start, that is, until
+// hostDelay has passed since the last request to the host started. It is used
+// for the additional requests made while fetching a feed (e.g., for more
+// pages), which are made one at a time by the fetch, so they count against
+// its concurrency limits instead of taking a slot of their own. It returns an
+// error if ctx is canceled in the meantime.
+func (p *fetchPool) wait(ctx context.Context, rawURL string) error {
+	host := hostOf(rawURL)
+	h := p.acquireHost(host)
+	defer p.releaseHost(host)
+	return p.delay(ctx, h)
+}
+
+// delay waits until hostDelay has passed since the last request to the host of
+// h started, and records the start of a new request. It returns an error if
+// ctx is canceled in the meantime.
+func (p *fetchPool) delay(ctx context.Context, h *hostState) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(h.last.Add(p.hostDelay)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	}
+	h.last = time.Now()
+	return nil
+}
+
+// run calls fetch for each of the pending fetches within the limits of the
+// pool, and waits for all of them to finish. Fetches that have not started
+// yet are skipped once ctx is canceled.
+func (p *fetchPool) run(ctx context.Context, pending []*pendingFetch, fetch func(pf *pendingFetch)) {
+	queuedAt := time.Now()
+	byHost := make(map[string][]*pendingFetch)
+	for _, pf := range pending {
+		host := hostOf(pf.params.URL)
+		byHost[host] = append(byHost[host], pf)
+	}
+
+	sem := make(chan struct{}, p.concurrency)
+	wg := sync.WaitGroup{}
+	for host, fetches := range byHost {
+		queue := make(chan *pendingFetch, len(fetches))
+		for _, pf := range fetches {
+			queue <- pf
+		}
+		close(queue)
+
+		h := p.acquireHost(host)
+		for range min(p.hostConcurrency, len(fetches)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for pf := range queue {
+					// The slot is taken before waiting for the host, since
+					// running fetches hold their slots while waiting for
+					// the host in their additional requests. Holding h.mu
+					// while waiting for a slot would deadlock them.
+					if !p.acquire(ctx, sem) {
+						return
+					}
+					if err := p.delay(ctx, h); err != nil {
+						<-sem
+						return
+					}
+
+					slog.Info("dequeued feed fetch",
+						slog.String("feedName", pf.params.FeedName),
+						slog.String("host", host),
+						slog.Duration("queued", time.Since(queuedAt)),
+					)
+					fetch(pf)
+					<-sem
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	for host := range byHost {
+		p.releaseHost(host)
+	}
+}
+
+// acquire waits for a slot in sem, returning false if ctx is canceled in the
+// meantime.
+func (p *fetchPool) acquire(ctx context.Context, sem chan struct{}) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case sem <- struct{}{}:
+	}
+	// Both cases may have been ready in the select above.
+	if ctx.Err() != nil {
+		<-sem
+		return false
+	}
+	return true
+}
+
+// hostOf returns the host of the given URL, or an empty string if it cannot
+// be parsed.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -2,46 +2,201 @@ package mem
 
 import (
 	"log/slog"
-	"sync"
+	"maps"
+	"reflect"
+	"slices"
 	"time"
 
+	"github.com/alnvdl/varys/internal/feed"
 	"github.com/alnvdl/varys/internal/fetch"
+	"github.com/alnvdl/varys/internal/timeutil"
 )
 
 // Refresh fetches all feeds in the list and then refreshes them. The auto flag
 // indicates whether this refresh was triggered automatically or manually.
+// Automatic refreshes skip feeds that are backing off after failures.
 func (l *List) Refresh(auto bool) {
 	if !auto {
 		defer l.delayAutoSave()
 	}
 	l.muFeeds.Lock()
-	defer l.muFeeds.Unlock()
+	uids := slices.Collect(maps.Keys(l.feeds))
+	l.muFeeds.Unlock()
+
+	l.refresh(uids, auto)
+}
+
+// pendingFetch is a feed being fetched in a refresh.
+type pendingFetch struct {
+	feed   *feed.Feed
+	params fetch.FetchParams
+	res    *feed.FetchResult
+	err    error
+
+	// icon is the icon of the feed when the fetch started, and newIcon is
+	// the icon fetched along with the feed, if it had to be fetched.
+	icon    *feed.Icon
+	newIcon *feed.Icon
+}
 
-	wg := sync.WaitGroup{}
+// refresh fetches the feeds with the given UIDs and then refreshes them,
+// scheduling their next refresh. Feeds are fetched and parsed without holding
+// muFeeds, so the list remains usable while slow feeds are fetched, and only
+// merging the results into the feeds is serialized. Refreshes never overlap,
+// so a feed is not fetched twice at once.
+func (l *List) refresh(uids []string, auto bool) {
+	l.muRefresh.Lock()
+	defer l.muRefresh.Unlock()
 
 	slog.Info("refreshing feeds",
 		slog.Bool("auto", auto),
-		slog.Int("feedCount", len(l.feeds)),
+		slog.Int("feedCount", len(uids)),
 	)
-	for _, feed := range l.feeds {
-		wg.Add(1)
-		go func() {
-			feed.Refresh(l.fetcher(fetch.FetchParams{
-				URL:        feed.URL,
-				FeedName:   feed.Name,
-				FeedType:   feed.Type,
-				FeedParams: feed.Params,
-			}))
-			wg.Done()
-		}()
+	start := time.Now()
+
+	pending := l.pendingFetches(uids, auto)
+	l.pool.run(l.ctx, pending, func(pf *pendingFetch) {
+		pf.res, pf.err = l.fetcher(l.ctx, pf.params)
+		l.fetchIcon(pf)
+	})
+	if l.ctx.Err() != nil {
+		// Fetches aborted because the list was closed are not failures of
+		// the feeds, so they are not merged.
+		slog.Info("refresh canceled", slog.Bool("auto", auto))
+		return
 	}
+	l.mergeFetches(uids, pending)
+
+	slog.Info("finished refreshing feeds",
+		slog.Bool("auto", auto),
+		slog.Int("fetchCount", len(pending)),
+		slog.Duration("duration", time.Since(start)),
+	)
 
-	wg.Wait()
 	if l.refreshCallback != nil {
 		l.refreshCallback()
 	}
 }
 
+// pendingFetches returns the fetches needed to refresh the feeds with the
+// given UIDs. Feeds that are backing off are skipped in automatic refreshes.
+func (l *List) pendingFetches(uids []string, auto bool) []*pendingFetch {
+	l.muFeeds.Lock()
+	defer l.muFeeds.Unlock()
+
+	var pending []*pendingFetch
+	now := timeutil.Now()
+	for _, uid := range uids {
+		f, ok := l.feeds[uid]
+		if !ok {
+			continue
+		}
+		if auto && f.NextAttemptAt > now {
+			slog.Info("skipping feed that is backing off",
+				slog.String("feedName", f.Name),
+				slog.Int64("nextAttemptAt", f.NextAttemptAt),
+			)
+			continue
+		}
+		knownItems := make(map[string]bool, len(f.Items))
+		for iuid, item := range f.Items {
+			if !item.NeedsFullContent() {
+				knownItems[iuid] = true
+			}
+		}
+		pending = append(pending, &pendingFetch{
+			feed: f,
+			params: fetch.FetchParams{
+				URL:          f.URL,
+				FeedName:     f.Name,
+				FeedType:     f.Type,
+				FeedParams:   f.Params,
+				ETag:         f.ETag,
+				LastModified: f.LastModified,
+				KnownItems:   knownItems,
+				Wait:         l.pool.wait,
+			},
+			icon: f.Icon,
+		})
+	}
+	return pending
+}
+
+// fetchIcon fetches the icon declared in the metadata of a successful fetch if
+// the cached icon of the feed must be refreshed. Failures are recorded as
+// icons without data, so they are not retried on every refresh.
+func (l *List) fetchIcon(pf *pendingFetch) {
+	if pf.err != nil || pf.res == nil || pf.res.Metadata == nil {
+		return
+	}
+	iconURL := pf.res.Metadata.IconURL
+	now := timeutil.Now()
+	if !pf.icon.NeedsRefresh(iconURL, now) {
+		return
+	}
+	icon, err := l.iconFetcher(l.ctx, iconURL)
+	if err != nil {
+		slog.Info("cannot fetch feed icon",
+			slog.String("feedName", pf.params.FeedName),
+			slog.String("iconURL", iconURL),
+			slog.String("err", err.Error()),
+		)
+		icon = &feed.Icon{URL: iconURL, FetchedAt: now}
+	}
+	pf.newIcon = icon
+}
+
+// mergeFetches refreshes the feeds with the results of the given fetches and
+// schedules the next refresh of the feeds with the given UIDs. Results for
+// feeds that were removed from the list or reconfigured while being fetched
+// are discarded, and reconfigured feeds are scheduled to be refreshed again
+// right away.
+func (l *List) mergeFetches(uids []string, pending []*pendingFetch) {
+	l.muFeeds.Lock()
+	defer l.muFeeds.Unlock()
+
+	refreshedAt := time.Now()
+	stale := make(map[string]bool)
+	for _, pf := range pending {
+		uid := pf.feed.UID()
+		if l.feeds[uid] != pf.feed {
+			slog.Info("discarding fetch of feed removed during refresh",
+				slog.String("feedName", pf.params.FeedName),
+			)
+			continue
+		}
+		if pf.feed.Type != pf.params.FeedType || !reflect.DeepEqual(pf.feed.Params, pf.params.FeedParams) {
+			slog.Info("discarding fetch of feed changed during refresh",
+				slog.String("feedName", pf.params.FeedName),
+			)
+			stale[uid] = true
+			continue
+		}
+		pf.feed.Refresh(pf.res, pf.err)
+		if pf.newIcon != nil {
+			pf.feed.Icon = pf.newIcon
+		}
+	}
+
+	for _, uid := range uids {
+		f, ok := l.feeds[uid]
+		if !ok {
+			continue
+		}
+		if stale[uid] {
+			l.scheduleFeed(uid, refreshedAt)
+			continue
+		}
+		interval := l.refreshInterval
+		if l.maxRefreshInterval > 0 {
+			if d := f.AdaptiveInterval(l.minRefreshInterval, l.maxRefreshInterval); d > 0 {
+				interval = d
+			}
+		}
+		l.scheduleFeed(uid, f.ScheduleRefresh(refreshedAt, interval, l.refreshJitter))
+	}
+}
+
 func (l *List) initRefresh() {
 	slog.Info("running initial feed refresh")
 	l.Refresh(true)
@@ -53,6 +208,8 @@ func (l *List) initRefresh() {
 	}()
 }
 
+// autoRefresh runs the refresh scheduler: it waits until the next feed in the
+// schedule is due, and then refreshes all feeds that are due at that time.
 func (l *List) autoRefresh() {
 	if l.refreshInterval == 0 {
 		slog.Info("auto-refresh disabled")
@@ -64,14 +221,38 @@ func (l *List) autoRefresh() {
 	)
 	log.Info("auto-refresh enabled")
 	for {
+		l.muFeeds.Lock()
+		next, ok := l.nextDue(time.Now())
+		l.muFeeds.Unlock()
+
+		wait := l.refreshInterval
+		if ok {
+			wait = time.Until(next)
+		}
+		timer := time.NewTimer(wait)
+
 		select {
-		case <-l.close:
+		case <-l.ctx.Done():
+			timer.Stop()
 			log.Info("stopping auto-refresh")
 			return
-		case <-time.After(l.refreshInterval):
-			log.Info("auto-refresh interval reached")
-			l.Refresh(true)
-			log.Info("auto-refresh completed")
+		case <-l.scheduleChanged:
+			timer.Stop()
+		case <-timer.C:
+			if l.ctx.Err() != nil {
+				// The timer may have fired together with the list being
+				// closed.
+				log.Info("stopping auto-refresh")
+				return
+			}
+			l.muFeeds.Lock()
+			uids := l.popDue(time.Now())
+			l.muFeeds.Unlock()
+			if len(uids) > 0 {
+				log.Info("feeds due for refresh", slog.Int("feedCount", len(uids)))
+				l.refresh(uids, true)
+				log.Info("auto-refresh completed")
+			}
 		}
 	}
 }
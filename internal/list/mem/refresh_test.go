@@ -1,7 +1,16 @@
 package mem_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,20 +25,26 @@ func TestListRefresh(t *testing.T) {
 	t.Parallel()
 	now := timeutil.Now()
 
-	mockFetcher := func(p fetch.FetchParams) ([]feed.RawItem, int64, error) {
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
 		switch p.URL {
 		case "http://example.com/feed1":
-			return []feed.RawItem{
-				{URL: "http://example.com/item1", Title: "Item 1"},
-			}, now, nil
+			return &feed.FetchResult{
+				Items: []feed.RawItem{
+					{URL: "http://example.com/item1", Title: "Item 1"},
+				},
+				Timestamp: now,
+			}, nil
 		case "http://example.com/feed2":
-			return []feed.RawItem{
-				{URL: "http://example.com/item2", Title: "Item 2"},
-			}, now, nil
+			return &feed.FetchResult{
+				Items: []feed.RawItem{
+					{URL: "http://example.com/item2", Title: "Item 2"},
+				},
+				Timestamp: now,
+			}, nil
 		case "http://example.com/feed3":
-			return nil, 0, errors.New("oh no")
+			return nil, errors.New("oh no")
 		default:
-			return nil, 0, errors.New("unknown feed URL")
+			return nil, errors.New("unknown feed URL")
 		}
 	}
 
@@ -168,11 +183,11 @@ func TestAutoRefresh(t *testing.T) {
 		},
 	}
 
-	mockFetcher := func(p fetch.FetchParams) ([]feed.RawItem, int64, error) {
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
 		if items, ok := mockResponses[p.URL]; ok {
-			return items, now, nil
+			return &feed.FetchResult{Items: items, Timestamp: now}, nil
 		}
-		return nil, 0, errors.New("unknown feed URL")
+		return nil, errors.New("unknown feed URL")
 	}
 
 	refreshNotify := make(chan bool, 1)
@@ -326,3 +341,778 @@ func TestAutoRefresh(t *testing.T) {
 
 	l.Close()
 }
+
+func TestListRefreshConditional(t *testing.T) {
+	t.Parallel()
+	now := timeutil.Now()
+
+	var gotParams []fetch.FetchParams
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+		gotParams = append(gotParams, p)
+		if p.ETag == `"v1"` {
+			return &feed.FetchResult{Timestamp: now + 1, NotModified: true, ETag: `"v1"`}, nil
+		}
+		return &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "http://example.com/item1", Title: "Item 1"},
+			},
+			Timestamp: now,
+			ETag:      `"v1"`,
+		}, nil
+	}
+
+	l, err := mem.NewList(mem.ListParams{
+		Fetcher: mockFetcher,
+		InitialFeeds: []*list.InputFeed{{
+			Name: "Feed 1",
+			URL:  "http://example.com/feed1",
+			Type: "xml",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	l.Refresh(false)
+
+	if len(gotParams) != 2 {
+		t.Fatalf("expected 2 fetches, got %d", len(gotParams))
+	}
+	if gotParams[0].ETag != "" {
+		t.Errorf("expected no ETag in the first fetch, got %v", gotParams[0].ETag)
+	}
+	if gotParams[1].ETag != `"v1"` {
+		t.Errorf("expected ETag %v in the second fetch, got %v", `"v1"`, gotParams[1].ETag)
+	}
+	if len(gotParams[0].KnownItems) != 0 {
+		t.Errorf("expected no known items in the first fetch, got %v", gotParams[0].KnownItems)
+	}
+	expectedKnownItems := map[string]bool{feed.UID("http://example.com/item1"): true}
+	if !reflect.DeepEqual(gotParams[1].KnownItems, expectedKnownItems) {
+		t.Errorf("expected known items %v in the second fetch, got %v", expectedKnownItems, gotParams[1].KnownItems)
+	}
+
+	checkFeed(t, *mem.FeedsMap(l)[feed.UID("http://example.com/feed1")], feed.Feed{
+		Name: "Feed 1",
+		URL:  "http://example.com/feed1",
+		Type: "xml",
+		Items: map[string]*feed.Item{
+			feed.UID("http://example.com/item1"): {
+				RawItem: feed.RawItem{
+					URL:   "http://example.com/item1",
+					Title: "Item 1",
+				},
+				FeedUID:   feed.UID("http://example.com/feed1"),
+				Timestamp: now,
+			},
+		},
+		LastRefreshedAt: now + 1,
+		ETag:            `"v1"`,
+	})
+}
+
+func TestListRefreshFullContentRetry(t *testing.T) {
+	t.Parallel()
+	now := timeutil.Now()
+
+	var gotParams []fetch.FetchParams
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+		gotParams = append(gotParams, p)
+		return &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: "http://example.com/item1", Title: "Item 1"},
+				{URL: "http://example.com/item2", Title: "Item 2", Position: 1, FullContentFailed: true},
+			},
+			Timestamp: now,
+		}, nil
+	}
+
+	l, err := mem.NewList(mem.ListParams{
+		Fetcher: mockFetcher,
+		InitialFeeds: []*list.InputFeed{{
+			Name:   "Feed 1",
+			URL:    "http://example.com/feed1",
+			Type:   "xml",
+			Params: map[string]any{"fetch_full_content": true},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	l.Refresh(false)
+
+	if len(gotParams) != 2 {
+		t.Fatalf("expected 2 fetches, got %d", len(gotParams))
+	}
+	// Item 2 is not known, so fetching its full content is retried.
+	expectedKnownItems := map[string]bool{feed.UID("http://example.com/item1"): true}
+	if !reflect.DeepEqual(gotParams[1].KnownItems, expectedKnownItems) {
+		t.Errorf("expected known items %v in the second fetch, got %v", expectedKnownItems, gotParams[1].KnownItems)
+	}
+	item := mem.FeedsMap(l)[feed.UID("http://example.com/feed1")].Items[feed.UID("http://example.com/item2")]
+	if item.FullContentFailures != 2 {
+		t.Errorf("expected 2 full content failures, got %d", item.FullContentFailures)
+	}
+}
+
+func TestListRefreshBackoff(t *testing.T) {
+	t.Parallel()
+	now := timeutil.Now()
+
+	var muFetched sync.Mutex
+	fetched := make(map[string]bool)
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+		muFetched.Lock()
+		defer muFetched.Unlock()
+		fetched[p.URL] = true
+		return &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: p.URL + "/item1", Title: "Item 1"},
+			},
+			Timestamp: now,
+		}, nil
+	}
+
+	l, err := mem.NewList(mem.ListParams{Fetcher: mockFetcher})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	mem.SetFeedsMap(l, map[string]*feed.Feed{
+		feed.UID("http://example.com/feed1"): {
+			Name:                "Feed 1",
+			URL:                 "http://example.com/feed1",
+			Type:                "xml",
+			ConsecutiveFailures: 2,
+			NextAttemptAt:       now + 3600,
+		},
+		feed.UID("http://example.com/feed2"): {
+			Name:                "Feed 2",
+			URL:                 "http://example.com/feed2",
+			Type:                "xml",
+			ConsecutiveFailures: 2,
+			NextAttemptAt:       now - 1,
+		},
+	})
+
+	// An automatic refresh skips the feed that is backing off.
+	l.Refresh(true)
+	if fetched["http://example.com/feed1"] {
+		t.Errorf("expected feed 1 not to be fetched")
+	}
+	if !fetched["http://example.com/feed2"] {
+		t.Errorf("expected feed 2 to be fetched")
+	}
+	feed2 := mem.FeedsMap(l)[feed.UID("http://example.com/feed2")]
+	if feed2.ConsecutiveFailures != 0 || feed2.NextAttemptAt != 0 {
+		t.Errorf("expected feed 2 backoff to be reset, got %d failures and next attempt at %d", feed2.ConsecutiveFailures, feed2.NextAttemptAt)
+	}
+
+	// A manual refresh fetches all feeds.
+	l.Refresh(false)
+	if !fetched["http://example.com/feed1"] {
+		t.Errorf("expected feed 1 to be fetched")
+	}
+}
+
+func TestAutoRefreshSchedule(t *testing.T) {
+	t.Parallel()
+	now := timeutil.Now()
+
+	var muFetches sync.Mutex
+	fetches := make(map[string]int)
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+		muFetches.Lock()
+		defer muFetches.Unlock()
+		fetches[p.URL]++
+		return &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: p.URL + "/item1", Title: "Item 1"},
+			},
+			Timestamp: now,
+		}, nil
+	}
+
+	refreshNotify := make(chan bool, 1)
+	l, err := mem.NewList(mem.ListParams{
+		InitialFeeds: []*list.InputFeed{{
+			Name:   "Fast feed",
+			URL:    "http://example.com/fast",
+			Type:   "xml",
+			Params: map[string]any{"refresh_interval": "500ms"},
+		}, {
+			Name: "Slow feed",
+			URL:  "http://example.com/slow",
+			Type: "xml",
+		}},
+		RefreshInterval: 1 * time.Hour,
+		Fetcher:         mockFetcher,
+		RefreshCallback: func() {
+			refreshNotify <- true
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	defer l.Close()
+
+	// The first notification comes from the initial refresh, and the
+	// following ones from the fast feed being refreshed on its own schedule.
+	for range 3 {
+		select {
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected refresh to be triggered")
+		case <-refreshNotify:
+		}
+	}
+
+	muFetches.Lock()
+	defer muFetches.Unlock()
+	if fetches["http://example.com/fast"] != 3 {
+		t.Errorf("expected fast feed to be fetched 3 times, got %d", fetches["http://example.com/fast"])
+	}
+	if fetches["http://example.com/slow"] != 1 {
+		t.Errorf("expected slow feed to be fetched once, got %d", fetches["http://example.com/slow"])
+	}
+}
+
+func TestAutoRefreshHints(t *testing.T) {
+	t.Parallel()
+	now := timeutil.Now()
+
+	var muFetches sync.Mutex
+	fetches := make(map[string]int)
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+		muFetches.Lock()
+		defer muFetches.Unlock()
+		fetches[p.URL]++
+		return &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: p.URL + "/item1", Title: "Item 1"},
+			},
+			Timestamp: now,
+			Hints:     &feed.UpdateHints{TTL: 60 * 60},
+		}, nil
+	}
+
+	refreshNotify := make(chan bool, 1)
+	l, err := mem.NewList(mem.ListParams{
+		InitialFeeds: []*list.InputFeed{{
+			Name:   "Polite feed",
+			URL:    "http://example.com/polite",
+			Type:   "xml",
+			Params: map[string]any{"refresh_interval": "500ms"},
+		}, {
+			Name:   "Impolite feed",
+			URL:    "http://example.com/impolite",
+			Type:   "xml",
+			Params: map[string]any{"refresh_interval": "500ms", "ignore_hints": true},
+		}},
+		RefreshInterval: 1 * time.Hour,
+		Fetcher:         mockFetcher,
+		RefreshCallback: func() {
+			refreshNotify <- true
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	defer l.Close()
+
+	// The first notification comes from the initial refresh, and the
+	// following ones from the feed that ignores the TTL hint.
+	for range 3 {
+		select {
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected refresh to be triggered")
+		case <-refreshNotify:
+		}
+	}
+
+	muFetches.Lock()
+	defer muFetches.Unlock()
+	if fetches["http://example.com/polite"] != 1 {
+		t.Errorf("expected polite feed to be fetched once, got %d", fetches["http://example.com/polite"])
+	}
+	if fetches["http://example.com/impolite"] != 3 {
+		t.Errorf("expected impolite feed to be fetched 3 times, got %d", fetches["http://example.com/impolite"])
+	}
+}
+
+// blockingFetcher returns a fetcher that blocks fetches of the given URL
+// once blocked is set, until release is closed. Every fetch of the URL while
+// blocked is signaled on started, and returns a "late" item instead of the
+// usual one.
+func blockingFetcher(url string, blocked *atomic.Bool, started chan<- bool, release <-chan bool) func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+	return func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+		itemURL := p.URL + "/item1"
+		if p.URL == url && blocked.Load() {
+			started <- true
+			<-release
+			itemURL = p.URL + "/late"
+		}
+		return &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: itemURL, Title: "Item 1"},
+			},
+			Timestamp: timeutil.Now(),
+		}, nil
+	}
+}
+
+func TestListRefreshConcurrentReads(t *testing.T) {
+	t.Parallel()
+
+	var blocked atomic.Bool
+	started := make(chan bool, 1)
+	release := make(chan bool)
+	l, err := mem.NewList(mem.ListParams{
+		InitialFeeds: []*list.InputFeed{{
+			Name: "Slow feed",
+			URL:  "http://example.com/slow",
+			Type: "xml",
+		}},
+		Fetcher: blockingFetcher("http://example.com/slow", &blocked, started, release),
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	defer l.Close()
+
+	blocked.Store(true)
+	refreshed := make(chan bool)
+	go func() {
+		l.Refresh(false)
+		refreshed <- true
+	}()
+	<-started
+
+	// The list must remain usable while the slow feed is being fetched.
+	read := make(chan bool)
+	go func() {
+		fuid := feed.UID("http://example.com/slow")
+		l.Summary()
+		l.FeedSummary(fuid)
+		l.FeedItem(fuid, feed.UID("http://example.com/slow/item1"))
+		l.MarkRead(fuid, "", timeutil.Now())
+		read <- true
+	}()
+	select {
+	case <-read:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected reads not to block during refresh")
+	}
+
+	close(release)
+	select {
+	case <-refreshed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected refresh to finish")
+	}
+}
+
+func TestListRefreshSlowFetcher(t *testing.T) {
+	t.Parallel()
+
+	var blocked atomic.Bool
+	started := make(chan bool, 1)
+	release := make(chan bool)
+	l, err := mem.NewList(mem.ListParams{
+		InitialFeeds: []*list.InputFeed{{
+			Name: "Slow feed",
+			URL:  "http://example.com/slow",
+			Type: "xml",
+		}, {
+			Name: "Fast feed",
+			URL:  "http://example.com/fast",
+			Type: "xml",
+		}},
+		Fetcher: blockingFetcher("http://example.com/slow", &blocked, started, release),
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	defer l.Close()
+
+	// Start from feeds that were never refreshed, so it is possible to tell
+	// when the results of the refresh are merged.
+	l.LoadFeeds(nil)
+	l.LoadFeeds([]*list.InputFeed{{
+		Name: "Slow feed",
+		URL:  "http://example.com/slow",
+		Type: "xml",
+	}, {
+		Name: "Fast feed",
+		URL:  "http://example.com/fast",
+		Type: "xml",
+	}})
+
+	blocked.Store(true)
+	refreshed := make(chan bool)
+	go func() {
+		l.Refresh(false)
+		refreshed <- true
+	}()
+	<-started
+
+	// Results are only merged once all fetches are done.
+	for _, summary := range l.Summary() {
+		if summary.ItemCount != 0 {
+			t.Errorf("expected feed %s to have no items during refresh, got %d", summary.Name, summary.ItemCount)
+		}
+	}
+
+	close(release)
+	select {
+	case <-refreshed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected refresh to finish")
+	}
+
+	for _, summary := range l.Summary() {
+		if summary.UID != "all" && summary.ItemCount != 1 {
+			t.Errorf("expected feed %s to have 1 item after refresh, got %d", summary.Name, summary.ItemCount)
+		}
+	}
+}
+
+func TestListRefreshFeedChangedDuringFetch(t *testing.T) {
+	t.Parallel()
+
+	var blocked atomic.Bool
+	started := make(chan bool, 1)
+	release := make(chan bool)
+	l, err := mem.NewList(mem.ListParams{
+		InitialFeeds: []*list.InputFeed{{
+			Name: "Changed feed",
+			URL:  "http://example.com/changed",
+			Type: "xml",
+		}, {
+			Name: "Removed feed",
+			URL:  "http://example.com/removed",
+			Type: "xml",
+		}},
+		Fetcher: blockingFetcher("http://example.com/changed", &blocked, started, release),
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	defer l.Close()
+
+	blocked.Store(true)
+	refreshed := make(chan bool)
+	go func() {
+		l.Refresh(false)
+		refreshed <- true
+	}()
+	<-started
+
+	l.LoadFeeds([]*list.InputFeed{{
+		Name:   "Changed feed",
+		URL:    "http://example.com/changed",
+		Type:   "xml",
+		Params: map[string]any{"max_items": 10},
+	}})
+
+	close(release)
+	select {
+	case <-refreshed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected refresh to finish")
+	}
+
+	feeds := mem.FeedsMap(l)
+	if len(feeds) != 1 {
+		t.Fatalf("expected 1 feed after refresh, got %d", len(feeds))
+	}
+	f := feeds[feed.UID("http://example.com/changed")]
+	if f.Items[feed.UID("http://example.com/changed/late")] != nil {
+		t.Errorf("expected fetch of changed feed to be discarded")
+	}
+}
+
+func TestListRefreshConcurrencyLimits(t *testing.T) {
+	t.Parallel()
+
+	var muFetches sync.Mutex
+	var active, maxActive int
+	activeByHost := make(map[string]int)
+	maxActiveByHost := make(map[string]int)
+	startsByHost := make(map[string][]time.Time)
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+		host := strings.Split(p.URL, "/")[2]
+		muFetches.Lock()
+		active++
+		activeByHost[host]++
+		maxActive = max(maxActive, active)
+		maxActiveByHost[host] = max(maxActiveByHost[host], activeByHost[host])
+		startsByHost[host] = append(startsByHost[host], time.Now())
+		muFetches.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		// Additional requests made by the fetch (e.g., for more pages)
+		// are also subject to the host delay.
+		if err := p.Wait(ctx, p.URL+"?page=2"); err != nil {
+			return nil, err
+		}
+		muFetches.Lock()
+		startsByHost[host] = append(startsByHost[host], time.Now())
+		active--
+		activeByHost[host]--
+		muFetches.Unlock()
+		return &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: p.URL + "/item1", Title: "Item 1"},
+			},
+			Timestamp: timeutil.Now(),
+		}, nil
+	}
+
+	var feeds []*list.InputFeed
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		for i := range 3 {
+			feeds = append(feeds, &list.InputFeed{
+				Name: fmt.Sprintf("Feed %d on %s", i, host),
+				URL:  fmt.Sprintf("http://%s/feed%d", host, i),
+				Type: "xml",
+			})
+		}
+	}
+
+	const hostDelay = 50 * time.Millisecond
+	l, err := mem.NewList(mem.ListParams{
+		InitialFeeds:       feeds,
+		RefreshConcurrency: 2,
+		HostConcurrency:    1,
+		HostDelay:          hostDelay,
+		Fetcher:            mockFetcher,
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	defer l.Close()
+
+	muFetches.Lock()
+	defer muFetches.Unlock()
+	if maxActive > 2 {
+		t.Errorf("expected at most 2 concurrent fetches, got %d", maxActive)
+	}
+	for host, starts := range startsByHost {
+		if len(starts) != 6 {
+			t.Errorf("expected 6 requests to %s, got %d", host, len(starts))
+		}
+		if maxActiveByHost[host] > 1 {
+			t.Errorf("expected at most 1 concurrent fetch from %s, got %d", host, maxActiveByHost[host])
+		}
+		for i := 1; i < len(starts); i++ {
+			// Allow some tolerance for the time between the pool starting
+			// a request and the fetcher recording it.
+			if d := starts[i].Sub(starts[i-1]); d < hostDelay*9/10 {
+				t.Errorf("expected requests to %s to start at least %v apart, got %v", host, hostDelay, d)
+			}
+		}
+	}
+}
+
+func TestListRefreshAdditionalRequests(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc             string
+		params           map[string]any
+		expectedRequests int
+	}{{
+		desc:   "paged feeds",
+		params: map[string]any{"max_pages": 3},
+		// Two feeds with three pages each.
+		expectedRequests: 6,
+	}, {
+		desc:   "feeds with full content",
+		params: map[string]any{"fetch_full_content": true},
+		// Two feeds and the pages of their two items.
+		expectedRequests: 6,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if strings.HasPrefix(r.URL.Path, "/feed") {
+					page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+					page = max(page, 1)
+					w.Write([]byte(fmt.Sprintf(`<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+						<atom:link rel="next" href="%[1]s?page=%[2]d"/>
+						<item><title>Item %[2]d</title><link>http://%[3]s%[1]s/item%[2]da</link></item>
+						<item><title>Item %[2]d</title><link>http://%[3]s%[1]s/item%[2]db</link></item>
+					</channel></rss>`, r.URL.Path, page+1, r.Host)))
+					return
+				}
+				w.Write([]byte(`<html><body><div id="content">Full content</div></body></html>`))
+			}))
+			defer server.Close()
+
+			// Additional requests wait for the host while their fetch holds
+			// one of the slots of the pool, which must not deadlock the
+			// fetches of other feeds of the host waiting for a slot.
+			created := make(chan *mem.List)
+			go func() {
+				l, err := mem.NewList(mem.ListParams{
+					InitialFeeds: []*list.InputFeed{
+						{Name: "Feed 1", URL: server.URL + "/feed1", Type: "xml", Params: test.params},
+						{Name: "Feed 2", URL: server.URL + "/feed2", Type: "xml", Params: test.params},
+					},
+					RefreshConcurrency: 1,
+					HostConcurrency:    2,
+					HostDelay:          time.Millisecond,
+					Fetcher:            fetch.Fetch,
+					IconFetcher: func(ctx context.Context, iconURL string) (*feed.Icon, error) {
+						return nil, errors.New("no icon")
+					},
+				})
+				if err != nil {
+					t.Errorf("failed to create list: %v", err)
+				}
+				created <- l
+			}()
+
+			select {
+			case l := <-created:
+				if l != nil {
+					l.Close()
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("initial refresh did not finish")
+			}
+			if n := requests.Load(); n != int32(test.expectedRequests) {
+				t.Errorf("expected %d requests, got %d", test.expectedRequests, n)
+			}
+		})
+	}
+}
+
+func TestListCloseCancelsFetches(t *testing.T) {
+	t.Parallel()
+
+	var blocked atomic.Bool
+	started := make(chan bool, 1)
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+		if blocked.Load() {
+			select {
+			case started <- true:
+			default:
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: p.URL + "/item1", Title: "Item 1"},
+			},
+			Timestamp: timeutil.Now(),
+		}, nil
+	}
+
+	l, err := mem.NewList(mem.ListParams{
+		InitialFeeds: []*list.InputFeed{{
+			Name:   "Slow feed",
+			URL:    "http://example.com/slow",
+			Type:   "xml",
+			Params: map[string]any{"refresh_interval": "100ms"},
+		}},
+		RefreshInterval: 1 * time.Hour,
+		Fetcher:         mockFetcher,
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+
+	blocked.Store(true)
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected auto-refresh to fetch the feed")
+	}
+
+	closed := make(chan bool)
+	go func() {
+		l.Close()
+		closed <- true
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected Close to cancel the outstanding fetch")
+	}
+
+	f := mem.FeedsMap(l)[feed.UID("http://example.com/slow")]
+	if f.ConsecutiveFailures != 0 || f.LastRefreshError != "" {
+		t.Errorf("expected canceled fetch not to be recorded as a failure, got %q", f.LastRefreshError)
+	}
+}
+
+func TestListRefreshIcons(t *testing.T) {
+	t.Parallel()
+	now := timeutil.Now()
+
+	mockFetcher := func(ctx context.Context, p fetch.FetchParams) (*feed.FetchResult, error) {
+		return &feed.FetchResult{
+			Items: []feed.RawItem{
+				{URL: p.URL + "/item1", Title: "Item 1"},
+			},
+			Timestamp: now,
+			Metadata:  &feed.Metadata{Title: p.FeedName, IconURL: p.URL + "/favicon.ico"},
+		}, nil
+	}
+	var mu sync.Mutex
+	var gotIconURLs []string
+	mockIconFetcher := func(ctx context.Context, iconURL string) (*feed.Icon, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		gotIconURLs = append(gotIconURLs, iconURL)
+		if strings.Contains(iconURL, "broken") {
+			return nil, errors.New("unsupported icon type text/html")
+		}
+		return &feed.Icon{URL: iconURL, MimeType: "image/png", Data: []byte("png"), FetchedAt: now}, nil
+	}
+
+	l, err := mem.NewList(mem.ListParams{
+		Fetcher:     mockFetcher,
+		IconFetcher: mockIconFetcher,
+		InitialFeeds: []*list.InputFeed{{
+			Name: "Feed 1",
+			URL:  "http://example.com/feed1",
+			Type: "xml",
+		}, {
+			Name: "Broken",
+			URL:  "http://example.com/broken",
+			Type: "xml",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	l.Refresh(false)
+
+	// Icons are fetched only once, even after failures.
+	if len(gotIconURLs) != 2 {
+		t.Fatalf("expected 2 icon fetches, got %v", gotIconURLs)
+	}
+
+	icon := l.FeedIcon(feed.UID("http://example.com/feed1"))
+	if icon == nil || icon.URL != "http://example.com/feed1/favicon.ico" || string(icon.Data) != "png" {
+		t.Errorf("expected icon for feed 1, got %#v", icon)
+	}
+	if icon := l.FeedIcon(feed.UID("http://example.com/broken")); icon != nil {
+		t.Errorf("expected no icon for broken feed, got %#v", icon)
+	}
+	if icon := l.FeedIcon("unknown"); icon != nil {
+		t.Errorf("expected no icon for unknown feed, got %#v", icon)
+	}
+
+	summary := l.FeedSummary(feed.UID("http://example.com/feed1"))
+	expectedIconURL := fmt.Sprintf("/api/feeds/%s/icon?v=%d", feed.UID("http://example.com/feed1"), now)
+	if summary.IconURL != expectedIconURL {
+		t.Errorf("expected icon URL %q, got %q", expectedIconURL, summary.IconURL)
+	}
+}
@@ -0,0 +1,99 @@
+package mem
+
+import (
+	"container/heap"
+	"time"
+)
+
+// scheduledFeed is an entry in the refresh schedule.
+type scheduledFeed struct {
+	uid   string
+	due   time.Time
+	index int
+}
+
+// schedule is a priority queue of feeds ordered by the time when they are due
+// to be refreshed. It implements heap.Interface.
+type schedule []*scheduledFeed
+
+func (s schedule) Len() int { return len(s) }
+
+func (s schedule) Less(i, j int) bool { return s[i].due.Before(s[j].due) }
+
+func (s schedule) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index = i
+	s[j].index = j
+}
+
+func (s *schedule) Push(x any) {
+	e := x.(*scheduledFeed)
+	e.index = len(*s)
+	*s = append(*s, e)
+}
+
+func (s *schedule) Pop() any {
+	old := *s
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*s = old[:n-1]
+	return e
+}
+
+// scheduleFeed schedules the feed with the given uid to be refreshed at due,
+// replacing any previous entry for it. It must be called with muFeeds held.
+func (l *List) scheduleFeed(uid string, due time.Time) {
+	if e, ok := l.scheduled[uid]; ok {
+		e.due = due
+		heap.Fix(&l.queue, e.index)
+		return
+	}
+	e := &scheduledFeed{uid: uid, due: due}
+	heap.Push(&l.queue, e)
+	l.scheduled[uid] = e
+}
+
+// nextDue returns the time when the next feed is due to be refreshed, or
+// false if no feeds are scheduled. Feeds that are not yet in the schedule are
+// scheduled to be refreshed at now, and feeds that are no longer in the list
+// are dropped from the schedule. It must be called with muFeeds held.
+func (l *List) nextDue(now time.Time) (time.Time, bool) {
+	for uid := range l.feeds {
+		if _, ok := l.scheduled[uid]; !ok {
+			l.scheduleFeed(uid, now)
+		}
+	}
+	for len(l.queue) > 0 {
+		e := l.queue[0]
+		if _, ok := l.feeds[e.uid]; ok {
+			return e.due, true
+		}
+		heap.Pop(&l.queue)
+		delete(l.scheduled, e.uid)
+	}
+	return time.Time{}, false
+}
+
+// popDue removes the feeds that are due to be refreshed at now from the
+// schedule and returns their UIDs. It must be called with muFeeds held.
+func (l *List) popDue(now time.Time) []string {
+	var uids []string
+	for len(l.queue) > 0 && !l.queue[0].due.After(now) {
+		e := heap.Pop(&l.queue).(*scheduledFeed)
+		delete(l.scheduled, e.uid)
+		if _, ok := l.feeds[e.uid]; ok {
+			uids = append(uids, e.uid)
+		}
+	}
+	return uids
+}
+
+// notifyScheduleChanged wakes up the auto-refresh loop so it can take changes
+// to the feed list into account.
+func (l *List) notifyScheduleChanged() {
+	select {
+	case l.scheduleChanged <- struct{}{}:
+	default:
+	}
+}
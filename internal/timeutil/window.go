@@ -0,0 +1,126 @@
+package timeutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a recurring weekly time window, such as "weekdays 08:00-18:00".
+type Window struct {
+	days [7]bool
+	// start and end are offsets in minutes from midnight. If end is not after
+	// start, the window extends past midnight into the next day.
+	start int
+	end   int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseWindow parses a window in the form "<days> [HH:MM-HH:MM]". The days
+// can be "daily", "weekdays", "weekends" or a comma-separated list of
+// three-letter day names and ranges (e.g., "mon-wed,fri"). If the time range
+// is omitted, the window spans whole days. If the end of the time range is
+// not after its start, the window extends past midnight.
+func ParseWindow(s string) (*Window, error) {
+	fields := strings.Fields(strings.ReplaceAll(strings.ToLower(s), "–", "-"))
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, errors.New("window must be in the form <days> [HH:MM-HH:MM]")
+	}
+
+	w := &Window{}
+	switch fields[0] {
+	case "daily":
+		for d := range w.days {
+			w.days[d] = true
+		}
+	case "weekdays":
+		for d := time.Monday; d <= time.Friday; d++ {
+			w.days[d] = true
+		}
+	case "weekends":
+		w.days[time.Saturday] = true
+		w.days[time.Sunday] = true
+	default:
+		for _, part := range strings.Split(fields[0], ",") {
+			from, to, isRange := strings.Cut(part, "-")
+			fromDay, ok := weekdays[from]
+			if !ok {
+				return nil, fmt.Errorf("invalid day: %s", from)
+			}
+			toDay := fromDay
+			if isRange {
+				if toDay, ok = weekdays[to]; !ok {
+					return nil, fmt.Errorf("invalid day: %s", to)
+				}
+			}
+			for d := fromDay; ; d = (d + 1) % 7 {
+				w.days[d] = true
+				if d == toDay {
+					break
+				}
+			}
+		}
+	}
+
+	if len(fields) == 2 {
+		from, to, ok := strings.Cut(fields[1], "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid time range: %s", fields[1])
+		}
+		var err error
+		if w.start, err = parseClock(from); err != nil {
+			return nil, err
+		}
+		if w.end, err = parseClock(to); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// parseClock parses a time of the day in the form HH:MM, returning the number
+// of minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time: %s", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Next returns t if it falls within the window, or otherwise the time when the
+// window opens next after t.
+func (w *Window) Next(t time.Time) time.Time {
+	y, m, d := t.Date()
+	// Starting from the previous day accounts for windows extending past
+	// midnight.
+	for i := -1; i <= 7; i++ {
+		start := time.Date(y, m, d+i, 0, w.start, 0, 0, t.Location())
+		if !w.days[start.Weekday()] {
+			continue
+		}
+		end := time.Date(y, m, d+i, 0, w.end, 0, 0, t.Location())
+		if w.end <= w.start {
+			end = time.Date(y, m, d+i+1, 0, w.end, 0, 0, t.Location())
+		}
+		if t.Before(start) {
+			return start
+		}
+		if t.Before(end) {
+			return t
+		}
+	}
+	// Only reachable if no days are set, which ParseWindow never does.
+	return t
+}
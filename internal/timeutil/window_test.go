@@ -0,0 +1,132 @@
+package timeutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alnvdl/varys/internal/timeutil"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		desc   string
+		window string
+		err    string
+	}{{
+		desc:   "daily",
+		window: "daily",
+	}, {
+		desc:   "weekdays with time range",
+		window: "weekdays 08:00-18:00",
+	}, {
+		desc:   "weekends with en dash",
+		window: "weekends 08:00–18:00",
+	}, {
+		desc:   "days and ranges",
+		window: "mon-wed,Fri 22:00-02:00",
+	}, {
+		desc:   "error: empty",
+		window: "",
+		err:    "window must be in the form <days> [HH:MM-HH:MM]",
+	}, {
+		desc:   "error: too many fields",
+		window: "daily 08:00-18:00 UTC",
+		err:    "window must be in the form <days> [HH:MM-HH:MM]",
+	}, {
+		desc:   "error: invalid day",
+		window: "mon-someday",
+		err:    "invalid day: someday",
+	}, {
+		desc:   "error: missing end time",
+		window: "daily 08:00",
+		err:    "invalid time range: 08:00",
+	}, {
+		desc:   "error: invalid time",
+		window: "daily 08:00-25:00",
+		err:    "invalid time: 25:00",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := timeutil.ParseWindow(test.window)
+			if test.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != "" && (err == nil || err.Error() != test.err) {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestWindowNext(t *testing.T) {
+	// 2025-01-06 is a Monday.
+	date := func(day, hour, min int) time.Time {
+		return time.Date(2025, 1, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		desc     string
+		window   string
+		t        time.Time
+		expected time.Time
+	}{{
+		desc:     "daily, any time",
+		window:   "daily",
+		t:        date(6, 3, 0),
+		expected: date(6, 3, 0),
+	}, {
+		desc:     "weekdays, inside the window",
+		window:   "weekdays 08:00-18:00",
+		t:        date(6, 9, 30),
+		expected: date(6, 9, 30),
+	}, {
+		desc:     "weekdays, before the window",
+		window:   "weekdays 08:00-18:00",
+		t:        date(6, 7, 0),
+		expected: date(6, 8, 0),
+	}, {
+		desc:     "weekdays, after the window",
+		window:   "weekdays 08:00-18:00",
+		t:        date(6, 18, 0),
+		expected: date(7, 8, 0),
+	}, {
+		desc:     "weekdays, on Friday night",
+		window:   "weekdays 08:00-18:00",
+		t:        date(10, 19, 0),
+		expected: date(13, 8, 0),
+	}, {
+		desc:     "weekends, on a weekday",
+		window:   "weekends",
+		t:        date(8, 12, 0),
+		expected: date(11, 0, 0),
+	}, {
+		desc:     "window past midnight, after midnight",
+		window:   "mon 22:00-02:00",
+		t:        date(7, 1, 0),
+		expected: date(7, 1, 0),
+	}, {
+		desc:     "window past midnight, after the window",
+		window:   "mon 22:00-02:00",
+		t:        date(7, 3, 0),
+		expected: date(13, 22, 0),
+	}, {
+		desc:     "wrapping day range",
+		window:   "sat-mon 10:00-11:00",
+		t:        date(7, 12, 0),
+		expected: date(11, 10, 0),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			w, err := timeutil.ParseWindow(test.window)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := w.Next(test.t)
+			if !result.Equal(test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
@@ -4,10 +4,14 @@ package web
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -28,12 +32,18 @@ type FeedLister interface {
 	Summary() []*feed.FeedSummary
 	FeedSummary(uid string) *feed.FeedSummary
 	FeedItem(fuid, iuid string) *feed.ItemSummary
+	ItemDiff(fuid, iuid string, from, to int) *feed.ItemDiff
+	ItemReadable(ctx context.Context, fuid, iuid string) (*feed.Readable, error)
+	FeedIcon(fuid string) *feed.Icon
 	MarkRead(fuid, iuid string, before int64) bool
 }
 
 // HandlerParams contains the parameters for creating a new API server.
 type HandlerParams struct {
-	FeedList    FeedLister
+	FeedList FeedLister
+	// Discover finds the feeds of the website at siteURL. If nil, feed
+	// discovery is not available.
+	Discover    func(ctx context.Context, siteURL string) ([]feed.DiscoveredFeed, error)
 	AccessToken string
 	SessionKey  []byte
 }
@@ -75,6 +85,11 @@ func NewHandler(p *HandlerParams) *handler {
 		path:    "/api/feeds/{fuid}",
 		handler: h.feed,
 		authn:   true,
+	}, {
+		method:  "GET",
+		path:    "/api/feeds/{fuid}/icon",
+		handler: h.icon,
+		authn:   true,
 	}, {
 		method:  "POST",
 		path:    "/api/feeds/{fuid}/read",
@@ -85,11 +100,26 @@ func NewHandler(p *HandlerParams) *handler {
 		path:    "/api/feeds/{fuid}/items/{iuid}",
 		handler: h.item,
 		authn:   true,
+	}, {
+		method:  "GET",
+		path:    "/api/feeds/{fuid}/items/{iuid}/diff",
+		handler: h.itemDiff,
+		authn:   true,
+	}, {
+		method:  "GET",
+		path:    "/api/feeds/{fuid}/items/{iuid}/readable",
+		handler: h.itemReadable,
+		authn:   true,
 	}, {
 		method:  "POST",
 		path:    "/api/feeds/{fuid}/items/{iuid}/read",
 		handler: h.read,
 		authn:   true,
+	}, {
+		method:  "GET",
+		path:    "/api/discover",
+		handler: h.discover,
+		authn:   true,
 	}, {
 		method:  "GET",
 		path:    "/status",
@@ -194,6 +224,23 @@ func (s *handler) feed(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, feed)
 }
 
+func (s *handler) icon(w http.ResponseWriter, r *http.Request) {
+	fuid := r.PathValue("fuid")
+	icon := s.p.FeedList.FeedIcon(fuid)
+	if icon == nil {
+		writeErrorResponse(w, http.StatusNotFound, "icon not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", icon.MimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Icons may be SVG documents, which must not be able to run scripts.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	// Icon URLs change when icons are fetched again.
+	w.Header().Set("Cache-Control", "private, max-age=604800")
+	w.Write(icon.Data)
+}
+
 func (s *handler) item(w http.ResponseWriter, r *http.Request) {
 	fuid := r.PathValue("fuid")
 	iuid := r.PathValue("iuid")
@@ -206,6 +253,62 @@ func (s *handler) item(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, item)
 }
 
+func (s *handler) itemDiff(w http.ResponseWriter, r *http.Request) {
+	fuid := r.PathValue("fuid")
+	iuid := r.PathValue("iuid")
+
+	from, ok := revisionParam(r, "from")
+	if !ok {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid from revision")
+		return
+	}
+	to, ok := revisionParam(r, "to")
+	if !ok {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid to revision")
+		return
+	}
+
+	diff := s.p.FeedList.ItemDiff(fuid, iuid, from, to)
+	if diff == nil {
+		writeErrorResponse(w, http.StatusNotFound, "item or revision not found")
+		return
+	}
+
+	jsonResponse(w, diff)
+}
+
+func (s *handler) itemReadable(w http.ResponseWriter, r *http.Request) {
+	fuid := r.PathValue("fuid")
+	iuid := r.PathValue("iuid")
+
+	readable, err := s.p.FeedList.ItemReadable(r.Context(), fuid, iuid)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadGateway, fmt.Sprintf("cannot fetch readable content: %v", err))
+		return
+	}
+	if readable == nil {
+		writeErrorResponse(w, http.StatusNotFound, "item not found")
+		return
+	}
+
+	jsonResponse(w, readable)
+}
+
+// revisionParam returns the revision number in the query parameter with the
+// given name, or -1 if it is not set. It returns false if the parameter is
+// not a valid revision number.
+func revisionParam(r *http.Request, name string) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return -1, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
 func (s *handler) read(w http.ResponseWriter, r *http.Request) {
 	fuid := r.PathValue("fuid")
 	iuid := r.PathValue("iuid")
@@ -231,17 +334,50 @@ func (s *handler) read(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+func (s *handler) discover(w http.ResponseWriter, r *http.Request) {
+	if s.p.Discover == nil {
+		writeErrorResponse(w, http.StatusNotImplemented, "feed discovery is not available")
+		return
+	}
+	siteURL := r.URL.Query().Get("url")
+	if u, err := url.Parse(siteURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid site URL")
+		return
+	}
+
+	feeds, err := s.p.Discover(r.Context(), siteURL)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadGateway, fmt.Sprintf("cannot discover feeds: %v", err))
+		return
+	}
+	if feeds == nil {
+		feeds = []feed.DiscoveredFeed{}
+	}
+
+	jsonResponse(w, feeds)
+}
+
 type statusResponse struct {
 	Status  string `json:"status"`
 	Version string `json:"version"`
 }
 
-func (s *handler) status(w http.ResponseWriter, r *http.Request) {
+// Version returns the version of the application as recorded in the embedded
+// static version file.
+func Version() (string, error) {
 	bVersion, err := staticFiles.ReadFile("static/version")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bVersion)), nil
+}
+
+func (s *handler) status(w http.ResponseWriter, r *http.Request) {
+	version, err := Version()
 	if err != nil {
 		writeErrorResponse(w, http.StatusInternalServerError, "cannot read version file")
+		return
 	}
-	version := strings.TrimSpace(string(bVersion))
 
 	jsonResponse(w, statusResponse{
 		Status:  "ok",
@@ -2,10 +2,13 @@ package web_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
@@ -17,6 +20,11 @@ import (
 
 type mockFeedLister struct {
 	feeds []*feed.FeedSummary
+	items map[string]*feed.Item
+	icons map[string]*feed.Icon
+
+	readables    map[string]*feed.Readable
+	readableErrs map[string]error
 }
 
 func (m *mockFeedLister) Summary() []*feed.FeedSummary {
@@ -45,6 +53,25 @@ func (m *mockFeedLister) FeedItem(fuid, iuid string) *feed.ItemSummary {
 	return nil
 }
 
+func (m *mockFeedLister) ItemDiff(fuid, iuid string, from, to int) *feed.ItemDiff {
+	item := m.items[fuid+"/"+iuid]
+	if item == nil {
+		return nil
+	}
+	return item.Diff(from, to)
+}
+
+func (m *mockFeedLister) ItemReadable(ctx context.Context, fuid, iuid string) (*feed.Readable, error) {
+	if err := m.readableErrs[fuid+"/"+iuid]; err != nil {
+		return nil, err
+	}
+	return m.readables[fuid+"/"+iuid], nil
+}
+
+func (m *mockFeedLister) FeedIcon(fuid string) *feed.Icon {
+	return m.icons[fuid]
+}
+
 func (m *mockFeedLister) MarkRead(fuid, iuid string, before int64) bool {
 	for _, f := range m.feeds {
 		if f.UID == fuid {
@@ -437,6 +464,326 @@ func TestGetItem(t *testing.T) {
 	}
 }
 
+func TestGetItemDiff(t *testing.T) {
+	items := map[string]*feed.Item{
+		"1/1": {
+			RawItem:   feed.RawItem{URL: "url1", Title: "Item 1", Content: "<p>Edited</p>"},
+			Timestamp: 100,
+			UpdatedAt: 200,
+			Revisions: []feed.Revision{{Timestamp: 100, Title: "Item 1", Content: "<p>Original</p>"}},
+		},
+	}
+
+	tests := []struct {
+		desc           string
+		fuid           string
+		iuid           string
+		query          string
+		expectedDiff   *feed.ItemDiff
+		token          string
+		expectedStatus int
+		authSuccess    bool
+	}{{
+		desc:        "success: last revision compared with the current version",
+		token:       "valid-token",
+		authSuccess: true,
+		fuid:        "1",
+		iuid:        "1",
+		expectedDiff: &feed.ItemDiff{
+			From:          0,
+			To:            1,
+			FromTimestamp: 100,
+			ToTimestamp:   200,
+			Title:         []feed.DiffLine{{Op: feed.DiffEqual, Text: "Item 1"}},
+			Authors:       []feed.DiffLine{{Op: feed.DiffEqual, Text: ""}},
+			Content: []feed.DiffLine{
+				{Op: feed.DiffDelete, Text: "Original"},
+				{Op: feed.DiffInsert, Text: "Edited"},
+			},
+		},
+		expectedStatus: http.StatusOK,
+	}, {
+		desc:        "success: explicit revisions",
+		token:       "valid-token",
+		authSuccess: true,
+		fuid:        "1",
+		iuid:        "1",
+		query:       "?from=1&to=0",
+		expectedDiff: &feed.ItemDiff{
+			From:          1,
+			To:            0,
+			FromTimestamp: 200,
+			ToTimestamp:   100,
+			Title:         []feed.DiffLine{{Op: feed.DiffEqual, Text: "Item 1"}},
+			Authors:       []feed.DiffLine{{Op: feed.DiffEqual, Text: ""}},
+			Content: []feed.DiffLine{
+				{Op: feed.DiffDelete, Text: "Edited"},
+				{Op: feed.DiffInsert, Text: "Original"},
+			},
+		},
+		expectedStatus: http.StatusOK,
+	}, {
+		desc:           "failure: invalid revision",
+		token:          "valid-token",
+		authSuccess:    true,
+		fuid:           "1",
+		iuid:           "1",
+		query:          "?from=abc",
+		expectedStatus: http.StatusBadRequest,
+	}, {
+		desc:           "failure: revision not found",
+		token:          "valid-token",
+		authSuccess:    true,
+		fuid:           "1",
+		iuid:           "1",
+		query:          "?to=2",
+		expectedStatus: http.StatusNotFound,
+	}, {
+		desc:           "failure: item not found",
+		token:          "valid-token",
+		authSuccess:    true,
+		fuid:           "1",
+		iuid:           "2",
+		expectedStatus: http.StatusNotFound,
+	}, {
+		desc:           "failure: authentication with invalid cookie",
+		token:          "invalid-token",
+		authSuccess:    false,
+		fuid:           "1",
+		iuid:           "1",
+		expectedStatus: http.StatusUnauthorized,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			feedList := &mockFeedLister{items: items}
+			handlerParams := &web.HandlerParams{
+				FeedList:    feedList,
+				AccessToken: "valid-token",
+				SessionKey:  []byte("test-session-key"),
+			}
+			h := web.NewHandler(handlerParams)
+
+			cookie := performLogin(t, h, performLoginParams{
+				Token:         test.token,
+				ExpectSuccess: test.authSuccess,
+			})
+
+			req, _ := http.NewRequest("GET", "/api/feeds/"+test.fuid+"/items/"+test.iuid+"/diff"+test.query, nil)
+			if cookie != nil {
+				req.AddCookie(cookie)
+			}
+			rr := httptest.NewRecorder()
+
+			h.ServeHTTP(rr, req)
+			if rr.Code != test.expectedStatus {
+				t.Errorf("expected status %v, got %v", test.expectedStatus, rr.Code)
+			}
+
+			if test.expectedDiff != nil {
+				var diff feed.ItemDiff
+				err := json.NewDecoder(rr.Body).Decode(&diff)
+				if err != nil {
+					t.Fatalf("could not decode response: %v", err)
+				}
+				if !reflect.DeepEqual(&diff, test.expectedDiff) {
+					t.Errorf("expected diff %#v, got %#v", test.expectedDiff, &diff)
+				}
+			}
+		})
+	}
+}
+
+func TestGetItemReadable(t *testing.T) {
+	readables := map[string]*feed.Readable{
+		"1/1": {URL: "https://example.com/post1", Content: "<p>Full post</p>", FetchedAt: 100},
+	}
+	readableErrs := map[string]error{
+		"1/2": errors.New("cannot find content in page"),
+	}
+
+	tests := []struct {
+		desc             string
+		fuid             string
+		iuid             string
+		token            string
+		authSuccess      bool
+		expectedStatus   int
+		expectedReadable *feed.Readable
+		expectedMessage  string
+	}{{
+		desc:             "success: readable content",
+		token:            "valid-token",
+		authSuccess:      true,
+		fuid:             "1",
+		iuid:             "1",
+		expectedStatus:   http.StatusOK,
+		expectedReadable: readables["1/1"],
+	}, {
+		desc:            "failure: content cannot be fetched",
+		token:           "valid-token",
+		authSuccess:     true,
+		fuid:            "1",
+		iuid:            "2",
+		expectedStatus:  http.StatusBadGateway,
+		expectedMessage: "cannot fetch readable content: cannot find content in page",
+	}, {
+		desc:            "failure: item not found",
+		token:           "valid-token",
+		authSuccess:     true,
+		fuid:            "1",
+		iuid:            "3",
+		expectedStatus:  http.StatusNotFound,
+		expectedMessage: "item not found",
+	}, {
+		desc:           "failure: authentication with invalid cookie",
+		token:          "invalid-token",
+		authSuccess:    false,
+		fuid:           "1",
+		iuid:           "1",
+		expectedStatus: http.StatusUnauthorized,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			feedList := &mockFeedLister{readables: readables, readableErrs: readableErrs}
+			handlerParams := &web.HandlerParams{
+				FeedList:    feedList,
+				AccessToken: "valid-token",
+				SessionKey:  []byte("test-session-key"),
+			}
+			h := web.NewHandler(handlerParams)
+
+			cookie := performLogin(t, h, performLoginParams{
+				Token:         test.token,
+				ExpectSuccess: test.authSuccess,
+			})
+
+			req, _ := http.NewRequest("GET", "/api/feeds/"+test.fuid+"/items/"+test.iuid+"/readable", nil)
+			if cookie != nil {
+				req.AddCookie(cookie)
+			}
+			rr := httptest.NewRecorder()
+
+			h.ServeHTTP(rr, req)
+			if rr.Code != test.expectedStatus {
+				t.Errorf("expected status %v, got %v", test.expectedStatus, rr.Code)
+			}
+
+			if test.expectedReadable != nil {
+				var readable feed.Readable
+				err := json.NewDecoder(rr.Body).Decode(&readable)
+				if err != nil {
+					t.Fatalf("cannot decode response: %v", err)
+				}
+				if !reflect.DeepEqual(&readable, test.expectedReadable) {
+					t.Errorf("expected readable content %#v, got %#v", test.expectedReadable, &readable)
+				}
+			}
+			if test.expectedMessage != "" {
+				var errRes struct {
+					Message string `json:"message"`
+				}
+				err := json.NewDecoder(rr.Body).Decode(&errRes)
+				if err != nil {
+					t.Fatalf("cannot decode response: %v", err)
+				}
+				if errRes.Message != test.expectedMessage {
+					t.Errorf("expected message %q, got %q", test.expectedMessage, errRes.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestGetIcon(t *testing.T) {
+	icons := map[string]*feed.Icon{
+		"1": {URL: "https://example.com/favicon.png", MimeType: "image/png", Data: []byte("png"), FetchedAt: 100},
+		"2": {URL: "https://example.com/icon.svg", MimeType: "image/svg+xml", Data: []byte("<svg></svg>"), FetchedAt: 100},
+	}
+
+	tests := []struct {
+		desc                string
+		fuid                string
+		expectedContentType string
+		expectedData        string
+		token               string
+		expectedStatus      int
+		authSuccess         bool
+	}{{
+		desc:                "success: PNG icon",
+		token:               "valid-token",
+		authSuccess:         true,
+		fuid:                "1",
+		expectedContentType: "image/png",
+		expectedData:        "png",
+		expectedStatus:      http.StatusOK,
+	}, {
+		desc:                "success: SVG icon",
+		token:               "valid-token",
+		authSuccess:         true,
+		fuid:                "2",
+		expectedContentType: "image/svg+xml",
+		expectedData:        "<svg></svg>",
+		expectedStatus:      http.StatusOK,
+	}, {
+		desc:           "failure: icon not found",
+		token:          "valid-token",
+		authSuccess:    true,
+		fuid:           "3",
+		expectedStatus: http.StatusNotFound,
+	}, {
+		desc:           "failure: authentication with invalid cookie",
+		token:          "invalid-token",
+		authSuccess:    false,
+		fuid:           "1",
+		expectedStatus: http.StatusUnauthorized,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			feedList := &mockFeedLister{icons: icons}
+			handlerParams := &web.HandlerParams{
+				FeedList:    feedList,
+				AccessToken: "valid-token",
+				SessionKey:  []byte("test-session-key"),
+			}
+			h := web.NewHandler(handlerParams)
+
+			cookie := performLogin(t, h, performLoginParams{
+				Token:         test.token,
+				ExpectSuccess: test.authSuccess,
+			})
+
+			req, _ := http.NewRequest("GET", "/api/feeds/"+test.fuid+"/icon?v=100", nil)
+			if cookie != nil {
+				req.AddCookie(cookie)
+			}
+			rr := httptest.NewRecorder()
+
+			h.ServeHTTP(rr, req)
+			if rr.Code != test.expectedStatus {
+				t.Errorf("expected status %v, got %v", test.expectedStatus, rr.Code)
+			}
+
+			if test.expectedStatus == http.StatusOK {
+				if contentType := rr.Header().Get("Content-Type"); contentType != test.expectedContentType {
+					t.Errorf("expected content type %q, got %q", test.expectedContentType, contentType)
+				}
+				if nosniff := rr.Header().Get("X-Content-Type-Options"); nosniff != "nosniff" {
+					t.Errorf("expected X-Content-Type-Options nosniff, got %q", nosniff)
+				}
+				if csp := rr.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "sandbox") {
+					t.Errorf("expected sandboxed Content-Security-Policy, got %q", csp)
+				}
+				if body := rr.Body.String(); body != test.expectedData {
+					t.Errorf("expected icon data %q, got %q", test.expectedData, body)
+				}
+			}
+		})
+	}
+}
+
 func TestMarkAsRead(t *testing.T) {
 	tests := []struct {
 		desc string
@@ -569,6 +916,109 @@ func TestMarkAsRead(t *testing.T) {
 	}
 }
 
+func TestDiscover(t *testing.T) {
+	discover := func(ctx context.Context, siteURL string) ([]feed.DiscoveredFeed, error) {
+		switch siteURL {
+		case "https://example.com":
+			return []feed.DiscoveredFeed{{URL: "https://example.com/feed", Type: "xml", Title: "Example"}}, nil
+		case "https://example.com/nofeeds":
+			return nil, nil
+		}
+		return nil, errors.New("cannot fetch site")
+	}
+
+	tests := []struct {
+		desc           string
+		siteURL        string
+		noDiscover     bool
+		expectedFeeds  []feed.DiscoveredFeed
+		token          string
+		expectedStatus int
+		authSuccess    bool
+	}{{
+		desc:           "success: feeds found",
+		token:          "valid-token",
+		authSuccess:    true,
+		siteURL:        "https://example.com",
+		expectedFeeds:  []feed.DiscoveredFeed{{URL: "https://example.com/feed", Type: "xml", Title: "Example"}},
+		expectedStatus: http.StatusOK,
+	}, {
+		desc:           "success: no feeds found",
+		token:          "valid-token",
+		authSuccess:    true,
+		siteURL:        "https://example.com/nofeeds",
+		expectedFeeds:  []feed.DiscoveredFeed{},
+		expectedStatus: http.StatusOK,
+	}, {
+		desc:           "failure: invalid site URL",
+		token:          "valid-token",
+		authSuccess:    true,
+		siteURL:        "example.com",
+		expectedStatus: http.StatusBadRequest,
+	}, {
+		desc:           "failure: discovery error",
+		token:          "valid-token",
+		authSuccess:    true,
+		siteURL:        "https://example.com/error",
+		expectedStatus: http.StatusBadGateway,
+	}, {
+		desc:           "failure: discovery not available",
+		token:          "valid-token",
+		authSuccess:    true,
+		siteURL:        "https://example.com",
+		noDiscover:     true,
+		expectedStatus: http.StatusNotImplemented,
+	}, {
+		desc:           "failure: authentication with invalid cookie",
+		token:          "invalid-token",
+		authSuccess:    false,
+		siteURL:        "https://example.com",
+		expectedStatus: http.StatusUnauthorized,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			handlerParams := &web.HandlerParams{
+				FeedList:    &mockFeedLister{},
+				Discover:    discover,
+				AccessToken: "valid-token",
+				SessionKey:  []byte("test-session-key"),
+			}
+			if test.noDiscover {
+				handlerParams.Discover = nil
+			}
+			h := web.NewHandler(handlerParams)
+
+			cookie := performLogin(t, h, performLoginParams{
+				Token:         test.token,
+				ExpectSuccess: test.authSuccess,
+			})
+
+			req, _ := http.NewRequest("GET", "/api/discover?url="+url.QueryEscape(test.siteURL), nil)
+			if cookie != nil {
+				req.AddCookie(cookie)
+			}
+			rr := httptest.NewRecorder()
+
+			h.ServeHTTP(rr, req)
+			if rr.Code != test.expectedStatus {
+				t.Errorf("expected status %v, got %v", test.expectedStatus, rr.Code)
+			}
+
+			if test.expectedFeeds != nil {
+				var feeds []feed.DiscoveredFeed
+				err := json.NewDecoder(rr.Body).Decode(&feeds)
+				if err != nil {
+					t.Fatalf("could not decode response: %v", err)
+				}
+				if !reflect.DeepEqual(feeds, test.expectedFeeds) {
+					t.Errorf("expected feeds %#v, got %#v", test.expectedFeeds, feeds)
+				}
+			}
+		})
+	}
+}
+
 func TestStatic(t *testing.T) {
 	tests := []struct {
 		desc           string
@@ -7,7 +7,7 @@ import (
 	"runtime/debug"
 )
 
-const defaultCSPPolicy = "default-src 'self'; img-src * data:"
+const defaultCSPPolicy = "default-src 'self'; img-src * data:; media-src *"
 
 // loggingResponseWriter is a wrapper around http.ResponseWriter that stores
 // the status code written to the response for logging.
@@ -111,6 +111,10 @@ async function fetch_item(fuid, iuid) {
     return fetch_json(`/api/feeds/${fuid}/items/${iuid}`);
 }
 
+async function fetch_readable(fuid, iuid) {
+    return fetch_json(`/api/feeds/${fuid}/items/${iuid}/readable`);
+}
+
 async function login(token) {
     return fetch("/login", {
         method: "POST",
@@ -167,6 +171,13 @@ function gen_feed_list(feeds, opts = {}) {
     let feed_fragment = document.createDocumentFragment();
     feeds.forEach(feed => {
         let a = create_element("a", {text: feed.name});
+        if (feed.icon_url) {
+            let icon = create_element("img", {class_name: "feed-icon"});
+            icon.src = feed.icon_url;
+            icon.alt = "";
+            icon.loading = "lazy";
+            a.prepend(icon);
+        }
         let feed_url = opts.feed_url
             ? opts.feed_url(feed)
             : `/feeds/${feed.uid}`;
@@ -270,10 +281,22 @@ function gen_status_content(feeds, opts = {}) {
             table.append(table_row("Last item", relative_time_desc(feed.last_item)));
         }
 
+        if (feed.refresh_interval) {
+            table.append(table_row("Refresh interval", `every ${duration_desc(feed.refresh_interval)}`));
+        }
+
+        if (feed.next_refresh_at) {
+            table.append(table_row("Next update", relative_time_desc(feed.next_refresh_at)));
+        }
+
         if (feed.last_error) {
             table.append(table_row("Error", feed.last_error ? feed.last_error : "none"));
         }
 
+        if (feed.last_error_code) {
+            table.append(table_row("HTTP status", `${feed.last_error_code}`));
+        }
+
         feed_fragment.append(create_element("div", {
             class_name: "feed-status-block",
             children: [
@@ -403,8 +426,15 @@ function gen_item_header(item, opts = {}) {
     }
 
     let when = relative_time_desc(item.timestamp);
+    if (item.published && item.published < item.timestamp) {
+        when = "published " + relative_time_desc(item.published);
+    }
     details.push(when);
 
+    if (item.updated_at) {
+        details.push("edited " + relative_time_desc(item.updated_at));
+    }
+
     return create_element("div", {
         class_name: "item-header",
         children: [
@@ -420,12 +450,94 @@ function gen_item_header(item, opts = {}) {
     });
 }
 
+function gen_item_attachment(attachment) {
+    let type = attachment.mime_type || "";
+    let media;
+    if (type.startsWith("audio/")) {
+        media = create_element("audio");
+    } else if (type.startsWith("video/")) {
+        media = create_element("video");
+        if (attachment.thumbnail) {
+            media.poster = attachment.thumbnail;
+        }
+    }
+    if (media) {
+        media.controls = true;
+        media.preload = "none";
+        media.src = attachment.url;
+    } else {
+        // Attachments that cannot be played are linked, showing their
+        // thumbnail if they have one.
+        media = create_element("a", {text: attachment.thumbnail ? "" : attachment.url});
+        media.setAttribute("href", attachment.url);
+        media.setAttribute("target", "_blank");
+        media.setAttribute("rel", "noopener noreferrer");
+        let thumbnail = attachment.thumbnail
+            || (type.startsWith("image/") ? attachment.url : "");
+        if (thumbnail) {
+            let img = create_element("img");
+            img.src = thumbnail;
+            media.append(img);
+        }
+    }
+
+    let details = [];
+    if (type) details.push(type);
+    if (attachment.duration) details.push(duration_desc(attachment.duration));
+    if (attachment.length) {
+        details.push(`${(attachment.length / 1e6).toFixed(1)} MB`);
+    }
+    return create_element("div", {
+        class_name: "item-attachment",
+        children: [
+            media,
+            create_element("div", {
+                class_name: "item-attachment-details",
+                text: details.join(" · "),
+            }),
+        ],
+    });
+}
+
 function gen_item_content(item) {
     let content_div = create_element("div", {class_name: "item-content"});
     content_div.innerHTML = item.content;
+    if (item.attachments?.length) {
+        content_div.prepend(...item.attachments.map(gen_item_attachment));
+    }
     return content_div;
 }
 
+// gen_readable_button creates a button that replaces the content of the item
+// in content_div with the full article extracted from the item's page.
+function gen_readable_button(item, content_div) {
+    let button = create_element("button", {
+        class_name: "readable-button",
+        text: "Load full article",
+    });
+    button.type = "button";
+    button.onclick = async () => {
+        button.disabled = true;
+        button.textContent = "Loading full article...";
+        let rsp, data;
+        try {
+            [rsp, data] = await fetch_readable(item.feed_uid, item.uid);
+        } catch (err) {
+            button.textContent = `Cannot load full article: ${err}`;
+            return;
+        }
+        if (rsp.status !== 200) {
+            button.textContent = rsp.status === 502
+                ? "Cannot load full article."
+                : response_error(rsp, data, "Item not found.");
+            return;
+        }
+        content_div.replaceWith(gen_item_content({...item, content: data.content}));
+        button.remove();
+    };
+    return button;
+}
+
 function gen_item(item, opts) {
     opts = opts || {};
     let list_view = (opts && opts.list_view) || false;
@@ -433,7 +545,8 @@ function gen_item(item, opts) {
     let header_div = gen_item_header(item, {list_view});
     let item_children = [header_div];
     if (!list_view) {
-        item_children.push(gen_item_content(item));
+        let content_div = gen_item_content(item);
+        item_children.push(content_div, gen_readable_button(item, content_div));
     }
 
     let item_div = create_element("div", {
@@ -701,3 +814,21 @@ function relative_time_desc(timestamp) {
     let adapted_diff = Math.round(diff / chosen_quantity);
     return rtf.format(adapted_diff, chosen_unit);
 }
+
+// duration_desc receives a duration in seconds and returns a human-readable
+// string in English representing it (e.g., 3 hours).
+function duration_desc(seconds) {
+    let chosen_quantity = 1;
+    let chosen_unit = "second";
+    for (let i = 0; i < units.length; i++) {
+        let [quantity, unit] = units[i];
+        if (seconds < quantity) {
+            break
+        }
+        chosen_quantity = quantity;
+        chosen_unit = unit;
+    }
+
+    let adapted_seconds = Math.round(seconds / chosen_quantity);
+    return `${adapted_seconds} ${chosen_unit}${adapted_seconds === 1 ? "" : "s"}`;
+}